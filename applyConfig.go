@@ -0,0 +1,66 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+
+    config "gh-actions-workflow-runs-sorter/config"
+
+    log "github.com/sirupsen/logrus"
+)
+
+// applyConfig fills in the policy flags of fs not in skip (set on the command line or from the environment)
+// from the configuration file's policy for workflow on branch.
+func applyConfig(fs *flag.FlagSet, skip map[string]bool, configPath string, workflow string, branch string) error {
+
+    // the default location is optional - an explicitly passed path is not:
+    if configPath == "" {
+        if _, err := os.Stat(config.DefaultPath); err == nil {
+            configPath = config.DefaultPath
+        }
+    }
+
+    if configPath == "" {
+        return nil
+    }
+
+    cfg, err := config.Load(configPath)
+
+    if err != nil {
+        return err
+    }
+
+    values := cfg.Resolve(workflow, branch).Values()
+
+    log.WithFields(log.Fields{
+        "config":   configPath,
+        "workflow": workflow,
+        "branch":   branch,
+    }).Info("Loaded config file ...")
+
+    // policies are resolved before the workflow is looked up - an ID or display name matches no file name:
+    if _, ok := cfg.WorkflowKey(workflow); !ok && workflow != "" && len(cfg.Workflows) > 0 {
+        log.WithFields(log.Fields{
+            "config":   configPath,
+            "workflow": workflow,
+        }).Warn("--workflow-file matches no key of 'workflows' - keys are matched by file name (e.g. 'release.yml') or path, so no workflow policy applies")
+    }
+
+    for _, key := range config.PolicyKeys {
+
+        name := config.PolicyFlags[key]
+        value, ok := values[key]
+
+        // commands only register the flags they use:
+        if !ok || skip[name] || fs.Lookup(name) == nil {
+            continue
+        }
+
+        if err := fs.Set(name, value); err != nil {
+            return fmt.Errorf("Invalid value '%s' for %s from %s: %s", value, key, configPath, err.Error())
+        }
+    }
+
+    return nil
+}
@@ -0,0 +1,771 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strings"
+    "time"
+
+    config "gh-actions-workflow-runs-sorter/config"
+    gh "gh-actions-workflow-runs-sorter/gh"
+    policysim "gh-actions-workflow-runs-sorter/policysim"
+    server "gh-actions-workflow-runs-sorter/server"
+)
+
+// options holds the value of every flag - each command only registers the flags it uses.
+type options struct {
+    owner                string
+    repo                 string
+    branch               string
+    workflowFile         string
+    workflowRunsToReturn int
+    events               string
+    runNumber            int
+    previousRunId        int
+    waitBetweenChecks    int
+    waitBeforeComplete   float64
+    completionPolicy     string
+    timeout              int
+    exitCodes            bool
+    configPath           string
+    explain              bool
+    dryRun               bool
+    stepSummary          bool
+    estimateEta          bool
+    etaJob               string
+    etaHistory           int
+    logFormat            string
+    logLevel             string
+    metricsTextfile      string
+    metricsPushURL       string
+    metricsPushJob       string
+    metricsListen        string
+    traceExporter        string
+    traceEndpoint        string
+    traceFile            string
+    cacheDir             string
+    cacheRunsTTL         int
+    cacheStatusTTL       int
+    cacheCompletedTTL    int
+    apiURL               string
+    record               string
+    replay               string
+    output               string
+    snapshotPath         string
+    snapshot             *gh.Snapshot
+    policies             policySpecs
+    simTrials            int
+    simSeed              int64
+    simRuns              int
+    simMeanInterval      time.Duration
+    simMeanDuration      time.Duration
+    simDurationStdDev    time.Duration
+    simFailureRate       float64
+    simStarvationAfter   time.Duration
+    minRateLimit         int
+    tokenFile            string
+    tokenStdin           bool
+    anonymous            bool
+    token                string
+    tokenSource          string
+    caCert               string
+    clientCert           string
+    clientKey            string
+    connectTimeout       int
+    responseTimeout      int
+    tlsDebug             bool
+    server               string
+    listen               string
+    refreshInterval      int
+    completedTTL         int
+    webhookSecret        string
+    allowRepos           string
+    serverSecret         string
+    coordinate           bool
+    coordinationKey      string
+    rerunGracePeriod     int
+}
+
+// command is a subcommand of the cli - legacyName is its name as a '--run-mode' value. Commands run without a
+// token only when tokenOptional is set or --anonymous is passed.
+type command struct {
+    name          string
+    legacyName    string
+    summary       string
+    flagGroups    []func(*flag.FlagSet, *options)
+    required      []string
+    tokenOptional bool
+    run           func(*session, *options) error
+}
+
+// deprecatedFlags maps flag names used before the cli had subcommands (and the names documented for them)
+// to their current names - they keep working with a warning.
+var deprecatedFlags = map[string]string{
+    "run_number":             "run-number",
+    "prev_run_number":        "previous-run-id",
+    "previousRunId":          "previous-run-id",
+    "workflowFile":           "workflow-file",
+    "workflow_run_to_return": "workflow-runs-to-return",
+    "wait_between_checks":    "wait-between-checks",
+    "waitBetweenChecks":      "wait-between-checks",
+    "wait_before_complete":   "wait-before-complete",
+    "waitBeforeComplete":     "wait-before-complete",
+    "completion_policy":      "completion-policy",
+    "estimate_eta":           "estimate-eta",
+    "eta_job":                "eta-job",
+    "eta_history":            "eta-history",
+    "metrics_textfile":       "metrics-textfile",
+    "metrics_push_url":       "metrics-push-url",
+    "metrics_push_job":       "metrics-push-job",
+    "metrics_listen":         "metrics-listen",
+    "trace_exporter":         "trace-exporter",
+    "trace_endpoint":         "trace-endpoint",
+    "trace_file":             "trace-file",
+    "cache_dir":              "cache-dir",
+    "cache_runs_ttl":         "cache-runs-ttl",
+    "cache_status_ttl":       "cache-status-ttl",
+    "cache_completed_ttl":    "cache-completed-ttl",
+}
+
+// legacyModeFlags select the command when none is passed as the first argument.
+var legacyModeFlags = []string{"run-mode", "mode"}
+
+// legacyDefaults are the values flags defaulted to before the cli had subcommands - command lines without a
+// command still get them when the flags are set nowhere else, with a warning.
+var legacyDefaults = []struct {
+    name  string
+    value string
+}{
+    {name: "owner", value: "sarmad-abualkaz"},
+    {name: "repo", value: "test-repo"},
+    {name: "workflow-file", value: "cron_and_dispatch.yml"},
+    {name: "run-number", value: "0"},
+}
+
+func commands() []*command {
+
+    return []*command{
+        {
+            name:       "should-execute",
+            legacyName: "shouldExecute",
+            summary:    "check if this workflow run should execute and whether it must wait for a previous run",
+            flagGroups: []func(*flag.FlagSet, *options){repositoryFlags, workflowFlags, runNumberFlags, waitFlags, decisionFlags, etaFlags, coordinationFlags, commonFlags},
+            required:   []string{"owner", "repo", "workflow-file", "run-number"},
+            run:        runShouldExecute,
+        },
+        {
+            name:       "should-complete",
+            legacyName: "shouldComplete",
+            summary:    "wait until the previous run has completed and its post-completion wait has passed",
+            flagGroups: []func(*flag.FlagSet, *options){repositoryFlags, workflowFlags, runNumberFlags, previousRunFlags, waitFlags, decisionFlags, etaFlags, listenFlags, coordinationFlags, commonFlags},
+            required:   []string{"owner", "repo", "previous-run-id"},
+            run:        runShouldComplete,
+        },
+        {
+            name:       "queue",
+            legacyName: "queue",
+            summary:    "show the current ordering queue of a workflow on a branch",
+            flagGroups: []func(*flag.FlagSet, *options){repositoryFlags, workflowFlags, commonFlags},
+            required:   []string{"owner", "repo", "workflow-file"},
+            run:        runQueue,
+        },
+        {
+            name:       "snapshot",
+            legacyName: "snapshot",
+            summary:    "dump the most recent runs of a workflow on a branch to a JSON file for 'simulate'",
+            flagGroups: []func(*flag.FlagSet, *options){repositoryFlags, snapshotFlags, commonFlags},
+            required:   []string{"owner", "repo", "workflow-file"},
+            run:        runSnapshot,
+        },
+        {
+            name:          "simulate",
+            legacyName:    "simulate",
+            summary:       "compute the decision for a run offline from a snapshot and the configured policies",
+            flagGroups:    []func(*flag.FlagSet, *options){repositoryFlags, workflowFlags, runNumberFlags, waitFlags, simulateFlags, commonFlags},
+            required:      []string{"snapshot", "run-number"},
+            tokenOptional: true,
+            run:           runSimulate,
+        },
+        {
+            name:          "simulate-policies",
+            legacyName:    "simulatePolicies",
+            summary:       "compare ordering policies on simulated random workloads - ordering violations, starvation, waits and API calls",
+            flagGroups:    []func(*flag.FlagSet, *options){policyFlags, waitFlags, workloadFlags, commonFlags},
+            tokenOptional: true,
+            run:           runSimulatePolicies,
+        },
+        {
+            name:          "doctor",
+            legacyName:    "doctor",
+            summary:       "check the token, its permissions, the repository, workflow and branch and the rate limit - and print a checklist",
+            flagGroups:    []func(*flag.FlagSet, *options){repositoryFlags, doctorFlags, commonFlags},
+            required:      []string{"owner", "repo", "workflow-file"},
+            tokenOptional: true,
+            run:           runDoctor,
+        },
+        {
+            name:       "serve",
+            legacyName: "serve",
+            summary:    "serve decisions, and the workflow runs they are made from, to many runners from one shared view per workflow - see --server",
+            flagGroups: []func(*flag.FlagSet, *options){serveFlags, commonFlags},
+            required:   []string{"allow-repos"},
+            run:        runServe,
+        },
+    }
+}
+
+func repositoryFlags(fs *flag.FlagSet, opts *options) {
+
+    owner, repo := "", ""
+
+    // inside Github Actions the repository running the workflow is the default:
+    if parts := strings.SplitN(os.Getenv("GITHUB_REPOSITORY"), "/", 2); len(parts) == 2 {
+        owner, repo = parts[0], parts[1]
+    }
+
+    fs.StringVar(&opts.owner, "owner", owner, "owner of the github repo - defaults to the owner in $GITHUB_REPOSITORY")
+    fs.StringVar(&opts.repo, "repo", repo, "github repository name - defaults to the repository in $GITHUB_REPOSITORY")
+    fs.StringVar(&opts.branch, "branch", "main", "git branch name")
+}
+
+func workflowFlags(fs *flag.FlagSet, opts *options) {
+
+    fs.StringVar(&opts.workflowFile, "workflow-file", "", "the workflow - a file name (e.g. 'release.yml'), path, numeric ID or display name")
+    fs.IntVar(&opts.workflowRunsToReturn, "workflow-runs-to-return", 20, "number of workflow runs to visit per check")
+    fs.StringVar(&opts.events, "events", "", "comma separated events (e.g. 'push,workflow_dispatch') whose runs take part in ordering - all runs when empty")
+}
+
+func runNumberFlags(fs *flag.FlagSet, opts *options) {
+
+    runNumber := 0
+    fmt.Sscanf(os.Getenv("GITHUB_RUN_NUMBER"), "%d", &runNumber)
+
+    fs.IntVar(&opts.runNumber, "run-number", runNumber, "run number (github.run_number) of this workflow run - defaults to $GITHUB_RUN_NUMBER")
+}
+
+func previousRunFlags(fs *flag.FlagSet, opts *options) {
+
+    fs.IntVar(&opts.previousRunId, "previous-run-id", 0, "run id of the previous workflow run (PAST_RUN_ID from should-execute)")
+    fs.IntVar(&opts.waitBetweenChecks, "wait-between-checks", 10, "how long, in seconds, to wait between checks on the previous workflow run")
+    fs.StringVar(&opts.completionPolicy, "completion-policy", config.CompletionAlways, "when to wait post-completion of the previous run - 'always', 'success' (only after a successful run) or 'fail' (fail this run when the previous run did not succeed)")
+    fs.IntVar(&opts.timeout, "timeout", 0, "how long, in seconds, to wait on the previous run at most - no limit when 0")
+    fs.IntVar(&opts.rerunGracePeriod, "rerun-grace-period", 0, "how long, in seconds, after the previous run completed without success to keep checking it for a re-run - and wait for the re-run when there is one; not at all when 0")
+}
+
+func snapshotFlags(fs *flag.FlagSet, opts *options) {
+
+    fs.StringVar(&opts.workflowFile, "workflow-file", "", "the workflow - a file name (e.g. 'release.yml'), path, numeric ID or display name")
+    fs.StringVar(&opts.output, "output", "", "file to write the snapshot to - stdout when empty")
+}
+
+func doctorFlags(fs *flag.FlagSet, opts *options) {
+
+    fs.StringVar(&opts.workflowFile, "workflow-file", "", "the workflow - a file name (e.g. 'release.yml'), path, numeric ID or display name")
+    fs.IntVar(&opts.minRateLimit, "min-rate-limit", 100, "fewest API requests left in the rate limit for the check to pass")
+}
+
+func serveFlags(fs *flag.FlagSet, opts *options) {
+
+    fs.StringVar(&opts.listen, "listen", ":8080", "address to serve on")
+    fs.IntVar(&opts.refreshInterval, "refresh-interval", int(server.DefaultRefreshInterval.Seconds()), "how long, in seconds, the runs of a workflow and the state of a run that has not completed are served before they are fetched again")
+    fs.IntVar(&opts.completedTTL, "completed-ttl", int(server.DefaultCompletedTTL.Seconds()), "how long, in seconds, a completed run and the workflows of a repository are served before they are fetched again")
+    fs.StringVar(&opts.webhookSecret, "webhook-secret", "", "secret of the webhook delivering workflow_run events to /webhook - /webhook is not served when empty")
+    fs.StringVar(&opts.allowRepos, "allow-repos", "", "comma separated repositories (e.g. 'octo/hello,octo/*') runs and decisions are served for - requests for others are forbidden")
+}
+
+func simulateFlags(fs *flag.FlagSet, opts *options) {
+
+    fs.StringVar(&opts.snapshotPath, "snapshot", "", "snapshot file written by 'snapshot' - its repository, workflow and branch are the defaults")
+}
+
+func policyFlags(fs *flag.FlagSet, opts *options) {
+
+    fs.IntVar(&opts.workflowRunsToReturn, "workflow-runs-to-return", 20, "number of workflow runs to visit per check")
+    fs.IntVar(&opts.waitBetweenChecks, "wait-between-checks", 10, "how long, in seconds, to wait between checks on the previous workflow run")
+    fs.StringVar(&opts.completionPolicy, "completion-policy", config.CompletionAlways, "when to wait post-completion of the previous run - 'always', 'success' or 'fail'")
+    fs.Var(&opts.policies, "policy", "a policy to compare, e.g. 'name=fast,completion-policy=success,wait-between-checks=30s' - settings left out take the other flags; repeat for each policy, every completion policy when none")
+}
+
+func workloadFlags(fs *flag.FlagSet, opts *options) {
+
+    fs.IntVar(&opts.simTrials, "trials", 200, "number of random workloads to simulate")
+    fs.Int64Var(&opts.simSeed, "seed", 1, "seed of the random workloads - the same seed gives the same results")
+    fs.IntVar(&opts.simRuns, "runs", policysim.DefaultWorkload.Runs, "number of runs per workload")
+    fs.DurationVar(&opts.simMeanInterval, "mean-interval", policysim.DefaultWorkload.MeanInterval, "mean time between run arrivals")
+    fs.DurationVar(&opts.simMeanDuration, "mean-duration", policysim.DefaultWorkload.MeanDuration, "mean time a run works before 'should-complete'")
+    fs.DurationVar(&opts.simDurationStdDev, "duration-stddev", policysim.DefaultWorkload.DurationStdDev, "standard deviation of run durations")
+    fs.Float64Var(&opts.simFailureRate, "failure-rate", policysim.DefaultWorkload.FailureRate, "share of runs that fail, between 0 and 1")
+    fs.DurationVar(&opts.simStarvationAfter, "starvation-after", time.Hour, "wait on the previous run after which a run counts as starved")
+}
+
+func waitFlags(fs *flag.FlagSet, opts *options) {
+
+    fs.Float64Var(&opts.waitBeforeComplete, "wait-before-complete", 60, "how long, in seconds, to wait after the previous workflow run completed")
+}
+
+func decisionFlags(fs *flag.FlagSet, opts *options) {
+
+    fs.BoolVar(&opts.explain, "explain", false, "print every run considered and the rule that produced the decision")
+    fs.BoolVar(&opts.dryRun, "dry-run", false, "same as --explain but do not emit outputs or wait")
+    fs.BoolVar(&opts.stepSummary, "step-summary", true, "write a Markdown summary of the decision to $GITHUB_STEP_SUMMARY when it is set")
+}
+
+func etaFlags(fs *flag.FlagSet, opts *options) {
+
+    fs.BoolVar(&opts.estimateEta, "estimate-eta", false, "estimate when the previous run finishes and this run is released from durations of recent completed runs")
+    fs.StringVar(&opts.etaJob, "eta-job", "", "name of a job (typically the last one in the workflow) to base release estimates on instead of whole workflow runs")
+    fs.IntVar(&opts.etaHistory, "eta-history", 20, "number of recent workflow runs to compute duration statistics from")
+}
+
+func coordinationFlags(fs *flag.FlagSet, opts *options) {
+
+    key := ""
+
+    // every leg of a matrix job runs in the same attempt of the same run:
+    if runID := os.Getenv("GITHUB_RUN_ID"); runID != "" {
+        key = fmt.Sprintf("%s-%s", runID, os.Getenv("GITHUB_RUN_ATTEMPT"))
+    }
+
+    fs.BoolVar(&opts.coordinate, "coordinate", false, "share the decision between the processes of this run attempt (e.g. the legs of a matrix job) - one computes it and the others reuse it")
+    fs.StringVar(&opts.coordinationKey, "coordination-key", key, "identifies the run attempt processes coordinate in - defaults to $GITHUB_RUN_ID-$GITHUB_RUN_ATTEMPT")
+}
+
+func listenFlags(fs *flag.FlagSet, opts *options) {
+
+    fs.StringVar(&opts.metricsListen, "metrics-listen", "", "serve metrics on /metrics at this address (e.g. ':9090') while waiting")
+}
+
+func commonFlags(fs *flag.FlagSet, opts *options) {
+
+    fs.StringVar(&opts.configPath, "config", "", "configuration file with per-workflow and per-branch policies - defaults to "+config.DefaultPath+" when present")
+    fs.BoolVar(&opts.exitCodes, "exit-codes", false, "end with a documented exit code per outcome (e.g. 10 when superseded) and one error message instead of a panic")
+    fs.StringVar(&opts.logFormat, "log-format", "text", "log format - 'text', 'json' or 'github' (workflow-command annotations and groups)")
+    fs.StringVar(&opts.logLevel, "log-level", "info", "log level - 'error', 'warn', 'info' or 'debug'")
+    fs.StringVar(&opts.metricsTextfile, "metrics-textfile", "", "write metrics to this file on exit, for node_exporter's textfile collector")
+    fs.StringVar(&opts.metricsPushURL, "metrics-push-url", "", "push metrics on exit to this Pushgateway-compatible endpoint")
+    fs.StringVar(&opts.metricsPushJob, "metrics-push-job", "gh_actions_workflow_runs_sorter", "job name to push metrics under")
+    fs.StringVar(&opts.traceExporter, "trace-exporter", "none", "where to export traces - 'none', 'otlp-grpc', 'otlp-http', 'stdout' or 'file'")
+    fs.StringVar(&opts.traceEndpoint, "trace-endpoint", "", "OTLP collector address - defaults to OTEL_EXPORTER_OTLP_ENDPOINT")
+    fs.StringVar(&opts.traceFile, "trace-file", "", "file to append spans to with the 'file' exporter")
+    fs.StringVar(&opts.cacheDir, "cache-dir", "", "directory for an on-disk cache of workflow runs shared between invocations (e.g. $RUNNER_TEMP) - disabled when empty")
+    fs.IntVar(&opts.cacheRunsTTL, "cache-runs-ttl", 10, "how long, in seconds, a cached list of workflow runs stays valid")
+    fs.IntVar(&opts.cacheStatusTTL, "cache-status-ttl", 5, "how long, in seconds, the cached status of a run that has not completed stays valid")
+    fs.IntVar(&opts.cacheCompletedTTL, "cache-completed-ttl", 60, "how long, in seconds, the cached status of a successfully completed run stays valid - and a re-run of it may go unseen")
+    fs.StringVar(&opts.apiURL, "api-url", os.Getenv("GITHUB_API_URL"), "base URL of the Github REST API (e.g. 'https://github.example.com/api/v3' or a fake-actions-server) - defaults to $GITHUB_API_URL, then api.github.com")
+    fs.StringVar(&opts.server, "server", "", "URL of a 'serve' instance to ask instead of the Github API - it calls Github with its own token, so none is needed")
+    fs.StringVar(&opts.serverSecret, "server-secret", "", "secret shared by a 'serve' instance and the runners asking it - 'serve' forbids requests without it, '--server' sends it")
+    fs.StringVar(&opts.tokenFile, "token-file", "", "file holding the Github token (e.g. a Kubernetes or Docker secret mount) - see the README for where else tokens are looked for")
+    fs.BoolVar(&opts.tokenStdin, "token-stdin", false, "read the Github token from the first line of stdin")
+    fs.BoolVar(&opts.anonymous, "anonymous", false, "call the API without a token when none is found - public repositories only, at 60 requests an hour")
+    fs.StringVar(&opts.caCert, "ca-cert", "", "PEM file, or directory of PEM files, with CA certificates to trust besides the system's (e.g. of a TLS-intercepting proxy)")
+    fs.StringVar(&opts.clientCert, "client-cert", "", "PEM client certificate for mTLS")
+    fs.StringVar(&opts.clientKey, "client-key", "", "PEM key of --client-cert - defaults to --client-cert, for a file holding both")
+    fs.IntVar(&opts.connectTimeout, "connect-timeout", 30, "how long, in seconds, connecting to the API and the TLS handshake may take")
+    fs.IntVar(&opts.responseTimeout, "response-timeout", 60, "how long, in seconds, to wait for the API to respond to a request - no limit when 0")
+    fs.BoolVar(&opts.tlsDebug, "tls-debug", false, "log the certificate chain the API presents - as errors when it fails verification")
+    fs.StringVar(&opts.record, "record", "", "record every Github API interaction to this cassette file, with credentials scrubbed - e.g. to attach to a bug report")
+    fs.StringVar(&opts.replay, "replay", "", "serve Github API calls from this cassette file instead of the API - waits play out as recorded without taking any time")
+}
+
+// invocation is a parsed command line - flags passed explicitly and deprecated names that were used.
+type invocation struct {
+    command    *command
+    flags      *flag.FlagSet
+    options    *options
+    explicit   map[string]bool
+    fromEnv    map[string]bool
+    deprecated []string
+
+    // legacy is set for command lines without a command - they keep the defaults of legacyDefaults.
+    legacy bool
+}
+
+// parseCommandLine selects the command - the first argument, or '--run-mode'/'--mode' for the command lines
+// used before subcommands existed - and parses its flags.
+func parseCommandLine(args []string) (*invocation, error) {
+
+    all := commands()
+
+    if len(args) > 0 && (args[0] == "help" || args[0] == "-h" || args[0] == "--help") {
+
+        if len(args) > 1 {
+            if cmd := findCommand(all, args[1]); cmd != nil {
+                fs := newFlagSet(cmd, &options{})
+                fs.SetOutput(os.Stdout)
+                fs.Usage()
+
+                return nil, flag.ErrHelp
+            }
+        }
+
+        printUsage(os.Stdout, all)
+
+        return nil, flag.ErrHelp
+    }
+
+    deprecated := []string{}
+    legacy := false
+
+    var cmd *command
+
+    if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+
+        cmd = findCommand(all, args[0])
+
+        if cmd == nil {
+            fmt.Fprintf(os.Stderr, "unknown command '%s'\n\n", args[0])
+            printUsage(os.Stderr, all)
+
+            return nil, fmt.Errorf("unknown command '%s'", args[0])
+        }
+
+        if args[0] != cmd.name {
+            deprecated = append(deprecated, fmt.Sprintf("command '%s' is deprecated - use '%s'", args[0], cmd.name))
+        }
+
+        args = args[1:]
+
+    } else {
+
+        mode, found := legacyMode(args)
+
+        cmd = findCommand(all, mode)
+
+        if cmd == nil {
+            fmt.Fprintf(os.Stderr, "mode passed is %s - allowed values are shouldExecute, shouldComplete or queue\n\n", mode)
+            printUsage(os.Stderr, all)
+
+            return nil, fmt.Errorf("mode passed is %s - allowed values are shouldExecute, shouldComplete or queue", mode)
+        }
+
+        legacy = true
+
+        if found {
+            deprecated = append(deprecated, fmt.Sprintf("selecting a mode with --run-mode is deprecated - use '%s' as the first argument", cmd.name))
+        } else {
+            deprecated = append(deprecated, fmt.Sprintf("running without a command is deprecated - use '%s' as the first argument", cmd.name))
+        }
+    }
+
+    opts := &options{}
+    fs := newFlagSet(cmd, opts)
+
+    if err := fs.Parse(args); err != nil {
+        return nil, err
+    }
+
+    explicit := map[string]bool{}
+
+    fs.Visit(func(f *flag.Flag) {
+
+        if current, ok := deprecatedFlags[f.Name]; ok {
+            deprecated = append(deprecated, fmt.Sprintf("flag --%s is deprecated - use --%s", f.Name, current))
+            explicit[current] = true
+            return
+        }
+
+        explicit[f.Name] = true
+    })
+
+    return &invocation{command: cmd, flags: fs, options: opts, explicit: explicit, fromEnv: map[string]bool{}, deprecated: deprecated, legacy: legacy}, nil
+}
+
+// newFlagSet registers the flags of cmd, the deprecated names of those flags and the legacy mode flags.
+func newFlagSet(cmd *command, opts *options) *flag.FlagSet {
+
+    fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+
+    for _, group := range cmd.flagGroups {
+        group(fs, opts)
+    }
+
+    for old, current := range deprecatedFlags {
+
+        if f := fs.Lookup(current); f != nil {
+            fs.Var(f.Value, old, "DEPRECATED: use --"+current)
+        }
+    }
+
+    for _, name := range legacyModeFlags {
+        fs.String(name, cmd.legacyName, "DEPRECATED: pass the command as the first argument")
+    }
+
+    fs.Usage = func() {
+        printCommandUsage(fs.Output(), cmd, fs)
+    }
+
+    return fs
+}
+
+// applyEnv fills in every flag not passed on the command line from the environment (SORTER_<FLAG>).
+func (inv *invocation) applyEnv() error {
+
+    var envErr error
+
+    inv.flags.VisitAll(func(f *flag.Flag) {
+
+        if inv.explicit[f.Name] || isAlias(f.Name) || envErr != nil {
+            return
+        }
+
+        envName, value, ok := inv.lookupEnv(f.Name)
+
+        if !ok {
+            return
+        }
+
+        if err := inv.flags.Set(f.Name, value); err != nil {
+            envErr = withExitCode(exitConfigError, fmt.Errorf("Invalid value '%s' for --%s from %s: %s", value, f.Name, envName, err.Error()))
+        }
+
+        inv.fromEnv[f.Name] = true
+    })
+
+    return envErr
+}
+
+// findToken looks up the Github token - in --token-file, stdin, the environment or the Github CLI's hosts.yml.
+func (inv *invocation) findToken() error {
+
+    tokenOpts := gh.TokenOptions{File: inv.options.tokenFile, APIURL: inv.options.apiURL}
+
+    if inv.options.tokenStdin {
+        tokenOpts.Stdin = os.Stdin
+    }
+
+    token, source, err := gh.FindToken(tokenOpts)
+
+    if err != nil {
+        return withExitCode(exitConfigError, err)
+    }
+
+    inv.options.token, inv.options.tokenSource = token, source
+
+    return nil
+}
+
+// resolve fills in the policy flags set neither on the command line nor in the environment from the
+// configuration file and checks required flags. Precedence is flags over environment over configuration file.
+func (inv *invocation) resolve() error {
+
+    skip := map[string]bool{}
+
+    for name := range inv.explicit {
+        skip[name] = true
+    }
+
+    for name := range inv.fromEnv {
+        skip[name] = true
+    }
+
+    // a snapshot decides which repository, workflow and branch - and so which policies - apply:
+    if inv.options.snapshotPath != "" {
+
+        if err := inv.applySnapshot(skip); err != nil {
+            return withExitCode(exitConfigError, err)
+        }
+    }
+
+    if err := applyConfig(inv.flags, skip, inv.options.configPath, inv.options.workflowFile, inv.options.branch); err != nil {
+        return withExitCode(exitConfigError, err)
+    }
+
+    defaulted := inv.applyLegacyDefaults()
+
+    missing := []string{}
+
+    for _, name := range inv.command.required {
+
+        if defaulted[name] {
+            continue
+        }
+
+        if value := inv.flags.Lookup(name).Value.String(); value == "" || value == "0" {
+            missing = append(missing, "--"+name)
+        }
+    }
+
+    if len(missing) > 0 {
+        return withExitCode(exitUsage, fmt.Errorf("missing required flags for '%s': %s (or their SORTER_* environment variables)", inv.command.name, strings.Join(missing, ", ")))
+    }
+
+    return nil
+}
+
+// applyLegacyDefaults sets the flags of legacyDefaults that are still empty on a command line without a command -
+// it returns the names of the flags it set.
+func (inv *invocation) applyLegacyDefaults() map[string]bool {
+
+    defaulted := map[string]bool{}
+
+    if !inv.legacy {
+        return defaulted
+    }
+
+    for _, legacyDefault := range legacyDefaults {
+
+        f := inv.flags.Lookup(legacyDefault.name)
+
+        if f == nil || f.Value.String() != "" && f.Value.String() != "0" {
+            continue
+        }
+
+        inv.flags.Set(legacyDefault.name, legacyDefault.value)
+        defaulted[legacyDefault.name] = true
+
+        inv.deprecated = append(inv.deprecated, fmt.Sprintf("--%s defaults to '%s' without a command - this default is deprecated, pass --%s", legacyDefault.name, legacyDefault.value, legacyDefault.name))
+    }
+
+    return defaulted
+}
+
+// applySnapshot loads the snapshot and takes its repository, workflow and branch for flags not set in skip.
+func (inv *invocation) applySnapshot(skip map[string]bool) error {
+
+    snapshot, err := gh.LoadSnapshot(inv.options.snapshotPath)
+
+    if err != nil {
+        return err
+    }
+
+    inv.options.snapshot = snapshot
+
+    for name, value := range map[string]string{"owner": snapshot.Owner, "repo": snapshot.Repo, "workflow-file": snapshot.Workflow, "branch": snapshot.Branch} {
+
+        if !skip[name] {
+            inv.flags.Set(name, value)
+        }
+    }
+
+    return nil
+}
+
+// lookupEnv returns the environment variable setting name - falling back to the variables of its deprecated names.
+func (inv *invocation) lookupEnv(name string) (string, string, bool) {
+
+    if value, ok := os.LookupEnv(config.EnvName(name)); ok {
+        return config.EnvName(name), value, true
+    }
+
+    for old, current := range deprecatedFlags {
+
+        if current != name || config.EnvName(old) == config.EnvName(name) {
+            continue
+        }
+
+        if value, ok := os.LookupEnv(config.EnvName(old)); ok {
+            inv.deprecated = append(inv.deprecated, fmt.Sprintf("environment variable %s is deprecated - use %s", config.EnvName(old), config.EnvName(name)))
+            return config.EnvName(old), value, true
+        }
+    }
+
+    return "", "", false
+}
+
+func findCommand(all []*command, name string) *command {
+
+    for _, cmd := range all {
+
+        if cmd.name == name || cmd.legacyName == name {
+            return cmd
+        }
+    }
+
+    return nil
+}
+
+// legacyMode finds the value of '--run-mode' or '--mode' in args - defaulting to shouldExecute like before.
+func legacyMode(args []string) (string, bool) {
+
+    for i, arg := range args {
+
+        name := strings.TrimLeft(arg, "-")
+
+        for _, modeFlag := range legacyModeFlags {
+
+            if strings.HasPrefix(name, modeFlag+"=") {
+                return strings.TrimPrefix(name, modeFlag+"="), true
+            }
+
+            if name == modeFlag && i+1 < len(args) {
+                return args[i+1], true
+            }
+        }
+    }
+
+    return "shouldExecute", false
+}
+
+func isAlias(name string) bool {
+
+    if _, ok := deprecatedFlags[name]; ok {
+        return true
+    }
+
+    for _, modeFlag := range legacyModeFlags {
+
+        if name == modeFlag {
+            return true
+        }
+    }
+
+    return false
+}
+
+func printUsage(w io.Writer, all []*command) {
+
+    fmt.Fprintf(w, "Usage: gh-actions-workflow-runs-sorter <command> [flags]\n\nCommands:\n")
+
+    for _, cmd := range all {
+        fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.summary)
+    }
+
+    fmt.Fprintf(w, "\nRun 'gh-actions-workflow-runs-sorter help <command>' for the flags of a command.\n")
+}
+
+// printCommandUsage lists the current flags of cmd with their environment variables - deprecated names are left out.
+func printCommandUsage(w io.Writer, cmd *command, fs *flag.FlagSet) {
+
+    fmt.Fprintf(w, "Usage: gh-actions-workflow-runs-sorter %s [flags]\n\n%s\n\nFlags:\n", cmd.name, cmd.summary)
+
+    required := map[string]bool{}
+
+    for _, name := range cmd.required {
+        required[name] = true
+    }
+
+    names := []string{}
+
+    fs.VisitAll(func(f *flag.Flag) {
+
+        if !isAlias(f.Name) {
+            names = append(names, f.Name)
+        }
+    })
+
+    sort.Strings(names)
+
+    for _, name := range names {
+
+        f := fs.Lookup(name)
+        typeName, usage := flag.UnquoteUsage(f)
+
+        fmt.Fprintf(w, "  %s\n", strings.TrimSpace("--"+name+" "+typeName))
+        fmt.Fprintf(w, "        %s\n", usage)
+
+        details := []string{"env " + config.EnvName(name)}
+
+        if required[name] {
+            details = append(details, "required")
+        }
+
+        if f.DefValue != "" && f.DefValue != "0" && f.DefValue != "false" {
+            details = append(details, fmt.Sprintf("default %q", f.DefValue))
+        }
+
+        fmt.Fprintf(w, "        (%s)\n", strings.Join(details, ", "))
+    }
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    log "github.com/sirupsen/logrus"
+)
+
+func TestResolveCommandLine(t *testing.T){
+
+    tests := []struct {
+        name           string
+        args           []string
+        env            map[string]string
+        config         string
+        wantCommand    string
+        wantValues     map[string]string
+        wantDeprecated string
+    }{
+        {
+            name:        "legacy --run-mode command line keeps the flag names and defaults of earlier versions",
+            args:        []string{"--run-mode=shouldComplete", "--prev_run_number=42", "--workflowFile=ci.yml"},
+            wantCommand: "should-complete",
+            wantValues: map[string]string{
+                "owner":           "sarmad-abualkaz",
+                "repo":            "test-repo",
+                "workflow-file":   "ci.yml",
+                "previous-run-id": "42",
+            },
+            wantDeprecated: "selecting a mode with --run-mode is deprecated",
+        },
+        {
+            name:        "command line without a mode defaults to should-execute of cron_and_dispatch.yml",
+            args:        []string{"--run_number=7"},
+            wantCommand: "should-execute",
+            wantValues: map[string]string{
+                "owner":         "sarmad-abualkaz",
+                "repo":          "test-repo",
+                "workflow-file": "cron_and_dispatch.yml",
+                "run-number":    "7",
+            },
+            wantDeprecated: "--workflow-file defaults to 'cron_and_dispatch.yml' without a command",
+        },
+        {
+            name:        "legacy defaults give way to the environment",
+            args:        []string{"--run-mode", "shouldExecute"},
+            env:         map[string]string{"GITHUB_REPOSITORY": "octo/hello", "SORTER_WORKFLOW_FILE": "release.yml"},
+            wantCommand: "should-execute",
+            wantValues: map[string]string{
+                "owner":         "octo",
+                "repo":          "hello",
+                "workflow-file": "release.yml",
+                "run-number":    "0",
+            },
+            wantDeprecated: "--run-number defaults to '0' without a command",
+        },
+        {
+            name:        "environment overrides the configuration file",
+            args:        []string{"should-complete", "--owner=octo", "--repo=hello", "--previous-run-id=42"},
+            env:         map[string]string{"SORTER_WAIT_BEFORE_COMPLETE": "120"},
+            config:      "defaults:\n  wait_between_checks: 15s\n  wait_before_complete: 60s\n",
+            wantCommand: "should-complete",
+            wantValues: map[string]string{
+                "wait-between-checks":  "15",
+                "wait-before-complete": "120",
+            },
+        },
+        {
+            name:        "flag overrides the environment and the configuration file",
+            args:        []string{"should-complete", "--owner=octo", "--repo=hello", "--previous-run-id=42", "--wait-before-complete=30"},
+            env:         map[string]string{"SORTER_WAIT_BEFORE_COMPLETE": "120"},
+            config:      "defaults:\n  wait_before_complete: 60s\n",
+            wantCommand: "should-complete",
+            wantValues: map[string]string{
+                "wait-before-complete": "30",
+            },
+        },
+        {
+            name:        "deprecated flag name overrides the environment",
+            args:        []string{"should-execute", "--owner=octo", "--repo=hello", "--workflow-file=release.yml", "--run-number=7", "--workflow_run_to_return=50"},
+            env:         map[string]string{"SORTER_WORKFLOW_RUNS_TO_RETURN": "30"},
+            wantCommand: "should-execute",
+            wantValues: map[string]string{
+                "workflow-runs-to-return": "50",
+            },
+            wantDeprecated: "flag --workflow_run_to_return is deprecated",
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            // supress logrus
+            log.SetOutput(io.Discard)
+
+            clearEnv(t)
+
+            for name, value := range tt.env {
+                t.Setenv(name, value)
+            }
+
+            args := tt.args
+
+            if tt.config != "" {
+                args = append(args, "--config="+writeTestFile(t, "sorter.yml", tt.config))
+            }
+
+            inv := resolveCommandLine(t, args)
+
+            if inv.command.name != tt.wantCommand {
+                t.Errorf("parseCommandLine() failed - command expects '%s' but received '%s'", tt.wantCommand, inv.command.name)
+            }
+
+            for name, want := range tt.wantValues {
+
+                if got := inv.flags.Lookup(name).Value.String(); got != want {
+                    t.Errorf("resolve() failed - --%s expects '%s' but received '%s'", name, want, got)
+                }
+            }
+
+            if tt.wantDeprecated != "" && !strings.Contains(strings.Join(inv.deprecated, "\n"), tt.wantDeprecated) {
+                t.Errorf("resolve() failed - deprecation warnings expect '%s' but received %q", tt.wantDeprecated, inv.deprecated)
+            }
+
+            if tt.wantDeprecated == "" && len(inv.deprecated) > 0 {
+                t.Errorf("resolve() failed - unexpected deprecation warnings %q", inv.deprecated)
+            }
+
+        })
+    }
+
+}
+
+func TestResolveRequiredFlags(t *testing.T){
+
+    complete := map[string][]string{
+        "should-execute":  {"--owner=octo", "--repo=hello", "--workflow-file=release.yml", "--run-number=7"},
+        "should-complete": {"--owner=octo", "--repo=hello", "--previous-run-id=42"},
+        "queue":           {"--owner=octo", "--repo=hello", "--workflow-file=release.yml"},
+    }
+
+    for _, cmd := range commands() {
+
+        for _, required := range cmd.required {
+
+            args, ok := complete[cmd.name]
+
+            if !ok {
+                continue
+            }
+
+            t.Run(cmd.name+" without --"+required, func(t *testing.T) {
+
+                // supress logrus
+                log.SetOutput(io.Discard)
+
+                clearEnv(t)
+
+                withoutRequired := []string{cmd.name}
+
+                for _, arg := range args {
+
+                    if !strings.HasPrefix(arg, "--"+required+"=") {
+                        withoutRequired = append(withoutRequired, arg)
+                    }
+                }
+
+                inv, err := parseCommandLine(withoutRequired)
+
+                if err != nil {
+                    t.Fatalf("parseCommandLine() returned error: '%v'", err)
+                }
+
+                if err := inv.applyEnv(); err != nil {
+                    t.Fatalf("applyEnv() returned error: '%v'", err)
+                }
+
+                gotErr := inv.resolve()
+
+                if gotErr == nil || !strings.Contains(gotErr.Error(), "--"+required) {
+                    t.Errorf("resolve() failed - error expects to name --%s but received '%v'", required, gotErr)
+                }
+
+                if exitCodeOf(gotErr) != exitUsage {
+                    t.Errorf("resolve() failed - exit code expects %d but received %d", exitUsage, exitCodeOf(gotErr))
+                }
+
+            })
+        }
+    }
+
+}
+
+// resolveCommandLine parses args and fills in flags from the environment and configuration file like main does.
+func resolveCommandLine(t *testing.T, args []string) *invocation {
+
+    t.Helper()
+
+    inv, err := parseCommandLine(args)
+
+    if err != nil {
+        t.Fatalf("parseCommandLine() returned error: '%v'", err)
+    }
+
+    if err := inv.applyEnv(); err != nil {
+        t.Fatalf("applyEnv() returned error: '%v'", err)
+    }
+
+    if err := inv.resolve(); err != nil {
+        t.Fatalf("resolve() returned error: '%v'", err)
+    }
+
+    return inv
+}
+
+// clearEnv unsets the variables flags default to inside Github Actions for the duration of t.
+func clearEnv(t *testing.T) {
+
+    t.Helper()
+
+    for _, name := range []string{"GITHUB_REPOSITORY", "GITHUB_RUN_NUMBER"} {
+        t.Setenv(name, "")
+    }
+
+    for _, variable := range os.Environ() {
+
+        if name := strings.SplitN(variable, "=", 2)[0]; strings.HasPrefix(name, "SORTER_") {
+            t.Setenv(name, "")
+            os.Unsetenv(name)
+        }
+    }
+}
+
+func writeTestFile(t *testing.T, name string, content string) string {
+
+    t.Helper()
+
+    path := filepath.Join(t.TempDir(), name)
+
+    if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+        t.Fatalf("failed to write %s: '%v'", name, err)
+    }
+
+    return path
+}
@@ -0,0 +1,70 @@
+// fake-actions-server serves the workflow-runs endpoints of the Github REST API from a YAML scenario - point
+// gh-actions-workflow-runs-sorter at it with --api-url to test workflows end to end without Github:
+//
+//  fake-actions-server --scenario scenario.yml --listen 127.0.0.1:8080
+//  gh-actions-workflow-runs-sorter should-execute --api-url http://127.0.0.1:8080/ ...
+package main
+
+import (
+    "flag"
+    "fmt"
+    "net/http"
+    "os"
+    "time"
+
+    ghfake "gh-actions-workflow-runs-sorter/ghfake"
+
+    log "github.com/sirupsen/logrus"
+)
+
+func main(){
+
+    scenarioPath := flag.String("scenario", "", "YAML scenario of workflow runs, their changes over time and faults")
+    listen := flag.String("listen", "127.0.0.1:8080", "address to serve the API on")
+    flag.Parse()
+
+    if *scenarioPath == "" {
+        fmt.Fprintln(os.Stderr, "--scenario is required")
+        flag.Usage()
+        os.Exit(2)
+    }
+
+    scenario, err := ghfake.LoadScenario(*scenarioPath)
+
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err.Error())
+        os.Exit(2)
+    }
+
+    // offsets in the scenario count from now:
+    start := time.Now()
+
+    api := scenario.API(start)
+
+    handler := ghfake.Handler(api, scenario.Owner, scenario.Repo)
+
+    log.WithFields(log.Fields{
+        "listen":   *listen,
+        "scenario": *scenarioPath,
+        "owner":    scenario.Owner,
+        "repo":     scenario.Repo,
+        "runs":     len(scenario.Runs),
+    }).Info("Serving fake Github Actions API ...")
+
+    err = http.ListenAndServe(*listen, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+        begin := time.Now()
+
+        handler.ServeHTTP(w, r)
+
+        log.WithFields(log.Fields{
+            "method":  r.Method,
+            "path":    r.URL.Path,
+            "elapsed": time.Since(begin).String(),
+            "t":       time.Since(start).Truncate(time.Millisecond).String(),
+        }).Info("Request served ...")
+    }))
+
+    fmt.Fprintln(os.Stderr, err.Error())
+    os.Exit(1)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "text/tabwriter"
+
+    gh "gh-actions-workflow-runs-sorter/gh"
+)
+
+// runDoctor checks the token, its permissions, the repository, workflow and branch and the rate limit, and prints
+// a checklist with a hint for every failed check.
+func runDoctor(sess *session, opts *options) error {
+
+    checks := gh.Diagnose(sess.ctx, sess.client, gh.DiagnoseOptions{
+        TokenSource:           opts.tokenSource,
+        Owner:                 opts.owner,
+        Repo:                  opts.repo,
+        WorkflowFile:          opts.workflowFile,
+        Branch:                opts.branch,
+        MinRateLimitRemaining: opts.minRateLimit,
+    })
+
+    w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+    failed := 0
+
+    for _, check := range checks {
+
+        result := "PASS"
+
+        switch {
+
+        case check.Skipped:
+            result = "SKIP"
+
+        case !check.OK:
+            result = "FAIL"
+            failed++
+        }
+
+        fmt.Fprintf(w, "[%s]\t%s\t%s\n", result, check.Name, check.Detail)
+
+        if check.Hint != "" {
+            fmt.Fprintf(w, "\t\t→ %s\n", check.Hint)
+        }
+    }
+
+    w.Flush()
+
+    if failed > 0 {
+        return withExitCode(exitFailure, fmt.Errorf("%d of %d checks failed", failed, len(checks)))
+    }
+
+    return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+    "os"
+
+    util "gh-actions-workflow-runs-sorter/util"
+
+    log "github.com/sirupsen/logrus"
+)
+
+// runQueue shows the current ordering queue of the workflow on the branch.
+func runQueue(sess *session, opts *options) error {
+
+    gate, err := sess.gate(nil)
+
+    if err != nil {
+        return err
+    }
+
+    queue, err := gate.Queue(sess.ctx, sess.now())
+
+    if err != nil {
+        log.WithFields(log.Fields{
+            "repo":         opts.repo,
+            "owner":        opts.owner,
+            "workflowFile": opts.workflowFile,
+            "workflowRunsToReturn": opts.workflowRunsToReturn,
+        }).Error(err.Error())
+
+        return err
+    }
+
+    util.WriteQueue(os.Stdout, utilQueue(queue))
+
+    return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "os"
+    "os/signal"
+    "strings"
+    "syscall"
+    "time"
+
+    metrics "gh-actions-workflow-runs-sorter/metrics"
+    server "gh-actions-workflow-runs-sorter/server"
+
+    log "github.com/sirupsen/logrus"
+)
+
+// runServe serves decisions and the runs they are made from until SIGINT or SIGTERM - runners pass its URL
+// to --server.
+func runServe(sess *session, opts *options) error {
+
+    ctx, stop := signal.NotifyContext(sess.ctx, os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    allowRepos := []string{}
+
+    for _, repo := range strings.Split(opts.allowRepos, ",") {
+
+        if strings.TrimSpace(repo) != "" {
+            allowRepos = append(allowRepos, strings.TrimSpace(repo))
+        }
+    }
+
+    srv := server.New(server.Options{
+        API:             sess.client.Actions,
+        Workflows:       sess.client.Actions,
+        RefreshInterval: time.Duration(opts.refreshInterval)*time.Second,
+        CompletedTTL:    time.Duration(opts.completedTTL)*time.Second,
+        WebhookSecret:   opts.webhookSecret,
+        AllowRepos:      allowRepos,
+        Secret:          opts.serverSecret,
+    })
+
+    mux := http.NewServeMux()
+    mux.Handle("/", srv.Handler())
+    mux.Handle("/metrics", metrics.Handler())
+
+    httpServer := &http.Server{Addr: opts.listen, Handler: mux}
+
+    served := make(chan error, 1)
+
+    go func() {
+        served <- httpServer.ListenAndServe()
+    }()
+
+    log.WithFields(log.Fields{
+        "listen":          opts.listen,
+        "refreshInterval": opts.refreshInterval,
+        "completedTTL":    opts.completedTTL,
+        "webhookVerified": opts.webhookSecret != "",
+        "allowRepos":      opts.allowRepos,
+        "secretRequired":  opts.serverSecret != "",
+    }).Info("Serving decisions ...")
+
+    // the allowed repositories are served to anyone who can reach the server:
+    if opts.serverSecret == "" {
+        log.WithFields(log.Fields{
+            "listen": opts.listen,
+        }).Warn("no --server-secret set - requests for the allowed repositories are served without authentication")
+    }
+
+    // unsigned deliveries could change the state of any run:
+    if opts.webhookSecret == "" {
+        log.WithFields(log.Fields{
+            "listen": opts.listen,
+        }).Warn("no --webhook-secret set - /webhook is not served and views are only refreshed from Github")
+    }
+
+    select {
+
+    case err := <-served:
+        return withExitCode(exitConfigError, err)
+
+    case <-ctx.Done():
+    }
+
+    // requests in flight get a few seconds to finish:
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+    defer cancel()
+
+    err := httpServer.Shutdown(shutdownCtx)
+
+    log.WithFields(log.Fields{
+        "requests":    srv.Stats().Requests,
+        "githubCalls": srv.Stats().GithubCalls,
+        "webhooks":    srv.Stats().Webhooks,
+    }).Info("Stopped serving decisions ...")
+
+    return err
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "time"
+
+    util "gh-actions-workflow-runs-sorter/util"
+    logging "gh-actions-workflow-runs-sorter/logging"
+    metrics "gh-actions-workflow-runs-sorter/metrics"
+    sorter "gh-actions-workflow-runs-sorter/sorter"
+
+    "github.com/google/go-github/v47/github"
+    log "github.com/sirupsen/logrus"
+    "go.opentelemetry.io/otel/attribute"
+)
+
+// runShouldComplete waits until the previous workflow run has completed and its post-completion wait has passed.
+func runShouldComplete(sess *session, opts *options) error {
+
+    // waiting - and the calls made while waiting - end with --timeout:
+    ctx := sess.ctx
+
+    if opts.timeout > 0 {
+
+        var cancel context.CancelFunc
+
+        ctx, cancel = context.WithTimeout(ctx, time.Duration(opts.timeout)*time.Second)
+        defer cancel()
+    }
+
+    progress := &completeProgress{opts: opts, now: sess.now}
+
+    gate, err := sess.gate(progress.onEvent)
+
+    if err != nil {
+        return err
+    }
+
+    sess.rootSpan.SetAttributes(attribute.Int("sorter.previous_run_id", opts.previousRunId))
+
+    // dry-run reports what would happen on this check and stops - no waiting:
+    if opts.dryRun {
+
+        lastRun, checkErr := gate.Check(ctx, int64(opts.previousRunId))
+
+        if checkErr != nil {
+            return checkErr
+        }
+
+        explainComplete(os.Stderr, opts.previousRunId, lastRun, opts.waitBeforeComplete, sess.now())
+
+        return nil
+    }
+
+    // expose metrics while waiting:
+    if opts.metricsListen != "" {
+        server := metrics.Serve(opts.metricsListen)
+        defer server.Close()
+    }
+
+    // with --coordinate one process of the run attempt waits - the others wait on it and are released with it:
+    var completion sorter.Completion
+    var waitErr error
+
+    shared, coordinateErr := sess.coordinate(ctx, fmt.Sprintf("should-complete/%s/%s/%d", opts.owner, opts.repo, opts.previousRunId), &completion, func() error {
+
+        // gather duration statistics once - estimates are refreshed on every check. Runs of the workflow can only
+        // be listed by its file:
+        if opts.estimateEta && opts.workflowFile == "" {
+
+            log.WithFields(log.Fields{
+                "previousRunId": opts.previousRunId,
+            }).Warn("--estimate-eta needs --workflow-file to gather run durations - release time will not be estimated")
+
+        } else if opts.estimateEta {
+
+            fields := log.Fields{"previousRunId": opts.previousRunId}
+
+            if predecessor, checkErr := gate.Check(ctx, int64(opts.previousRunId)); checkErr == nil {
+                progress.basis = gatherEtaHistory(ctx, gate, opts, predecessor, fields)
+            } else {
+                log.WithFields(fields).Warn("unable to gather run durations - release time will not be estimated")
+            }
+        }
+
+        completion, waitErr = gate.WaitForTurn(ctx, int64(opts.previousRunId))
+
+        return waitErr
+    })
+
+    // waiting on another process ended with --timeout like waiting on the previous run:
+    if coordinateErr != nil && waitErr == nil {
+        waitErr = coordinateErr
+    }
+
+    sess.rootSpan.SetAttributes(attribute.Bool("sorter.coordination_shared", shared))
+
+    progress.endGroup()
+
+    // record the time actually waited - also when waiting ended early. A process released along with another
+    // waited on it rather than on the previous run:
+    if !shared {
+        metrics.ObserveWait(metrics.PhasePredecessor, completion.PredecessorWaited)
+        metrics.ObserveWait(metrics.PhasePostCompletion, completion.PostCompletionWaited)
+    }
+
+    if errors.Is(waitErr, sorter.ErrPredecessorFailed) {
+        metrics.ObserveDecision("shouldComplete", "predecessor_failed")
+    }
+
+    if waitErr != nil {
+        return waitError(waitErr, opts.previousRunId, opts.timeout)
+    }
+
+    metrics.ObserveDecision("shouldComplete", "complete")
+
+    if shared {
+        log.WithFields(log.Fields{
+            "repo":             opts.repo,
+            "owner":            opts.owner,
+            "currentRunNumber": opts.runNumber,
+            "releasedAt":       completion.ReleasedAt,
+        }).Info("Good to complete this workflow - released along with another process of this run ...")
+    }
+
+    if opts.explain {
+        explainComplete(os.Stderr, opts.previousRunId, completion.Predecessor, completion.WaitBeforeComplete.Seconds(), completion.ReleasedAt)
+    }
+
+    if opts.stepSummary {
+        writeStepSummary(util.StepSummary{
+            Mode:        "shouldComplete",
+            RunNumber:   opts.runNumber,
+            Decision:    "complete",
+            Rule:        completion.Rule,
+            Predecessor: &util.RunTrace{ID: int64(opts.previousRunId), Status: completion.Predecessor.GetStatus(), HTMLURL: util.RunURL(opts.owner, opts.repo, int64(opts.previousRunId))},
+            Waits: []util.PhaseWait{
+                {Phase: metrics.PhasePredecessor, Waited: completion.PredecessorWaited},
+                {Phase: metrics.PhasePostCompletion, Waited: completion.PostCompletionWaited},
+            },
+        })
+    }
+
+    return nil
+}
+
+// completeProgress logs the progress of waiting for the previous run and records wait metrics.
+type completeProgress struct {
+    opts            *options
+    now             func() time.Time
+    basis           etaBasis
+    estimateWritten bool
+    group           string
+}
+
+func (p *completeProgress) onEvent(event sorter.Event) {
+
+    fields := log.Fields{
+        "repo":             p.opts.repo,
+        "owner":            p.opts.owner,
+        "previousRunId":    p.opts.previousRunId,
+        "currentRunNumber": p.opts.runNumber,
+    }
+
+    switch event.Type {
+
+    case sorter.EventCheckFailed:
+        log.WithFields(fields).Error(event.Err.Error())
+
+    case sorter.EventChecked:
+        p.logEstimate(event.Run)
+
+    // repetitive wait output is folded into one group per phase:
+    case sorter.EventWaiting:
+        p.startGroup(event.Phase, fmt.Sprintf("waiting on previous run %d to complete", p.opts.previousRunId))
+
+        log.WithFields(fields).Info("must sleep - waiting on previous run to complete ...")
+
+    case sorter.EventAttemptChanged:
+        log.WithFields(fields).WithField("runAttempt", event.Run.GetRunAttempt()).Warn(event.Message)
+
+    case sorter.EventRerunGraceWaiting:
+        p.startGroup(event.Phase, fmt.Sprintf("checking previous run %d for a re-run", p.opts.previousRunId))
+
+        log.WithFields(fields).Info(event.Message)
+
+    case sorter.EventPredecessorCompleted:
+        p.endGroup()
+
+    case sorter.EventPostCompletionSkipped:
+        log.WithFields(fields).Info(event.Message)
+
+    case sorter.EventPostCompletionWaiting:
+        p.startGroup(event.Phase, fmt.Sprintf("waiting post-completion of previous run %d", p.opts.previousRunId))
+
+        log.WithFields(fields).Info("must sleep - waiting post-completion of previous workflow run ...")
+        log.WithFields(fields).Info(fmt.Sprintf("sleeping for %f seconds ...", event.Wait.Seconds()))
+
+    case sorter.EventReleased:
+        p.endGroup()
+
+        log.WithFields(log.Fields{
+            "repo":             p.opts.repo,
+            "owner":            p.opts.owner,
+            "currentRunNumber": p.opts.runNumber,
+        }).Info("Good to complete this workflow ...")
+    }
+}
+
+// logEstimate estimates the release time on every check and makes the first estimate available to later steps.
+func (p *completeProgress) logEstimate(run *github.WorkflowRun) {
+
+    if !p.opts.estimateEta {
+        return
+    }
+
+    now := p.now()
+
+    estimate, ok := p.basis.estimate(run.GetStatus(), run.UpdatedAt, p.opts.waitBeforeComplete, now)
+
+    if !ok {
+        return
+    }
+
+    fields := log.Fields{"previousRunId": p.opts.previousRunId, "currentRunNumber": p.opts.runNumber}
+
+    logEstimate(fields, p.basis, estimate, now)
+
+    if !p.estimateWritten {
+        writeEstimateOutputs(estimate, now, fields)
+        p.estimateWritten = true
+    }
+}
+
+func (p *completeProgress) startGroup(phase string, title string) {
+
+    if p.group == phase {
+        return
+    }
+
+    p.endGroup()
+
+    logging.StartGroup(title)
+    p.group = phase
+}
+
+func (p *completeProgress) endGroup() {
+
+    if p.group != "" {
+        logging.EndGroup()
+        p.group = ""
+    }
+}
+
+// explainComplete states the rule 'should-complete' applies given the current state of the previous run.
+func explainComplete(w io.Writer, previousRunId int, lastRun *github.WorkflowRun, waitBeforeComplete float64, now time.Time) {
+
+    completedAt := lastRun.GetUpdatedAt().Time
+
+    fmt.Fprintf(w, "previous run id %d: status=%s updated_at=%s\n", previousRunId, lastRun.GetStatus(), completedAt.Format(time.RFC3339))
+    fmt.Fprintf(w, "rule: %s\n", sorter.CompleteRule(lastRun.GetStatus(), completedAt, time.Duration(waitBeforeComplete*float64(time.Second)), now))
+}
+
+// writeStepSummary reports a decision on the run's summary page - failures are logged and never change the outcome.
+func writeStepSummary(summary util.StepSummary) {
+
+    if err := util.WriteStepSummary(summary); err != nil {
+        log.WithFields(log.Fields{
+            "runNumber": summary.RunNumber,
+        }).Warn(fmt.Sprintf("failed to write step summary: %s", err.Error()))
+    }
+}
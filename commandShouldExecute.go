@@ -0,0 +1,132 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "strconv"
+
+    util "gh-actions-workflow-runs-sorter/util"
+    metrics "gh-actions-workflow-runs-sorter/metrics"
+    sorter "gh-actions-workflow-runs-sorter/sorter"
+
+    log "github.com/sirupsen/logrus"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+)
+
+// runShouldExecute checks should the workflow execute - and exports the decision for the 'should-complete' command.
+func runShouldExecute(sess *session, opts *options) error {
+
+    gate, err := sess.gate(nil)
+
+    if err != nil {
+        return err
+    }
+
+    // with --coordinate every process of the run attempt gets the decision the first one made:
+    var decision sorter.Decision
+    var decideErr error
+
+    shared, coordinateErr := sess.coordinate(sess.ctx, fmt.Sprintf("should-execute/%s/%s/%s/%s/%d", opts.owner, opts.repo, opts.workflowFile, opts.branch, opts.runNumber), &decision, func() error {
+        decision, decideErr = gate.Decide(sess.ctx)
+        return decideErr
+    })
+
+    // waiting on another process ended before it decided:
+    if coordinateErr != nil && decideErr == nil {
+        return coordinateErr
+    }
+
+    sess.rootSpan.SetAttributes(attribute.Bool("sorter.coordination_shared", shared))
+
+    // print the full decision trace - to stderr so exported variables on stdout stay clean:
+    if (opts.explain || opts.dryRun) && !errors.As(decideErr, new(*sorter.APIError)) {
+        util.WriteExecuteTrace(os.Stderr, executeTrace(decision.Trace))
+    }
+
+    if decideErr != nil {
+        metrics.ObserveDecision("shouldExecute", "error")
+
+        sess.rootSpan.RecordError(decideErr)
+        sess.rootSpan.SetStatus(codes.Error, decideErr.Error())
+
+        log.WithFields(log.Fields{
+            "repo":         opts.repo,
+            "owner":        opts.owner,
+            "workflowFile": opts.workflowFile,
+            "workflowRunsToReturn": opts.workflowRunsToReturn,
+        }).Error(decideErr.Error())
+
+        return decideErr
+    }
+
+    if decision.Superseder != nil {
+        log.WithFields(log.Fields{
+            "runNumber": opts.runNumber,
+        }).Warn(fmt.Sprintf("There's no need to re-run this workflow run; latest 'future' workflow run has completed with id %d\n", decision.Superseder.GetRunNumber()))
+    }
+
+    if len(decision.Runs) < opts.workflowRunsToReturn {
+        log.WithFields(log.Fields{
+            "runNumber":               opts.runNumber,
+            "number of previous runs": len(decision.Runs),
+        }).Warn(fmt.Sprintf("Number of workflow runs recieved from API is less than %d.", opts.workflowRunsToReturn))
+    }
+
+    shouldRunExecute := strconv.FormatBool(decision.Execute())
+    shouldWaitForPastRun := strconv.FormatBool(decision.WaitForPastRun())
+    pastRunIdStr := strconv.FormatInt(decision.PastRunID, 10)
+
+    log.WithFields(log.Fields{
+        "runNumber": opts.runNumber,
+    }).Info(fmt.Sprintf("updating data with SHOULD_RUN_EXECUTE = %s; SHOULD_WAIT_FOR_PAST_RUN = %s; PAST_RUN_ID = %s\n", shouldRunExecute, shouldWaitForPastRun, pastRunIdStr))
+
+    metrics.ObserveDecision("shouldExecute", decision.Outcome)
+
+    sess.rootSpan.SetAttributes(
+        attribute.String("sorter.decision", decision.Outcome),
+        attribute.String("sorter.past_run_id", pastRunIdStr),
+    )
+
+    if opts.stepSummary && !opts.dryRun {
+        writeStepSummary(util.NewExecuteStepSummary(executeTrace(decision.Trace), decision.Outcome))
+    }
+
+    // with --exit-codes a superseded run ends with its own exit code - once the outputs were emitted:
+    var skipErr error
+
+    if opts.exitCodes && !decision.Execute() {
+        skipErr = withExitCode(exitSkip, fmt.Errorf("run #%d should not execute: %s", opts.runNumber, decision.Trace.Rule))
+    }
+
+    // dry-run stops short of emitting outputs:
+    if opts.dryRun {
+        log.WithFields(log.Fields{
+            "runNumber": opts.runNumber,
+        }).Info("dry-run - outputs were not emitted")
+
+        return skipErr
+    }
+
+    // export the decision to the environment:
+    fmt.Printf("export SHOULD_RUN_EXECUTE=%s\n", shouldRunExecute)
+    fmt.Printf("export SHOULD_WAIT_FOR_PAST_RUN=%s\n", shouldWaitForPastRun)
+    fmt.Printf("export PAST_RUN_ID=%s\n", pastRunIdStr)
+
+    // estimate the release time from the --eta-history most recent runs:
+    if opts.estimateEta && decision.WaitForPastRun() {
+
+        fields := log.Fields{"runNumber": opts.runNumber, "pastRunId": pastRunIdStr}
+        basis := gatherEtaHistory(sess.ctx, gate, opts, decision.Predecessor, fields)
+        now := sess.now()
+
+        if estimate, ok := basis.estimate(decision.Predecessor.GetStatus(), decision.Predecessor.UpdatedAt, opts.waitBeforeComplete, now); ok {
+
+            logEstimate(fields, basis, estimate, now)
+            writeEstimateOutputs(estimate, now, fields)
+        }
+    }
+
+    return skipErr
+}
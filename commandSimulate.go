@@ -0,0 +1,43 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "time"
+
+    sorter "gh-actions-workflow-runs-sorter/sorter"
+    util "gh-actions-workflow-runs-sorter/util"
+)
+
+// runSimulate computes the decision 'should-execute' would have made for the run when the snapshot was captured,
+// under the policies configured now - and what 'should-complete' would have done about its predecessor.
+func runSimulate(sess *session, opts *options) error {
+
+    gate, err := sess.gate(nil)
+
+    if err != nil {
+        return err
+    }
+
+    snapshot := opts.snapshot
+
+    fmt.Printf("snapshot of %s/%s %s on %s captured at %s (%d runs)\n\n", snapshot.Owner, snapshot.Repo, snapshot.Workflow, snapshot.Branch, snapshot.CapturedAt.Format(time.RFC3339), len(snapshot.Runs))
+
+    decision, err := gate.Decide(sess.ctx)
+
+    if err != nil && !errors.Is(err, sorter.ErrNoRuns) {
+        return err
+    }
+
+    util.WriteExecuteTrace(os.Stdout, executeTrace(decision.Trace))
+
+    if decision.WaitForPastRun() && decision.Predecessor != nil {
+
+        completedAt := decision.Predecessor.GetUpdatedAt().Time
+
+        fmt.Printf("should-complete: %s\n", sorter.CompleteRule(decision.Predecessor.GetStatus(), completedAt, time.Duration(opts.waitBeforeComplete*float64(time.Second)), sess.now()))
+    }
+
+    return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    config "gh-actions-workflow-runs-sorter/config"
+    policysim "gh-actions-workflow-runs-sorter/policysim"
+
+    log "github.com/sirupsen/logrus"
+)
+
+// policySpecs collects repeated --policy flags.
+type policySpecs []string
+
+func (p *policySpecs) String() string {
+
+    return strings.Join(*p, " ")
+}
+
+func (p *policySpecs) Set(value string) error {
+
+    *p = append(*p, value)
+
+    return nil
+}
+
+// runSimulatePolicies simulates random workloads under each policy and prints the results side by side.
+func runSimulatePolicies(sess *session, opts *options) error {
+
+    base := policysim.Policy{
+        CompletionPolicy:   opts.completionPolicy,
+        WaitBetweenChecks:  time.Duration(opts.waitBetweenChecks)*time.Second,
+        WaitBeforeComplete: time.Duration(opts.waitBeforeComplete*float64(time.Second)),
+        RunsToReturn:       opts.workflowRunsToReturn,
+    }
+
+    policies := []policysim.Policy{}
+
+    for _, spec := range opts.policies {
+
+        policy, err := parsePolicy(base, spec)
+
+        if err != nil {
+            return withExitCode(exitUsage, err)
+        }
+
+        policies = append(policies, policy)
+    }
+
+    // without --policy every completion policy is compared on the settings of the other flags:
+    if len(policies) == 0 {
+
+        for _, completionPolicy := range []string{config.CompletionAlways, config.CompletionSuccess, config.CompletionFail} {
+
+            policy := base
+            policy.Name = "completion-policy=" + completionPolicy
+            policy.CompletionPolicy = completionPolicy
+
+            policies = append(policies, policy)
+        }
+    }
+
+    // thousands of simulated runs would otherwise log every API call:
+    if log.GetLevel() < log.DebugLevel {
+        defer log.SetLevel(log.GetLevel())
+        log.SetLevel(log.WarnLevel)
+    }
+
+    results, err := policysim.Run(policysim.Options{
+        Workload: policysim.Workload{
+            Runs:           opts.simRuns,
+            MeanInterval:   opts.simMeanInterval,
+            MeanDuration:   opts.simMeanDuration,
+            DurationStdDev: opts.simDurationStdDev,
+            FailureRate:    opts.simFailureRate,
+        },
+        Policies:        policies,
+        Trials:          opts.simTrials,
+        Seed:            opts.simSeed,
+        StarvationAfter: opts.simStarvationAfter,
+    })
+
+    if err != nil {
+        return withExitCode(exitUsage, err)
+    }
+
+    fmt.Printf("%d trials of %d runs - a run every %s on average taking %s (±%s), %.0f%% failing - seed %d\n\n",
+        opts.simTrials, opts.simRuns, opts.simMeanInterval, opts.simMeanDuration, opts.simDurationStdDev, opts.simFailureRate*100, opts.simSeed)
+
+    policysim.WriteResults(os.Stdout, results)
+
+    return nil
+}
+
+// parsePolicy applies a spec such as 'completion-policy=success,wait-between-checks=30s' to base - a 'name' key
+// names the policy, which is otherwise named after the spec.
+func parsePolicy(base policysim.Policy, spec string) (policysim.Policy, error) {
+
+    policy := base
+    policy.Name = spec
+
+    for _, setting := range strings.Split(spec, ",") {
+
+        parts := strings.SplitN(strings.TrimSpace(setting), "=", 2)
+
+        if len(parts) != 2 {
+            return policy, fmt.Errorf("Invalid policy '%s': '%s' is not a key=value setting", spec, setting)
+        }
+
+        key, value := parts[0], parts[1]
+
+        var err error
+
+        switch key {
+
+        case "name":
+            policy.Name = value
+
+        case "completion-policy":
+            policy.CompletionPolicy = value
+
+        case "wait-between-checks":
+            policy.WaitBetweenChecks, err = parseSeconds(value)
+
+        case "wait-before-complete":
+            policy.WaitBeforeComplete, err = parseSeconds(value)
+
+        case "workflow-runs-to-return":
+            policy.RunsToReturn, err = strconv.Atoi(value)
+
+        default:
+            return policy, fmt.Errorf("Invalid policy '%s': unknown setting '%s' - expected name, completion-policy, wait-between-checks, wait-before-complete or workflow-runs-to-return", spec, key)
+        }
+
+        if err != nil {
+            return policy, fmt.Errorf("Invalid policy '%s': '%s' is not a valid %s", spec, value, key)
+        }
+    }
+
+    return policy, nil
+}
+
+// parseSeconds accepts a Go duration ('90s', '5m') or a number of seconds, like the configuration file.
+func parseSeconds(value string) (time.Duration, error) {
+
+    if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+        return time.Duration(seconds*float64(time.Second)), nil
+    }
+
+    return time.ParseDuration(value)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+    "os"
+
+    gh "gh-actions-workflow-runs-sorter/gh"
+
+    log "github.com/sirupsen/logrus"
+)
+
+// runSnapshot dumps the most recent runs of the workflow on the branch for 'simulate'.
+func runSnapshot(sess *session, opts *options) error {
+
+    workflow, err := sess.workflow()
+
+    if err != nil {
+        return err
+    }
+
+    snapshot, err := gh.TakeSnapshot(sess.ctx, sess.api, opts.owner, opts.repo, workflow, opts.branch)
+
+    if err != nil {
+        log.WithFields(log.Fields{
+            "repo":         opts.repo,
+            "owner":        opts.owner,
+            "workflowFile": opts.workflowFile,
+            "branch":       opts.branch,
+        }).Error(err.Error())
+
+        return err
+    }
+
+    if opts.output == "" {
+
+        data, err := snapshot.Marshal()
+
+        if err != nil {
+            return err
+        }
+
+        _, err = os.Stdout.Write(data)
+
+        return err
+    }
+
+    if err := snapshot.Save(opts.output); err != nil {
+        return err
+    }
+
+    log.WithFields(log.Fields{
+        "output": opts.output,
+        "runs":   len(snapshot.Runs),
+    }).Info("Snapshot written ...")
+
+    return nil
+}
@@ -0,0 +1,303 @@
+package config
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "path"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "gopkg.in/yaml.v3"
+)
+
+// DefaultPath is where the configuration file is looked up when no path is passed.
+const DefaultPath = ".github/sorter.yml"
+
+// completion policies - when to wait post-completion of the previous run:
+const (
+    CompletionAlways  = "always"
+    CompletionSuccess = "success"
+    CompletionFail    = "fail"
+)
+
+// PolicyKeys are the settings a configuration file can set, in the order they are applied.
+var PolicyKeys = []string{
+    "wait_between_checks",
+    "wait_before_complete",
+    "workflow_run_to_return",
+    "events",
+    "completion_policy",
+}
+
+// PolicyFlags maps each of PolicyKeys to the command line flag it sets.
+var PolicyFlags = map[string]string{
+    "wait_between_checks":    "wait-between-checks",
+    "wait_before_complete":   "wait-before-complete",
+    "workflow_run_to_return": "workflow-runs-to-return",
+    "events":                 "events",
+    "completion_policy":      "completion-policy",
+}
+
+// Duration accepts either a Go duration ("90s", "5m") or a number of seconds.
+type Duration struct {
+    time.Duration
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+
+    if seconds, err := strconv.ParseFloat(node.Value, 64); err == nil {
+        d.Duration = time.Duration(seconds*float64(time.Second))
+        return nil
+    }
+
+    parsed, err := time.ParseDuration(node.Value)
+
+    if err != nil {
+        return fmt.Errorf("line %d: '%s' is not a duration (e.g. '90s', '5m') or a number of seconds", node.Line, node.Value)
+    }
+
+    d.Duration = parsed
+
+    return nil
+}
+
+// Policy is a set of settings - any of which may be left unset to inherit from a less specific level.
+type Policy struct {
+    WaitBetweenChecks    *Duration `yaml:"wait_between_checks"`
+    WaitBeforeComplete   *Duration `yaml:"wait_before_complete"`
+    WorkflowRunsToReturn *int      `yaml:"workflow_run_to_return"`
+    Events               []string  `yaml:"events"`
+    CompletionPolicy     *string   `yaml:"completion_policy"`
+}
+
+// WorkflowPolicy is the policy of a single workflow file, with optional per-branch overrides.
+type WorkflowPolicy struct {
+    Policy   `yaml:",inline"`
+    Branches map[string]Policy `yaml:"branches"`
+}
+
+// Config is the content of a configuration file. Branch keys may be glob patterns (e.g. 'release/*').
+type Config struct {
+    Defaults  Policy                    `yaml:"defaults"`
+    Branches  map[string]Policy         `yaml:"branches"`
+    Workflows map[string]WorkflowPolicy `yaml:"workflows"`
+}
+
+// Load reads and validates the configuration file at path - unknown keys and invalid values are errors.
+func Load(filePath string) (*Config, error) {
+
+    data, err := os.ReadFile(filePath)
+
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read config file %s: %s", filePath, err.Error())
+    }
+
+    config := &Config{}
+
+    decoder := yaml.NewDecoder(bytes.NewReader(data))
+    decoder.KnownFields(true)
+
+    if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+        return nil, fmt.Errorf("Invalid config file %s: %s", filePath, err.Error())
+    }
+
+    if err := config.Validate(); err != nil {
+        return nil, fmt.Errorf("Invalid config file %s: %s", filePath, err.Error())
+    }
+
+    return config, nil
+}
+
+// Validate reports every invalid value along with where it was found (e.g. 'workflows.release.yml.branches.main.wait_before_complete').
+func (c *Config) Validate() error {
+
+    problems := c.Defaults.validate("defaults")
+
+    for _, branch := range sortedKeys(c.Branches) {
+        problems = append(problems, c.Branches[branch].validate("branches."+branch)...)
+    }
+
+    for _, workflow := range sortedKeys(c.Workflows) {
+
+        problems = append(problems, c.Workflows[workflow].Policy.validate("workflows."+workflow)...)
+
+        for _, branch := range sortedKeys(c.Workflows[workflow].Branches) {
+            problems = append(problems, c.Workflows[workflow].Branches[branch].validate("workflows."+workflow+".branches."+branch)...)
+        }
+    }
+
+    if len(problems) > 0 {
+        return fmt.Errorf("%s", strings.Join(problems, "; "))
+    }
+
+    return nil
+}
+
+func (p Policy) validate(at string) []string {
+
+    problems := []string{}
+
+    if p.WaitBetweenChecks != nil && p.WaitBetweenChecks.Duration < time.Second {
+        problems = append(problems, fmt.Sprintf("%s.wait_between_checks: must be at least 1s", at))
+    }
+
+    if p.WaitBeforeComplete != nil && p.WaitBeforeComplete.Duration < 0 {
+        problems = append(problems, fmt.Sprintf("%s.wait_before_complete: must not be negative", at))
+    }
+
+    if p.WorkflowRunsToReturn != nil && (*p.WorkflowRunsToReturn < 1 || *p.WorkflowRunsToReturn > 100) {
+        problems = append(problems, fmt.Sprintf("%s.workflow_run_to_return: must be between 1 and 100", at))
+    }
+
+    if p.CompletionPolicy != nil && !ValidCompletionPolicy(*p.CompletionPolicy) {
+        problems = append(problems, fmt.Sprintf("%s.completion_policy: must be '%s', '%s' or '%s'", at, CompletionAlways, CompletionSuccess, CompletionFail))
+    }
+
+    for _, event := range p.Events {
+
+        if strings.TrimSpace(event) == "" {
+            problems = append(problems, fmt.Sprintf("%s.events: must not contain empty values", at))
+        }
+    }
+
+    return problems
+}
+
+// ValidCompletionPolicy reports whether policy is one of the completion policies.
+func ValidCompletionPolicy(policy string) bool {
+
+    return policy == CompletionAlways || policy == CompletionSuccess || policy == CompletionFail
+}
+
+// Resolve merges the policies that apply to workflow on branch - from least to most specific:
+// defaults, branches, workflows and the workflow's branches. A workflow given by its path matches the key of its
+// file name.
+func (c *Config) Resolve(workflow string, branch string) Policy {
+
+    policy := c.Defaults
+
+    for _, pattern := range sortedKeys(c.Branches) {
+
+        if matchBranch(pattern, branch) {
+            policy = policy.merge(c.Branches[pattern])
+        }
+    }
+
+    if key, ok := c.WorkflowKey(workflow); ok {
+
+        workflowPolicy := c.Workflows[key]
+        policy = policy.merge(workflowPolicy.Policy)
+
+        for _, pattern := range sortedKeys(workflowPolicy.Branches) {
+
+            if matchBranch(pattern, branch) {
+                policy = policy.merge(workflowPolicy.Branches[pattern])
+            }
+        }
+    }
+
+    return policy
+}
+
+// WorkflowKey returns the key of 'workflows' that workflow - a file name or path - matches.
+func (c *Config) WorkflowKey(workflow string) (string, bool) {
+
+    for _, key := range []string{workflow, path.Base(workflow)} {
+
+        if _, ok := c.Workflows[key]; ok && key != "" {
+            return key, true
+        }
+    }
+
+    return "", false
+}
+
+// Values returns the settings of p as flag values (durations in seconds), keyed by setting name.
+func (p Policy) Values() map[string]string {
+
+    values := map[string]string{}
+
+    if p.WaitBetweenChecks != nil {
+        values["wait_between_checks"] = strconv.Itoa(int(p.WaitBetweenChecks.Seconds()))
+    }
+
+    if p.WaitBeforeComplete != nil {
+        values["wait_before_complete"] = strconv.FormatFloat(p.WaitBeforeComplete.Seconds(), 'f', -1, 64)
+    }
+
+    if p.WorkflowRunsToReturn != nil {
+        values["workflow_run_to_return"] = strconv.Itoa(*p.WorkflowRunsToReturn)
+    }
+
+    if p.Events != nil {
+        values["events"] = strings.Join(p.Events, ",")
+    }
+
+    if p.CompletionPolicy != nil {
+        values["completion_policy"] = *p.CompletionPolicy
+    }
+
+    return values
+}
+
+// EnvName is the environment variable that sets flagName, e.g. SORTER_WAIT_BEFORE_COMPLETE.
+func EnvName(flagName string) string {
+
+    return "SORTER_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(flagName))
+}
+
+// merge returns p overridden by every setting o sets.
+func (p Policy) merge(o Policy) Policy {
+
+    if o.WaitBetweenChecks != nil {
+        p.WaitBetweenChecks = o.WaitBetweenChecks
+    }
+
+    if o.WaitBeforeComplete != nil {
+        p.WaitBeforeComplete = o.WaitBeforeComplete
+    }
+
+    if o.WorkflowRunsToReturn != nil {
+        p.WorkflowRunsToReturn = o.WorkflowRunsToReturn
+    }
+
+    if o.Events != nil {
+        p.Events = o.Events
+    }
+
+    if o.CompletionPolicy != nil {
+        p.CompletionPolicy = o.CompletionPolicy
+    }
+
+    return p
+}
+
+// matchBranch matches branch against an exact name or a glob pattern.
+func matchBranch(pattern string, branch string) bool {
+
+    if pattern == branch {
+        return true
+    }
+
+    matched, err := path.Match(pattern, branch)
+
+    return err == nil && matched
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+
+    keys := make([]string, 0, len(m))
+
+    for key := range m {
+        keys = append(keys, key)
+    }
+
+    sort.Strings(keys)
+
+    return keys
+}
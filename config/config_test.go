@@ -0,0 +1,162 @@
+package config
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+const testConfig = `
+defaults:
+  wait_between_checks: 10s
+  wait_before_complete: 60
+  workflow_run_to_return: 20
+branches:
+  release/*:
+    completion_policy: success
+workflows:
+  release.yml:
+    wait_before_complete: 5m
+    events: [push]
+    branches:
+      main:
+        workflow_run_to_return: 50
+`
+
+func writeConfig(t *testing.T, content string) string {
+
+    t.Helper()
+
+    path := filepath.Join(t.TempDir(), "sorter.yml")
+
+    if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+        t.Fatalf("failed to write config: '%v'", err)
+    }
+
+    return path
+}
+
+func TestResolve(t *testing.T){
+
+    config, err := Load(writeConfig(t, testConfig))
+
+    if err != nil {
+        t.Fatalf("Load() returned error: '%v'", err)
+    }
+
+    tests := []struct {
+        name       string
+        workflow   string
+        branch     string
+        wantValues map[string]string
+    }{
+        {
+            name:     "defaults only",
+            workflow: "build.yml",
+            branch:   "main",
+            wantValues: map[string]string{
+                "wait_between_checks":    "10",
+                "wait_before_complete":   "60",
+                "workflow_run_to_return": "20",
+            },
+        },
+        {
+            name:     "branch glob",
+            workflow: "build.yml",
+            branch:   "release/1.2",
+            wantValues: map[string]string{
+                "wait_between_checks":    "10",
+                "wait_before_complete":   "60",
+                "workflow_run_to_return": "20",
+                "completion_policy":      "success",
+            },
+        },
+        {
+            name:     "workflow and workflow branch override",
+            workflow: "release.yml",
+            branch:   "main",
+            wantValues: map[string]string{
+                "wait_between_checks":    "10",
+                "wait_before_complete":   "300",
+                "workflow_run_to_return": "50",
+                "events":                 "push",
+            },
+        },
+        {
+            name:     "workflow given by its path",
+            workflow: ".github/workflows/release.yml",
+            branch:   "main",
+            wantValues: map[string]string{
+                "wait_between_checks":    "10",
+                "wait_before_complete":   "300",
+                "workflow_run_to_return": "50",
+                "events":                 "push",
+            },
+        },
+        {
+            name:     "workflow given by a display name matches no key",
+            workflow: "Release",
+            branch:   "main",
+            wantValues: map[string]string{
+                "wait_between_checks":    "10",
+                "wait_before_complete":   "60",
+                "workflow_run_to_return": "20",
+            },
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            gotValues := config.Resolve(tt.workflow, tt.branch).Values()
+
+            if !reflect.DeepEqual(gotValues, tt.wantValues) {
+                t.Errorf("Resolve() failed - expects %v but received %v", tt.wantValues, gotValues)
+            }
+
+        })
+    }
+
+}
+
+func TestLoadRejectsInvalidConfig(t *testing.T){
+
+    tests := []struct {
+        name    string
+        content string
+        wantErr string
+    }{
+        {
+            name:    "unknown key",
+            content: "defaults:\n  wait_beforecomplete: 60\n",
+            wantErr: "field wait_beforecomplete not found",
+        },
+        {
+            name:    "invalid duration",
+            content: "defaults:\n  wait_between_checks: soon\n",
+            wantErr: "'soon' is not a duration",
+        },
+        {
+            name:    "invalid values reported with their location",
+            content: "workflows:\n  release.yml:\n    branches:\n      main:\n        workflow_run_to_return: 500\n        completion_policy: never\n",
+            wantErr: "workflows.release.yml.branches.main.workflow_run_to_return: must be between 1 and 100; workflows.release.yml.branches.main.completion_policy: must be 'always', 'success' or 'fail'",
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            _, err := Load(writeConfig(t, tt.content))
+
+            if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+                t.Errorf("Load() returned error: '%v' expect '%s'", err, tt.wantErr)
+            }
+
+        })
+    }
+
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "strconv"
+    "time"
+
+    util "gh-actions-workflow-runs-sorter/util"
+    gh "gh-actions-workflow-runs-sorter/gh"
+    sorter "gh-actions-workflow-runs-sorter/sorter"
+
+    "github.com/google/go-github/v47/github"
+    log "github.com/sirupsen/logrus"
+)
+
+// etaBasis is what release estimates are computed from - duration statistics of recent runs (or of one of their jobs)
+// and when the predecessor (or its job) started.
+type etaBasis struct {
+    stats   util.DurationStats
+    started time.Time
+    level   string
+}
+
+// gatherEtaBasis computes duration statistics from the completed runs in history and finds when predecessor started.
+// With jobName set, statistics of that job are used instead of whole runs - falling back to whole runs when
+// the predecessor's job has not started yet or no samples were found.
+func gatherEtaBasis(ctx context.Context, api gh.WorkflowRunsAPI, owner string, repo string, history []*github.WorkflowRun, predecessor *github.WorkflowRun, jobName string, historyLimit int) etaBasis {
+
+    basis := etaBasis{
+        stats:   util.RunDurationStats(history),
+        started: util.RunStartedAt(predecessor),
+        level:   "workflow",
+    }
+
+    if jobName == "" {
+        return basis
+    }
+
+    predecessorJobs, err := gh.ReturnWorkflowJobs(ctx, api, owner, repo, int(predecessor.GetID()))
+
+    if err != nil {
+        log.WithFields(log.Fields{
+            "job": jobName,
+        }).Warn(fmt.Sprintf("falling back to workflow run durations: %s", err.Error()))
+
+        return basis
+    }
+
+    jobStarted := time.Time{}
+
+    for _, job := range predecessorJobs {
+
+        if job.GetName() == jobName && job.StartedAt != nil {
+            jobStarted = job.StartedAt.Time
+        }
+    }
+
+    if jobStarted.IsZero() {
+        return basis
+    }
+
+    jobs := []*github.WorkflowJob{}
+    visited := 0
+
+    for _, run := range history {
+
+        if visited >= historyLimit {
+            break
+        }
+
+        if run.GetStatus() != "completed" || run.GetID() == predecessor.GetID() {
+            continue
+        }
+
+        visited++
+
+        runJobs, err := gh.ReturnWorkflowJobs(ctx, api, owner, repo, int(run.GetID()))
+
+        if err != nil {
+            continue
+        }
+
+        jobs = append(jobs, runJobs...)
+    }
+
+    jobStats := util.JobDurationStats(jobs, jobName)
+
+    if jobStats.Samples == 0 {
+        return basis
+    }
+
+    return etaBasis{stats: jobStats, started: jobStarted, level: "job"}
+}
+
+// estimate returns the release estimate for a predecessor in lastRunStatus; false when there's nothing to estimate from.
+func (b etaBasis) estimate(lastRunStatus string, lastRunUpdateTime *github.Timestamp, waitBeforeComplete float64, now time.Time) (util.Estimate, bool) {
+
+    wait := time.Duration(waitBeforeComplete*float64(time.Second))
+
+    if lastRunStatus == "completed" {
+        return util.EstimateReleaseAfterCompletion(lastRunUpdateTime.Time, wait, now), true
+    }
+
+    if b.stats.Samples == 0 || b.started.IsZero() {
+        return util.Estimate{}, false
+    }
+
+    return util.EstimateRelease(b.started, b.stats, wait, now), true
+}
+
+// logEstimate logs an estimate in the wording used for notifications (e.g. "release expected in ~7 min").
+func logEstimate(fields log.Fields, basis etaBasis, estimate util.Estimate, now time.Time) {
+
+    fields["estimateBasis"] = basis.level
+    fields["samples"] = basis.stats.Samples
+    fields["p50"] = basis.stats.P50.String()
+    fields["p90"] = basis.stats.P90.String()
+    fields["predecessorDoneAt"] = estimate.PredecessorDoneP50.Format(time.RFC3339)
+
+    log.WithFields(fields).Info(fmt.Sprintf("release expected in ~%d min (p90 ~%d min) at %s", minutesUntil(estimate.ReleaseP50, now), minutesUntil(estimate.ReleaseP90, now), estimate.ReleaseP50.Format(time.RFC3339)))
+}
+
+// gatherEtaHistory lists the --eta-history most recent runs and computes the basis of estimates for predecessor
+// from them - without duration statistics when they cannot be listed.
+func gatherEtaHistory(ctx context.Context, gate *sorter.Gate, opts *options, predecessor *github.WorkflowRun, fields log.Fields) etaBasis {
+
+    history, err := gate.Runs(ctx, opts.etaHistory)
+
+    if err != nil {
+        log.WithFields(fields).Warn(fmt.Sprintf("unable to gather run durations - only the release time of a completed previous run will be estimated: %s", err.Error()))
+
+        return etaBasis{started: util.RunStartedAt(predecessor), level: "workflow"}
+    }
+
+    return gatherEtaBasis(ctx, gate.Options().API, opts.owner, opts.repo, history, predecessor, opts.etaJob, opts.etaHistory)
+}
+
+// writeEstimateOutputs writes the variables describing an estimate to $GITHUB_OUTPUT as step outputs - failures
+// are logged and never change the outcome.
+func writeEstimateOutputs(estimate util.Estimate, now time.Time, fields log.Fields) {
+
+    outputs := [][2]string{
+        {"ESTIMATED_RELEASE_AT", estimate.ReleaseP50.Format(time.RFC3339)},
+        {"ESTIMATED_RELEASE_AT_P90", estimate.ReleaseP90.Format(time.RFC3339)},
+        {"ESTIMATED_RELEASE_SECONDS", strconv.Itoa(int(estimate.ReleaseP50.Sub(now).Seconds()))},
+    }
+
+    for _, output := range outputs {
+
+        if err := util.WriteGithubOutput(output[0], output[1]); err != nil {
+            log.WithFields(fields).Warn(fmt.Sprintf("failed to write %s to $GITHUB_OUTPUT: %s", output[0], err.Error()))
+            return
+        }
+    }
+}
+
+func minutesUntil(t time.Time, now time.Time) int {
+
+    return int(t.Sub(now).Round(time.Minute).Minutes())
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+
+    sorter "gh-actions-workflow-runs-sorter/sorter"
+
+    log "github.com/sirupsen/logrus"
+)
+
+// exit codes used with --exit-codes - see "Exit codes" in README.md before changing any of them:
+const (
+    exitProceed           = 0
+    exitFailure           = 1
+    exitUsage             = 2
+    exitSkip              = 10
+    exitTimedOut          = 11
+    exitPredecessorFailed = 12
+    exitConfigError       = 20
+    exitAPIError          = 21
+    exitCancelled         = 130
+)
+
+// exitPanicked is the status every failure ends with without --exit-codes - that of the panics earlier versions
+// ended with, which callers may check for.
+const exitPanicked = 2
+
+// exitError is an error that ends a command with a specific exit code.
+type exitError struct {
+    code int
+    err  error
+}
+
+func (e *exitError) Error() string {
+
+    return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+
+    return e.err
+}
+
+func withExitCode(code int, err error) error {
+
+    return &exitError{code: code, err: err}
+}
+
+// exitCodeOf returns the exit code err ends a command with - exitFailure when it carries none.
+func exitCodeOf(err error) int {
+
+    var exitErr *exitError
+
+    switch {
+
+    case errors.As(err, &exitErr):
+        return exitErr.code
+
+    case errors.Is(err, sorter.ErrPredecessorFailed):
+        return exitPredecessorFailed
+
+    case errors.Is(err, context.Canceled):
+        return exitCancelled
+
+    case errors.Is(err, context.DeadlineExceeded):
+        return exitTimedOut
+    }
+
+    var apiErr *sorter.APIError
+
+    if errors.As(err, &apiErr) {
+        return exitAPIError
+    }
+
+    return exitFailure
+}
+
+// reportExit logs the one message err ends a command with and returns its exit code.
+func reportExit(commandName string, err error) int {
+
+    code := exitCodeOf(err)
+
+    fields := log.Fields{
+        "command":  commandName,
+        "exitCode": code,
+    }
+
+    if code == exitSkip {
+        log.WithFields(fields).Warn(err.Error())
+    } else {
+        log.WithFields(fields).Error(err.Error())
+    }
+
+    return code
+}
+
+// waitError describes why waiting on previousRunId ended early.
+func waitError(err error, previousRunId int, timeout int) error {
+
+    switch {
+
+    case errors.Is(err, context.DeadlineExceeded):
+        return withExitCode(exitTimedOut, fmt.Errorf("timed out after %ds waiting on previous run %d", timeout, previousRunId))
+
+    case errors.Is(err, context.Canceled):
+        return withExitCode(exitCancelled, fmt.Errorf("cancelled while waiting on previous run %d", previousRunId))
+    }
+
+    return err
+}
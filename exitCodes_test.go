@@ -0,0 +1,151 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+    "testing"
+
+    sorter "gh-actions-workflow-runs-sorter/sorter"
+
+    log "github.com/sirupsen/logrus"
+)
+
+func TestExitCodeOf(t *testing.T){
+
+    tests := []struct {
+        name      string
+        err       error
+        wantCode  int
+        wantLevel string
+    }{
+        {
+            name:      "previous run failed",
+            err:       fmt.Errorf("previous run 3333333333 concluded 'failure' - %w", sorter.ErrPredecessorFailed),
+            wantCode:  exitPredecessorFailed,
+            wantLevel: "error",
+        },
+        {
+            name:      "deadline exceeded",
+            err:       context.DeadlineExceeded,
+            wantCode:  exitTimedOut,
+            wantLevel: "error",
+        },
+        {
+            name:      "cancelled",
+            err:       context.Canceled,
+            wantCode:  exitCancelled,
+            wantLevel: "error",
+        },
+        {
+            name:      "API error",
+            err:       &sorter.APIError{Err: errors.New("Workflow not found")},
+            wantCode:  exitAPIError,
+            wantLevel: "error",
+        },
+        {
+            name:      "exit code carried by the error",
+            err:       withExitCode(exitConfigError, errors.New("No Github token found")),
+            wantCode:  exitConfigError,
+            wantLevel: "error",
+        },
+        {
+            name:      "exit code carried by the error wins over the error it wraps",
+            err:       withExitCode(exitAPIError, fmt.Errorf("Failed to list workflows: %w", context.DeadlineExceeded)),
+            wantCode:  exitAPIError,
+            wantLevel: "error",
+        },
+        {
+            name:      "skip is reported as a warning",
+            err:       withExitCode(exitSkip, errors.New("run #30 should not execute")),
+            wantCode:  exitSkip,
+            wantLevel: "warning",
+        },
+        {
+            name:      "plain error",
+            err:       errors.New("something went wrong"),
+            wantCode:  exitFailure,
+            wantLevel: "error",
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            var out bytes.Buffer
+
+            log.SetOutput(&out)
+            log.SetFormatter(&log.TextFormatter{DisableColors: true, DisableTimestamp: true})
+
+            if gotCode := exitCodeOf(tt.err); gotCode != tt.wantCode {
+                t.Errorf("exitCodeOf() failed - expects %d but received %d", tt.wantCode, gotCode)
+            }
+
+            if gotCode := reportExit("should-complete", tt.err); gotCode != tt.wantCode {
+                t.Errorf("reportExit() failed - expects %d but received %d", tt.wantCode, gotCode)
+            }
+
+            if !strings.Contains(out.String(), "level="+tt.wantLevel) || !strings.Contains(out.String(), fmt.Sprintf("exitCode=%d", tt.wantCode)) {
+                t.Errorf("reportExit() failed - expects a '%s' with exitCode=%d but logged '%s'", tt.wantLevel, tt.wantCode, out.String())
+            }
+
+        })
+    }
+
+}
+
+func TestWaitError(t *testing.T){
+
+    tests := []struct {
+        name        string
+        err         error
+        wantCode    int
+        wantMessage string
+    }{
+        {
+            name:        "timed out",
+            err:         context.DeadlineExceeded,
+            wantCode:    exitTimedOut,
+            wantMessage: "timed out after 600s waiting on previous run 3333333333",
+        },
+        {
+            name:        "cancelled",
+            err:         fmt.Errorf("check failed: %w", context.Canceled),
+            wantCode:    exitCancelled,
+            wantMessage: "cancelled while waiting on previous run 3333333333",
+        },
+        {
+            name:        "previous run failed is reported as is",
+            err:         fmt.Errorf("previous run 3333333333 concluded 'failure' - %w", sorter.ErrPredecessorFailed),
+            wantCode:    exitPredecessorFailed,
+            wantMessage: "previous run 3333333333 concluded 'failure'",
+        },
+        {
+            name:        "API error is reported as is",
+            err:         &sorter.APIError{Err: errors.New("Not Found")},
+            wantCode:    exitAPIError,
+            wantMessage: "Not Found",
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            gotErr := waitError(tt.err, 3333333333, 600)
+
+            if gotCode := exitCodeOf(gotErr); gotCode != tt.wantCode {
+                t.Errorf("waitError() failed - exit code expects %d but received %d", tt.wantCode, gotCode)
+            }
+
+            if !strings.Contains(gotErr.Error(), tt.wantMessage) {
+                t.Errorf("waitError() failed - message expects '%s' but received '%s'", tt.wantMessage, gotErr.Error())
+            }
+
+        })
+    }
+
+}
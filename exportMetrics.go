@@ -0,0 +1,32 @@
+package main
+
+import (
+    "fmt"
+
+    metrics "gh-actions-workflow-runs-sorter/metrics"
+
+    log "github.com/sirupsen/logrus"
+)
+
+// exportMetrics writes metrics to a textfile and/or pushes them to a Pushgateway - failures are logged
+// and never change the outcome of the run.
+func exportMetrics(textfile string, pushURL string, pushJob string, grouping map[string]string) {
+
+    if textfile != "" {
+
+        if err := metrics.WriteTextfile(textfile); err != nil {
+            log.WithFields(log.Fields{
+                "textfile": textfile,
+            }).Warn(fmt.Sprintf("failed to write metrics: %s", err.Error()))
+        }
+    }
+
+    if pushURL != "" {
+
+        if err := metrics.Push(pushURL, pushJob, grouping); err != nil {
+            log.WithFields(log.Fields{
+                "pushURL": pushURL,
+            }).Warn(fmt.Sprintf("failed to push metrics: %s", err.Error()))
+        }
+    }
+}
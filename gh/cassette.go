@@ -0,0 +1,322 @@
+package gh
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "strings"
+    "sync"
+    "time"
+)
+
+// CassetteVersion is the format version written to and accepted from cassette files.
+const CassetteVersion = 1
+
+// scrubbedHeaders never make it into a cassette - their values are replaced by 'REDACTED'.
+var scrubbedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// scrubbedParams are query parameters whose values never make it into a cassette.
+var scrubbedParams = []string{"access_token", "client_secret"}
+
+// Cassette is a recording of the Github API interactions of one invocation - attach it to a bug report
+// and the decision can be reproduced offline with a Replayer.
+type Cassette struct {
+    Version      int           `json:"version"`
+    RecordedAt   time.Time     `json:"recorded_at"`
+    Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one request and the response (or transport error) it got.
+type Interaction struct {
+    RecordedAt time.Time           `json:"recorded_at"`
+    Method     string              `json:"method"`
+    URL        string              `json:"url"`
+    Request    map[string][]string `json:"request_headers,omitempty"`
+    StatusCode int                 `json:"status_code,omitempty"`
+    Headers    map[string][]string `json:"response_headers,omitempty"`
+    Body       string              `json:"body,omitempty"`
+    Error      string              `json:"error,omitempty"`
+}
+
+// LoadCassette reads a cassette written by a Recorder.
+func LoadCassette(path string) (*Cassette, error) {
+
+    data, err := os.ReadFile(path)
+
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read cassette %s: %s", path, err.Error())
+    }
+
+    var cassette Cassette
+
+    if err := json.Unmarshal(data, &cassette); err != nil {
+        return nil, fmt.Errorf("Failed to parse cassette %s: %s", path, err.Error())
+    }
+
+    if cassette.Version != CassetteVersion {
+        return nil, fmt.Errorf("Cassette %s has version %d - expected %d", path, cassette.Version, CassetteVersion)
+    }
+
+    return &cassette, nil
+}
+
+// Save writes the cassette as indented JSON, readable only by the current user.
+func (c *Cassette) Save(path string) error {
+
+    data, err := json.MarshalIndent(c, "", "  ")
+
+    if err != nil {
+        return fmt.Errorf("Failed to encode cassette: %s", err.Error())
+    }
+
+    if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+        return fmt.Errorf("Failed to write cassette %s: %s", path, err.Error())
+    }
+
+    return nil
+}
+
+// Recorder is a transport that passes requests on to Base and records every interaction, with credentials scrubbed.
+type Recorder struct {
+    Base http.RoundTripper
+
+    mu       sync.Mutex
+    cassette Cassette
+    now      func() time.Time
+}
+
+func NewRecorder(base http.RoundTripper) *Recorder {
+
+    if base == nil {
+        base = http.DefaultTransport
+    }
+
+    now := time.Now
+
+    return &Recorder{
+        Base:     base,
+        cassette: Cassette{Version: CassetteVersion, RecordedAt: now().UTC()},
+        now:      now,
+    }
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+
+    res, err := r.Base.RoundTrip(req)
+
+    interaction := Interaction{
+        RecordedAt: r.now().UTC(),
+        Method:     req.Method,
+        URL:        scrubURL(req.URL.RequestURI()),
+        Request:    scrubHeaders(req.Header),
+    }
+
+    if err != nil {
+        interaction.Error = err.Error()
+    }
+
+    if res != nil {
+
+        body, readErr := io.ReadAll(res.Body)
+        res.Body.Close()
+
+        if readErr != nil {
+            return nil, readErr
+        }
+
+        // the caller still reads the body:
+        res.Body = io.NopCloser(bytes.NewReader(body))
+
+        interaction.StatusCode = res.StatusCode
+        interaction.Headers = scrubHeaders(res.Header)
+        interaction.Body = string(body)
+    }
+
+    r.mu.Lock()
+    r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+    r.mu.Unlock()
+
+    return res, err
+}
+
+// Cassette returns a copy of everything recorded so far.
+func (r *Recorder) Cassette() Cassette {
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    cassette := r.cassette
+    cassette.Interactions = append([]Interaction{}, r.cassette.Interactions...)
+
+    return cassette
+}
+
+// Save writes everything recorded so far to path.
+func (r *Recorder) Save(path string) error {
+
+    cassette := r.Cassette()
+
+    return cassette.Save(path)
+}
+
+// Replayer is a transport serving the interactions of a cassette instead of calling the API. Requests are matched
+// on method and URL (path and query); repeated requests get the recorded responses in order and ErrNotRecorded once
+// they run out - so a run polled three times while recording reports the same three states when replayed.
+//
+// A Replayer is also a clock: Now is the time the last served interaction was recorded plus whatever was slept
+// since, and Sleep returns at once - so waits play out as recorded without taking any time. Once a request went past
+// the end of its recordings Sleep fails with ErrNotRecorded too, so polling never outlasts the cassette.
+type Replayer struct {
+    mu        sync.Mutex
+    queues    map[string][]Interaction
+    served    map[string]int
+    now       time.Time
+    slept     time.Duration
+    exhausted string
+}
+
+// ErrNotRecorded is returned for requests the cassette has no (more) interactions for.
+var ErrNotRecorded = errors.New("no recorded interaction")
+
+func NewReplayer(cassette *Cassette) *Replayer {
+
+    r := &Replayer{
+        queues: map[string][]Interaction{},
+        served: map[string]int{},
+        now:    cassette.RecordedAt,
+    }
+
+    for _, interaction := range cassette.Interactions {
+        key := interaction.Method + " " + interaction.URL
+        r.queues[key] = append(r.queues[key], interaction)
+    }
+
+    if len(cassette.Interactions) > 0 {
+        r.now = cassette.Interactions[0].RecordedAt
+    }
+
+    return r
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+
+    key := req.Method + " " + scrubURL(req.URL.RequestURI())
+
+    r.mu.Lock()
+
+    queue := r.queues[key]
+
+    if len(queue) == 0 {
+        r.mu.Unlock()
+        return nil, fmt.Errorf("%w for %s", ErrNotRecorded, key)
+    }
+
+    index := r.served[key]
+
+    if index >= len(queue) {
+        r.exhausted = key
+        r.mu.Unlock()
+        return nil, fmt.Errorf("%w for %s - all %d recordings were replayed", ErrNotRecorded, key, len(queue))
+    }
+
+    r.served[key]++
+
+    interaction := queue[index]
+
+    // time never goes backwards - requests made after a sleep may be replayed from earlier recordings:
+    r.now, r.slept = r.now.Add(r.slept), 0
+
+    if interaction.RecordedAt.After(r.now) {
+        r.now = interaction.RecordedAt
+    }
+
+    r.mu.Unlock()
+
+    if interaction.Error != "" {
+        return nil, errors.New(interaction.Error)
+    }
+
+    return &http.Response{
+        Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+        StatusCode:    interaction.StatusCode,
+        Proto:         "HTTP/1.1",
+        ProtoMajor:    1,
+        ProtoMinor:    1,
+        Header:        http.Header(interaction.Headers).Clone(),
+        Body:          io.NopCloser(strings.NewReader(interaction.Body)),
+        ContentLength: int64(len(interaction.Body)),
+        Request:       req,
+    }, nil
+}
+
+func (r *Replayer) Now() time.Time {
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    return r.now.Add(r.slept)
+}
+
+func (r *Replayer) Sleep(ctx context.Context, d time.Duration) error {
+
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if r.exhausted != "" {
+        return fmt.Errorf("%w for %s - the replay went past the end of the cassette", ErrNotRecorded, r.exhausted)
+    }
+
+    r.slept += d
+
+    return nil
+}
+
+func scrubHeaders(headers http.Header) map[string][]string {
+
+    if len(headers) == 0 {
+        return nil
+    }
+
+    scrubbed := headers.Clone()
+
+    for _, name := range scrubbedHeaders {
+
+        if scrubbed.Get(name) != "" {
+            scrubbed.Set(name, "REDACTED")
+        }
+    }
+
+    return scrubbed
+}
+
+func scrubURL(uri string) string {
+
+    parts := strings.SplitN(uri, "?", 2)
+
+    if len(parts) < 2 {
+        return uri
+    }
+
+    params := strings.Split(parts[1], "&")
+
+    for i, param := range params {
+
+        for _, name := range scrubbedParams {
+
+            if strings.HasPrefix(param, name+"=") {
+                params[i] = name + "=REDACTED"
+            }
+        }
+    }
+
+    return parts[0] + "?" + strings.Join(params, "&")
+}
@@ -0,0 +1,197 @@
+package gh
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/google/go-github/v47/github"
+    log "github.com/sirupsen/logrus"
+)
+
+func TestCassette(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    base, mux, _, teardown := Setup()
+    defer teardown()
+
+    polls := 0
+
+    mux.HandleFunc("/repos/testowner/testrepo/actions/runs/1111111111", func(w http.ResponseWriter, r *http.Request) {
+
+        polls++
+
+        status := "in_progress"
+
+        if polls > 1 {
+            status = "completed"
+        }
+
+        w.Header().Set("Set-Cookie", "session=secret")
+        fmt.Fprintf(w, `{"id": 1111111111, "status": "%s"}`, status)
+    })
+
+    tests := []struct {
+        name        string
+        replayPolls int
+        wantStatus  []string
+    }{
+        {
+            name:        "should replay recorded responses in order",
+            replayPolls: 2,
+            wantStatus:  []string{"in_progress", "completed"},
+        },
+        {
+            name:        "should fail once the recorded responses run out",
+            replayPolls: 4,
+            wantStatus:  []string{"in_progress", "completed"},
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            polls = 0
+
+            recorder := NewRecorder(nil)
+
+            client := github.NewClient(&http.Client{Transport: &authTransport{base: recorder}})
+            client.BaseURL = base.BaseURL
+
+            for i := 0; i < 2; i++ {
+                if _, _, err := client.Actions.GetWorkflowRunByID(context.Background(), "testowner", "testrepo", 1111111111); err != nil {
+                    t.Fatalf("recording failed - %s", err.Error())
+                }
+            }
+
+            path := filepath.Join(t.TempDir(), "cassette.json")
+
+            if err := recorder.Save(path); err != nil {
+                t.Fatalf("Save() failed - %s", err.Error())
+            }
+
+            data, _ := ioutil.ReadFile(path)
+
+            if strings.Contains(string(data), "s3cr3t") || strings.Contains(string(data), "session=secret") {
+                t.Errorf("cassette should not contain credentials - received %s", string(data))
+            }
+
+            cassette, err := LoadCassette(path)
+
+            if err != nil {
+                t.Fatalf("LoadCassette() failed - %s", err.Error())
+            }
+
+            replayer := NewReplayer(cassette)
+
+            replayClient := github.NewClient(&http.Client{Transport: replayer})
+            replayClient.BaseURL = base.BaseURL
+
+            got := []string{}
+
+            for i := 0; i < tt.replayPolls; i++ {
+
+                run, _, err := replayClient.Actions.GetWorkflowRunByID(context.Background(), "testowner", "testrepo", 1111111111)
+
+                if i >= len(tt.wantStatus) {
+
+                    if !errors.Is(err, ErrNotRecorded) {
+                        t.Errorf("replay past the recordings failed - expects %v but received %v", ErrNotRecorded, err)
+                    }
+
+                    continue
+                }
+
+                if err != nil {
+                    t.Fatalf("replay failed - %s", err.Error())
+                }
+
+                got = append(got, run.GetStatus())
+            }
+
+            if !reflect.DeepEqual(got, tt.wantStatus) {
+                t.Errorf("replay failed - expects %v but received %v", tt.wantStatus, got)
+            }
+
+            // waiting stops once the replay went past the end of the cassette:
+            wantSleepErr := tt.replayPolls > len(tt.wantStatus)
+
+            if err := replayer.Sleep(context.Background(), time.Minute); errors.Is(err, ErrNotRecorded) != wantSleepErr {
+                t.Errorf("Sleep() failed - expects an error %t but received %v", wantSleepErr, err)
+            }
+
+            if polls != 2 {
+                t.Errorf("replay should not call the API - expects 2 calls but received %d", polls)
+            }
+
+            _, _, err = replayClient.Actions.GetWorkflowRunByID(context.Background(), "testowner", "testrepo", 2222222222)
+
+            if !errors.Is(err, ErrNotRecorded) {
+                t.Errorf("replay of an unrecorded request failed - expects %v but received %v", ErrNotRecorded, err)
+            }
+
+        })
+    }
+}
+
+func TestReplayerClock(t *testing.T){
+
+    start := time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+
+    replayer := NewReplayer(&Cassette{
+        Version:    CassetteVersion,
+        RecordedAt: start,
+        Interactions: []Interaction{
+            {RecordedAt: start.Add(time.Second), Method: "GET", URL: "/first", StatusCode: 200},
+            {RecordedAt: start.Add(30 * time.Second), Method: "GET", URL: "/second", StatusCode: 200},
+        },
+    })
+
+    if got := replayer.Now(); !got.Equal(start.Add(time.Second)) {
+        t.Errorf("Now() before any request failed - expects %s but received %s", start.Add(time.Second), got)
+    }
+
+    replayer.Sleep(context.Background(), 10*time.Second)
+
+    if got := replayer.Now(); !got.Equal(start.Add(11 * time.Second)) {
+        t.Errorf("Now() after a sleep failed - expects %s but received %s", start.Add(11*time.Second), got)
+    }
+
+    req, _ := http.NewRequest("GET", "https://api.github.com/second", nil)
+    replayer.RoundTrip(req)
+
+    if got := replayer.Now(); !got.Equal(start.Add(30 * time.Second)) {
+        t.Errorf("Now() after a request failed - expects %s but received %s", start.Add(30*time.Second), got)
+    }
+
+    replayer.Sleep(context.Background(), 45*time.Second)
+
+    req, _ = http.NewRequest("GET", "https://api.github.com/first", nil)
+    replayer.RoundTrip(req)
+
+    if got := replayer.Now(); !got.Equal(start.Add(75 * time.Second)) {
+        t.Errorf("Now() should never go backwards - expects %s but received %s", start.Add(75*time.Second), got)
+    }
+}
+
+// authTransport adds credentials the way the oauth2 transport does.
+type authTransport struct {
+    base http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+    req = req.Clone(req.Context())
+    req.Header.Set("Authorization", "Bearer s3cr3t")
+
+    return t.base.RoundTrip(req)
+}
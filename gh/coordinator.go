@@ -0,0 +1,138 @@
+package gh
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "time"
+
+    log "github.com/sirupsen/logrus"
+)
+
+// DefaultCoordinationPollInterval is how often a process waiting on another one checks whether it is done.
+const DefaultCoordinationPollInterval = time.Second
+
+// Coordinator shares results between the processes of one workflow run attempt - e.g. the legs of a matrix job
+// calling the cli in the same step - so they behave as one. The first process to ask for a result computes it
+// while holding a lock file in Dir; the others wait on the lock instead of calling the API and reuse the result
+// it stored. Processes on one host share $RUNNER_TEMP; processes on different hosts need a shared Dir.
+type Coordinator struct {
+    Dir string
+
+    // Key identifies the run attempt - e.g. '<GITHUB_RUN_ID>-<GITHUB_RUN_ATTEMPT>'.
+    Key string
+
+    // PollInterval is how often a waiting process checks whether the result was stored.
+    PollInterval time.Duration
+
+    now func() time.Time
+}
+
+func NewCoordinator(dir string, key string) (*Coordinator, error) {
+
+    if key == "" {
+        return nil, fmt.Errorf("A coordination key is required - it defaults to $GITHUB_RUN_ID-$GITHUB_RUN_ATTEMPT inside Github Actions")
+    }
+
+    if err := os.MkdirAll(dir, 0o700); err != nil {
+        return nil, fmt.Errorf("Failed to create coordination directory %s: %s", dir, err.Error())
+    }
+
+    return &Coordinator{
+        Dir:          dir,
+        Key:          key,
+        PollInterval: DefaultCoordinationPollInterval,
+        now:          time.Now,
+    }, nil
+}
+
+// Do fills result with the result stored under name by another process of the run attempt - or calls compute to
+// fill it and stores it for the others. shared reports whether result came from another process. Failures are
+// not stored, so the next process to ask computes the result again. Waiting on another process ends with ctx.
+func (c *Coordinator) Do(ctx context.Context, name string, result interface{}, compute func() error) (shared bool, err error) {
+
+    path := filepath.Join(c.Dir, fmt.Sprintf("%x.json", sha256.Sum256([]byte(c.Key+"/"+name))))
+
+    waiting := false
+
+    for {
+
+        if c.load(path, name, result) {
+            return true, nil
+        }
+
+        unlock, locked, lockErr := tryLockFile(path + ".lock")
+
+        if lockErr != nil {
+
+            log.WithFields(log.Fields{
+                "coordinationDir": c.Dir,
+            }).Warn(fmt.Sprintf("Failed to lock coordination entry - continuing without coordination: %s", lockErr.Error()))
+
+            return false, compute()
+        }
+
+        if locked {
+            defer unlock()
+
+            // the process holding the lock before may have stored the result in the meantime:
+            if c.load(path, name, result) {
+                return true, nil
+            }
+
+            if err := compute(); err != nil {
+                return false, err
+            }
+
+            if err := writeEntry(path, result, c.now()); err != nil {
+
+                log.WithFields(log.Fields{
+                    "coordinationDir": c.Dir,
+                }).Warn(fmt.Sprintf("Failed to write coordination entry: %s", err.Error()))
+            }
+
+            return false, nil
+        }
+
+        if !waiting {
+
+            log.WithFields(log.Fields{
+                "coordinationKey": c.Key,
+                "name":            name,
+            }).Info("Another process of this run is on it - waiting for its result ...")
+
+            waiting = true
+        }
+
+        timer := time.NewTimer(c.PollInterval)
+
+        select {
+
+        case <-ctx.Done():
+            timer.Stop()
+            return false, ctx.Err()
+
+        case <-timer.C:
+        }
+    }
+}
+
+func (c *Coordinator) load(path string, name string, result interface{}) bool {
+
+    entry, ok := readEntry(path)
+
+    if !ok || json.Unmarshal(entry.Payload, result) != nil {
+        return false
+    }
+
+    log.WithFields(log.Fields{
+        "coordinationKey": c.Key,
+        "name":            name,
+        "storedAt":        entry.StoredAt,
+    }).Info("Reusing the result of another process of this run ...")
+
+    return true
+}
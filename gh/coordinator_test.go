@@ -0,0 +1,161 @@
+//go:build !windows
+
+package gh
+
+import (
+    "context"
+    "errors"
+    "io/ioutil"
+    "path/filepath"
+    "sync"
+    "testing"
+    "time"
+
+    log "github.com/sirupsen/logrus"
+)
+
+func TestCoordinatorDo(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    dir := t.TempDir()
+
+    var mu sync.Mutex
+    computed := 0
+
+    compute := func(result *string) func() error {
+        return func() error {
+
+            mu.Lock()
+            computed++
+            mu.Unlock()
+
+            // slow enough for every leg to ask while the first one computes:
+            time.Sleep(50 * time.Millisecond)
+
+            *result = "decided"
+            return nil
+        }
+    }
+
+    var wg sync.WaitGroup
+
+    results := make([]string, 5)
+    shared := make([]bool, 5)
+    errs := make([]error, 5)
+
+    for i := range results {
+
+        wg.Add(1)
+
+        go func(i int) {
+            defer wg.Done()
+
+            coordinator, _ := NewCoordinator(dir, "4711-1")
+            coordinator.PollInterval = 5 * time.Millisecond
+
+            shared[i], errs[i] = coordinator.Do(context.Background(), "should-execute", &results[i], compute(&results[i]))
+        }(i)
+    }
+
+    wg.Wait()
+
+    sharedCount := 0
+
+    for i := range results {
+
+        if errs[i] != nil || results[i] != "decided" {
+            t.Fatalf("Do() failed - expects 'decided' but received '%s' (%v)", results[i], errs[i])
+        }
+
+        if shared[i] {
+            sharedCount++
+        }
+    }
+
+    if computed != 1 || sharedCount != 4 {
+        t.Errorf("Do() failed - expects 1 leg to compute and 4 to reuse its result but %d computed and %d reused", computed, sharedCount)
+    }
+
+    // another attempt of the run computes its own result:
+    coordinator, _ := NewCoordinator(dir, "4711-2")
+
+    result := ""
+
+    if shared, _ := coordinator.Do(context.Background(), "should-execute", &result, compute(&result)); shared || computed != 2 {
+        t.Errorf("Do() failed - expects a new attempt to compute its own result")
+    }
+}
+
+func TestCoordinatorDoFailure(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    coordinator, _ := NewCoordinator(t.TempDir(), "4711-1")
+
+    result := ""
+    failure := errors.New("API call failed")
+
+    if _, err := coordinator.Do(context.Background(), "should-complete", &result, func() error { return failure }); !errors.Is(err, failure) {
+        t.Fatalf("Do() failed - expects the error of compute but received %v", err)
+    }
+
+    // a failure is not shared - the next leg computes again:
+    shared, err := coordinator.Do(context.Background(), "should-complete", &result, func() error {
+        result = "released"
+        return nil
+    })
+
+    if shared || err != nil || result != "released" {
+        t.Errorf("Do() failed - expects the next leg to compute after a failure but received '%s', shared %t (%v)", result, shared, err)
+    }
+}
+
+func TestCoordinatorDoCancelled(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    coordinator, _ := NewCoordinator(t.TempDir(), "4711-1")
+    coordinator.PollInterval = 5 * time.Millisecond
+
+    // another leg is computing:
+    first := make(chan struct{})
+    done := make(chan struct{})
+
+    go func() {
+        result := ""
+        coordinator.Do(context.Background(), "should-complete", &result, func() error {
+            close(first)
+            <-done
+            return errors.New("cancelled")
+        })
+    }()
+
+    <-first
+    defer close(done)
+
+    ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+    defer cancel()
+
+    result := ""
+
+    _, err := coordinator.Do(ctx, "should-complete", &result, func() error {
+        t.Errorf("Do() failed - expects no compute while another leg holds the lock")
+        return nil
+    })
+
+    if !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("Do() failed - expects the deadline to end waiting but received %v", err)
+    }
+}
+
+func TestNewCoordinatorErrors(t *testing.T){
+
+    if _, err := NewCoordinator(t.TempDir(), ""); err == nil {
+        t.Errorf("NewCoordinator() failed - expects an error without a key")
+    }
+
+    if _, err := NewCoordinator(filepath.Join("/dev/null", "dir"), "4711-1"); err == nil {
+        t.Errorf("NewCoordinator() failed - expects an error for a directory that cannot be created")
+    }
+}
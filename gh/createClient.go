@@ -2,7 +2,10 @@ package gh
 
 import (
     "context"
-    "os"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
 
     "github.com/google/go-github/v47/github"
     "golang.org/x/oauth2"
@@ -10,20 +13,49 @@ import (
     log "github.com/sirupsen/logrus"
 )
 
-func CreateClient() (context.Context, *github.Client) {
+// CreateClient creates the Github client for the REST API at apiURL (api.github.com when empty), authenticated
+// with token unless it is empty - API calls go through transport (e.g. a Recorder or Replayer), or straight to
+// the API when it is nil.
+func CreateClient(apiURL string, token string, transport http.RoundTripper) (context.Context, *github.Client, error) {
 
     log.WithFields(log.Fields{
     }).Info("Initializing Github client ...")
 
     ctx := context.Background()
 
-    ts := oauth2.StaticTokenSource(
-        &oauth2.Token{AccessToken: os.Getenv("GH_TOKEN")},
-    )
+    if transport == nil {
+        transport = http.DefaultTransport
+    }
 
-    tc := oauth2.NewClient(ctx, ts)
+    // oauth2 wraps the transport of this client - every API call gets recorded in metrics:
+    hc := &http.Client{
+        Transport: &instrumentedTransport{base: transport},
+    }
 
-    client := github.NewClient(tc)
+    ctx = context.WithValue(ctx, oauth2.HTTPClient, hc)
 
-    return ctx, client
+    if token != "" {
+
+        ts := oauth2.StaticTokenSource(
+            &oauth2.Token{AccessToken: token},
+        )
+
+        hc = oauth2.NewClient(ctx, ts)
+    }
+
+    client := github.NewClient(hc)
+
+    if apiURL != "" {
+
+        // relative endpoints are resolved against the base URL - it must end with a slash:
+        baseURL, err := url.Parse(strings.TrimSuffix(apiURL, "/") + "/")
+
+        if err != nil || baseURL.Scheme == "" || baseURL.Host == "" {
+            return nil, nil, fmt.Errorf("Invalid API URL '%s' - expected e.g. 'https://github.example.com/api/v3'", apiURL)
+        }
+
+        client.BaseURL = baseURL
+    }
+
+    return ctx, client, nil
 }
@@ -0,0 +1,403 @@
+package gh
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "path"
+    "sort"
+    "strings"
+
+    "github.com/google/go-github/v47/github"
+)
+
+// Check is one item of the checklist Diagnose returns - skipped when a check it depends on failed.
+type Check struct {
+    Name    string
+    OK      bool
+    Skipped bool
+    Detail  string
+    Hint    string
+}
+
+// DiagnoseOptions are the settings Diagnose checks.
+type DiagnoseOptions struct {
+    // TokenSource describes where the token came from (e.g. 'GH_TOKEN') - empty when there is no token.
+    TokenSource  string
+    Owner        string
+    Repo         string
+    WorkflowFile string
+    Branch       string
+
+    // MinRateLimitRemaining is the fewest API calls left in the rate limit that still pass.
+    MinRateLimitRemaining int
+}
+
+// Diagnose checks the most common causes of failed runs in order - the token, authentication and its scopes,
+// access to the repository and its workflows, the workflow, the branch, runs to order and rate-limit headroom.
+func Diagnose(ctx context.Context, client *github.Client, opts DiagnoseOptions) []Check {
+
+    checks := []Check{}
+
+    add := func(check Check) bool {
+        checks = append(checks, check)
+        return check.OK
+    }
+
+    skip := func(names ...string) []Check {
+
+        for _, name := range names {
+            checks = append(checks, Check{Name: name, Skipped: true, Detail: "skipped - an earlier check failed"})
+        }
+
+        return checks
+    }
+
+    if opts.TokenSource == "" {
+        add(Check{Name: "token", Detail: "no token found - only public repositories can be read, at 60 requests an hour", Hint: "set GH_TOKEN (e.g. 'GH_TOKEN: ${{ github.token }}' in the step's env), pass --token-file or --token-stdin, or log in with 'gh auth login'"})
+    } else {
+        add(Check{Name: "token", OK: true, Detail: fmt.Sprintf("token found in %s", opts.TokenSource)})
+    }
+
+    limits, res, err := client.RateLimits(ctx)
+
+    if !add(authenticationCheck(res, err, opts.TokenSource != "")) {
+        return skip("token scopes", "repository", "actions permission", "workflow", "branch", "workflow runs", "rate limit")
+    }
+
+    add(scopesCheck(res))
+
+    repository, res, err := client.Repositories.Get(ctx, opts.Owner, opts.Repo)
+
+    if !add(repositoryCheck(repository, res, err, opts)) {
+        return append(skip("actions permission", "workflow", "branch", "workflow runs"), rateLimitCheck(limits, opts.MinRateLimitRemaining))
+    }
+
+    workflows, res, err := ListWorkflows(ctx, client.Actions, opts.Owner, opts.Repo)
+
+    actionsOK := add(actionsCheck(res, err))
+
+    workflowOK := false
+    workflow := ""
+
+    if actionsOK {
+
+        var check Check
+        check, workflow = workflowCheck(workflows, opts)
+
+        workflowOK = add(check)
+    } else {
+        skip("workflow")
+    }
+
+    _, res, err = client.Repositories.GetBranch(ctx, opts.Owner, opts.Repo, opts.Branch, false)
+
+    branchOK := add(branchCheck(res, err, repository, opts.Branch))
+
+    if workflowOK && branchOK {
+
+        runs, _, err := client.Actions.ListWorkflowRunsByFileName(ctx, opts.Owner, opts.Repo, workflow, &github.ListWorkflowRunsOptions{Branch: opts.Branch, ListOptions: github.ListOptions{PerPage: 1}})
+
+        add(runsCheck(runs, err, opts))
+    } else {
+        skip("workflow runs")
+    }
+
+    add(rateLimitCheck(limits, opts.MinRateLimitRemaining))
+
+    return checks
+}
+
+func authenticationCheck(res *github.Response, err error, hasToken bool) Check {
+
+    check := Check{Name: "authentication"}
+
+    switch {
+
+    case statusCode(res) == http.StatusUnauthorized:
+        check.Detail = "the token was rejected (401 Bad credentials)"
+        check.Hint = "the token is invalid, expired or revoked - create a new one"
+
+    case err != nil:
+        check.Detail = fmt.Sprintf("the API could not be reached: %s", err.Error())
+        check.Hint = "check the network, proxy settings and --api-url"
+
+    case !hasToken:
+        check.OK = true
+        check.Detail = "the API was reached without a token"
+
+    default:
+        check.OK = true
+        check.Detail = "the API accepted the token"
+    }
+
+    return check
+}
+
+// scopesCheck reads the scopes of a classic personal access token - other tokens carry permissions instead,
+// which the checks of the repository and its workflows cover.
+func scopesCheck(res *github.Response) Check {
+
+    header := res.Header.Get("X-OAuth-Scopes")
+
+    if res.Header.Values("X-OAuth-Scopes") == nil {
+        return Check{Name: "token scopes", OK: true, Detail: "not a classic personal access token - permissions are checked below"}
+    }
+
+    scopes := map[string]bool{}
+
+    for _, scope := range strings.Split(header, ",") {
+        scopes[strings.TrimSpace(scope)] = true
+    }
+
+    if scopes["repo"] || scopes["public_repo"] {
+        return Check{Name: "token scopes", OK: true, Detail: fmt.Sprintf("classic token with scopes '%s'", header)}
+    }
+
+    return Check{
+        Name:   "token scopes",
+        Detail: fmt.Sprintf("classic token with scopes '%s' - workflow runs of private repositories cannot be read", header),
+        Hint:   "add the 'repo' scope (or 'public_repo' for public repositories only)",
+    }
+}
+
+func repositoryCheck(repository *github.Repository, res *github.Response, err error, opts DiagnoseOptions) Check {
+
+    check := Check{Name: "repository"}
+
+    switch {
+
+    case statusCode(res) == http.StatusNotFound:
+        check.Detail = fmt.Sprintf("%s/%s was not found - or the token cannot see it", opts.Owner, opts.Repo)
+        check.Hint = "check --owner and --repo, and that the token has access to the repository (fine-grained tokens and Github Apps list the repositories they can read)"
+
+    case err != nil:
+        check.Detail = fmt.Sprintf("%s/%s could not be read: %s", opts.Owner, opts.Repo, err.Error())
+
+    default:
+        check.OK = true
+        check.Detail = fmt.Sprintf("%s/%s is readable (%s, default branch '%s')", opts.Owner, opts.Repo, repository.GetVisibility(), repository.GetDefaultBranch())
+    }
+
+    return check
+}
+
+func actionsCheck(res *github.Response, err error) Check {
+
+    check := Check{Name: "actions permission"}
+
+    switch {
+
+    case statusCode(res) == http.StatusForbidden || statusCode(res) == http.StatusNotFound:
+        check.Detail = fmt.Sprintf("workflows cannot be listed (%d)", statusCode(res))
+        check.Hint = "grant the token 'actions: read' - e.g. 'permissions: actions: read' in the workflow, or the Actions read permission of a fine-grained token"
+
+    case err != nil:
+        check.Detail = fmt.Sprintf("workflows could not be listed: %s", err.Error())
+
+    default:
+        check.OK = true
+        check.Detail = "workflows can be listed - the token can read Actions"
+    }
+
+    return check
+}
+
+// workflowCheck finds the workflow and returns the identifier its runs are listed by.
+func workflowCheck(workflows []*github.Workflow, opts DiagnoseOptions) (Check, string) {
+
+    check := Check{Name: "workflow"}
+
+    workflow, err := FindWorkflow(workflows, opts.WorkflowFile)
+
+    if err != nil {
+
+        check.Detail = fmt.Sprintf("%s/%s: %s", opts.Owner, opts.Repo, err.Error())
+
+        if errors.Is(err, ErrWorkflowAmbiguous) {
+            check.Hint = "pass the file name (e.g. 'release.yml') or ID of the workflow to --workflow-file"
+        } else {
+            check.Hint = "pass the file name (e.g. 'release.yml'), path, ID or name of the workflow to --workflow-file"
+        }
+
+        return check, ""
+    }
+
+    check.OK = workflow.GetState() == "active"
+    check.Detail = fmt.Sprintf("'%s' is workflow '%s' (id %d, %s, %s)", opts.WorkflowFile, workflow.GetName(), workflow.GetID(), workflow.GetPath(), workflow.GetState())
+
+    if !check.OK {
+        check.Hint = "enable the workflow - disabled workflows start no new runs"
+    }
+
+    return check, WorkflowIdentifier(workflow)
+}
+
+func branchCheck(res *github.Response, err error, repository *github.Repository, branch string) Check {
+
+    check := Check{Name: "branch"}
+
+    switch {
+
+    case statusCode(res) == http.StatusNotFound:
+        check.Detail = fmt.Sprintf("branch '%s' was not found", branch)
+        check.Hint = fmt.Sprintf("check --branch - the default branch is '%s'", repository.GetDefaultBranch())
+
+    // a 301 means the branch was renamed:
+    case statusCode(res) == http.StatusMovedPermanently:
+        check.Detail = fmt.Sprintf("branch '%s' was renamed", branch)
+        check.Hint = "pass the new name of the branch to --branch"
+
+    case err != nil:
+        check.Detail = fmt.Sprintf("branch '%s' could not be read: %s", branch, err.Error())
+
+    default:
+        check.OK = true
+        check.Detail = fmt.Sprintf("branch '%s' exists", branch)
+    }
+
+    return check
+}
+
+func runsCheck(runs *github.WorkflowRuns, err error, opts DiagnoseOptions) Check {
+
+    check := Check{Name: "workflow runs"}
+
+    switch {
+
+    case err != nil:
+        check.Detail = fmt.Sprintf("runs could not be listed: %s", err.Error())
+
+    case runs.GetTotalCount() == 0:
+        check.Detail = fmt.Sprintf("'%s' has no runs on '%s' - there is nothing to order", opts.WorkflowFile, opts.Branch)
+        check.Hint = "check --branch and --workflow-file - runs are listed by the branch they ran on"
+
+    default:
+        check.OK = true
+        check.Detail = fmt.Sprintf("'%s' has %d runs on '%s'", opts.WorkflowFile, runs.GetTotalCount(), opts.Branch)
+    }
+
+    return check
+}
+
+func rateLimitCheck(limits *github.RateLimits, minRemaining int) Check {
+
+    check := Check{Name: "rate limit"}
+
+    core := limits.GetCore()
+
+    if core == nil {
+        check.Detail = "the rate limit could not be read"
+        return check
+    }
+
+    check.Detail = fmt.Sprintf("%d of %d requests left, resetting at %s", core.Remaining, core.Limit, core.Reset.Format("15:04:05 MST"))
+    check.OK = core.Remaining >= minRemaining
+
+    if !check.OK {
+        check.Hint = fmt.Sprintf("fewer than %d requests left - increase --wait-between-checks, share responses with --cache-dir or wait for the reset", minRemaining)
+    }
+
+    return check
+}
+
+func statusCode(res *github.Response) int {
+
+    if res == nil || res.Response == nil {
+        return 0
+    }
+
+    return res.StatusCode
+}
+
+// closeMatches returns the candidates within a few edits of name, closest first.
+func closeMatches(name string, candidates []string) []string {
+
+    type match struct {
+        candidate string
+        distance  int
+    }
+
+    matches := []match{}
+
+    stem := strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))
+
+    for _, candidate := range candidates {
+
+        candidateStem := strings.ToLower(strings.TrimSuffix(candidate, path.Ext(candidate)))
+        distance := editDistance(stem, candidateStem)
+
+        // a third of the name may differ - or the name is part of the candidate (e.g. 'release' of 'release-candidate.yml'):
+        if distance <= len(stem)/3 || strings.Contains(candidateStem, stem) {
+            matches = append(matches, match{candidate, distance})
+        }
+    }
+
+    sort.SliceStable(matches, func(i, j int) bool {
+        return matches[i].distance < matches[j].distance
+    })
+
+    names := []string{}
+    seen := map[string]bool{}
+
+    // a workflow named after its file is suggested once:
+    for _, match := range matches {
+
+        candidateStem := strings.ToLower(strings.TrimSuffix(match.candidate, path.Ext(match.candidate)))
+
+        if seen[candidateStem] || len(names) == 3 {
+            continue
+        }
+
+        seen[candidateStem] = true
+        names = append(names, match.candidate)
+    }
+
+    return names
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a string, b string) int {
+
+    previous := make([]int, len(b)+1)
+    current := make([]int, len(b)+1)
+
+    for j := range previous {
+        previous[j] = j
+    }
+
+    for i := 1; i <= len(a); i++ {
+
+        current[0] = i
+
+        for j := 1; j <= len(b); j++ {
+
+            cost := 1
+
+            if a[i-1] == b[j-1] {
+                cost = 0
+            }
+
+            current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+        }
+
+        previous, current = current, previous
+    }
+
+    return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+
+    min := values[0]
+
+    for _, value := range values[1:] {
+
+        if value < min {
+            min = value
+        }
+    }
+
+    return min
+}
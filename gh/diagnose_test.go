@@ -0,0 +1,212 @@
+package gh
+
+import (
+    "context"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "reflect"
+    "strings"
+    "testing"
+
+    log "github.com/sirupsen/logrus"
+)
+
+func TestDiagnose(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    type endpoint struct {
+        rateLimitStatus int
+        scopes          string
+        remaining       int
+        repoStatus      int
+        workflowsStatus int
+        branchStatus    int
+        totalRuns       int
+    }
+
+    ok := endpoint{rateLimitStatus: 200, remaining: 4000, repoStatus: 200, workflowsStatus: 200, branchStatus: 200, totalRuns: 12}
+
+    with := func(change func(*endpoint)) endpoint {
+        e := ok
+        change(&e)
+        return e
+    }
+
+    tests := []struct {
+        name         string
+        endpoint     endpoint
+        workflowFile string
+        wantResults  []string
+        wantHint     string
+    }{
+        {
+            name:         "should pass every check",
+            endpoint:     ok,
+            workflowFile: "release.yml",
+            wantResults:  []string{"PASS", "PASS", "PASS", "PASS", "PASS", "PASS", "PASS", "PASS", "PASS"},
+        },
+        {
+            name:         "should skip every check after a rejected token",
+            endpoint:     with(func(e *endpoint) { e.rateLimitStatus = 401 }),
+            workflowFile: "release.yml",
+            wantResults:  []string{"PASS", "FAIL", "SKIP", "SKIP", "SKIP", "SKIP", "SKIP", "SKIP", "SKIP"},
+            wantHint:     "invalid, expired or revoked",
+        },
+        {
+            name:         "should fail a classic token without the repo scope",
+            endpoint:     with(func(e *endpoint) { e.scopes = "read:org, workflow" }),
+            workflowFile: "release.yml",
+            wantResults:  []string{"PASS", "PASS", "FAIL", "PASS", "PASS", "PASS", "PASS", "PASS", "PASS"},
+            wantHint:     "'repo' scope",
+        },
+        {
+            name:         "should skip the workflow and its runs without access to the repository",
+            endpoint:     with(func(e *endpoint) { e.repoStatus = 404 }),
+            workflowFile: "release.yml",
+            wantResults:  []string{"PASS", "PASS", "PASS", "FAIL", "SKIP", "SKIP", "SKIP", "SKIP", "PASS"},
+            wantHint:     "check --owner and --repo",
+        },
+        {
+            name:         "should fail without the actions permission",
+            endpoint:     with(func(e *endpoint) { e.workflowsStatus = 403 }),
+            workflowFile: "release.yml",
+            wantResults:  []string{"PASS", "PASS", "PASS", "PASS", "FAIL", "SKIP", "PASS", "SKIP", "PASS"},
+            wantHint:     "actions: read",
+        },
+        {
+            name:         "should suggest close matches for an unknown workflow",
+            endpoint:     ok,
+            workflowFile: "relase.yml",
+            wantResults:  []string{"PASS", "PASS", "PASS", "PASS", "PASS", "FAIL", "PASS", "SKIP", "PASS"},
+            wantHint:     "did you mean 'release.yml'?",
+        },
+        {
+            name:         "should fail a disabled workflow",
+            endpoint:     ok,
+            workflowFile: "nightly.yml",
+            wantResults:  []string{"PASS", "PASS", "PASS", "PASS", "PASS", "FAIL", "PASS", "SKIP", "PASS"},
+            wantHint:     "enable the workflow",
+        },
+        {
+            name:         "should point to the default branch for an unknown branch",
+            endpoint:     with(func(e *endpoint) { e.branchStatus = 404 }),
+            workflowFile: "release.yml",
+            wantResults:  []string{"PASS", "PASS", "PASS", "PASS", "PASS", "PASS", "FAIL", "SKIP", "PASS"},
+            wantHint:     "the default branch is 'trunk'",
+        },
+        {
+            name:         "should fail a workflow without runs on the branch",
+            endpoint:     with(func(e *endpoint) { e.totalRuns = 0 }),
+            workflowFile: "release.yml",
+            wantResults:  []string{"PASS", "PASS", "PASS", "PASS", "PASS", "PASS", "PASS", "FAIL", "PASS"},
+            wantHint:     "runs are listed by the branch",
+        },
+        {
+            name:         "should fail a nearly exhausted rate limit",
+            endpoint:     with(func(e *endpoint) { e.remaining = 12 }),
+            workflowFile: "release.yml",
+            wantResults:  []string{"PASS", "PASS", "PASS", "PASS", "PASS", "PASS", "PASS", "PASS", "FAIL"},
+            wantHint:     "fewer than 100 requests left",
+        },
+    }
+
+    for _, tt := range tests {
+
+        client, mux, _, teardown := Setup()
+
+        e := tt.endpoint
+
+        mux.HandleFunc("/rate_limit", func(w http.ResponseWriter, r *http.Request) {
+            if e.scopes != "" {
+                w.Header().Set("X-OAuth-Scopes", e.scopes)
+            }
+            w.WriteHeader(e.rateLimitStatus)
+            fmt.Fprintf(w, `{"resources":{"core":{"limit":5000,"remaining":%d,"reset":1670976000}}}`, e.remaining)
+        })
+
+        mux.HandleFunc("/repos/testowner/testrepo", func(w http.ResponseWriter, r *http.Request) {
+            w.WriteHeader(e.repoStatus)
+            fmt.Fprint(w, `{"id":1,"name":"testrepo","visibility":"private","default_branch":"trunk"}`)
+        })
+
+        mux.HandleFunc("/repos/testowner/testrepo/actions/workflows", func(w http.ResponseWriter, r *http.Request) {
+            w.WriteHeader(e.workflowsStatus)
+            fmt.Fprint(w, `{"total_count":3,"workflows":[
+                {"id":1,"name":"Release","path":".github/workflows/release.yml","state":"active"},
+                {"id":2,"name":"Tests","path":".github/workflows/tests.yml","state":"active"},
+                {"id":3,"name":"Nightly","path":".github/workflows/nightly.yml","state":"disabled_manually"}]}`)
+        })
+
+        mux.HandleFunc("/repos/testowner/testrepo/branches/main", func(w http.ResponseWriter, r *http.Request) {
+            w.WriteHeader(e.branchStatus)
+            fmt.Fprint(w, `{"name":"main"}`)
+        })
+
+        mux.HandleFunc("/repos/testowner/testrepo/actions/workflows/release.yml/runs", func(w http.ResponseWriter, r *http.Request) {
+            fmt.Fprintf(w, `{"total_count":%d,"workflow_runs":[]}`, e.totalRuns)
+        })
+
+        checks := Diagnose(context.Background(), client, DiagnoseOptions{
+            TokenSource:           "GH_TOKEN",
+            Owner:                 "testowner",
+            Repo:                  "testrepo",
+            WorkflowFile:          tt.workflowFile,
+            Branch:                "main",
+            MinRateLimitRemaining: 100,
+        })
+
+        results := []string{}
+        hints := []string{}
+
+        for _, check := range checks {
+
+            switch {
+
+            case check.Skipped:
+                results = append(results, "SKIP")
+
+            case check.OK:
+                results = append(results, "PASS")
+
+            default:
+                results = append(results, "FAIL")
+            }
+
+            hints = append(hints, check.Detail+" "+check.Hint)
+        }
+
+        if !reflect.DeepEqual(results, tt.wantResults) {
+            t.Errorf("Diagnose() %s failed - expects %v but received %v", tt.name, tt.wantResults, results)
+        }
+
+        if tt.wantHint != "" && !strings.Contains(strings.Join(hints, "\n"), tt.wantHint) {
+            t.Errorf("Diagnose() %s failed - expects a detail or hint containing '%s' but received %q", tt.name, tt.wantHint, hints)
+        }
+
+        teardown()
+    }
+}
+
+func TestCloseMatches(t *testing.T){
+
+    candidates := []string{"release.yml", "release-candidate.yml", "tests.yml", "deploy.yaml"}
+
+    tests := []struct {
+        name string
+        want []string
+    }{
+        {name: "relase.yml", want: []string{"release.yml"}},
+        {name: "release.yaml", want: []string{"release.yml", "release-candidate.yml"}},
+        {name: "deploy.yml", want: []string{"deploy.yaml"}},
+        {name: "lint.yml", want: []string{}},
+    }
+
+    for _, tt := range tests {
+
+        if got := closeMatches(tt.name, candidates); !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("closeMatches(%s) failed - expects %v but received %v", tt.name, tt.want, got)
+        }
+    }
+}
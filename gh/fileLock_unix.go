@@ -0,0 +1,56 @@
+//go:build !windows
+
+package gh
+
+import (
+    "errors"
+    "os"
+    "syscall"
+)
+
+// lockFile takes an exclusive advisory lock on path, blocking until it is available.
+func lockFile(path string) (func(), error) {
+
+    f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+
+    if err != nil {
+        return nil, err
+    }
+
+    if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+        f.Close()
+        return nil, err
+    }
+
+    return func() {
+        syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+        f.Close()
+    }, nil
+}
+
+// tryLockFile takes an exclusive advisory lock on path if it is available - locked is false when another
+// process holds it.
+func tryLockFile(path string) (unlock func(), locked bool, err error) {
+
+    f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+
+    if err != nil {
+        return nil, false, err
+    }
+
+    if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+
+        f.Close()
+
+        if errors.Is(err, syscall.EWOULDBLOCK) {
+            return nil, false, nil
+        }
+
+        return nil, false, err
+    }
+
+    return func() {
+        syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+        f.Close()
+    }, true, nil
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package gh
+
+import (
+    "fmt"
+)
+
+// lockFile is not supported on windows - callers continue without a lock.
+func lockFile(path string) (func(), error) {
+    return nil, fmt.Errorf("file locking is not supported on windows")
+}
+
+// tryLockFile is not supported on windows - callers continue without a lock.
+func tryLockFile(path string) (func(), bool, error) {
+    return nil, false, fmt.Errorf("file locking is not supported on windows")
+}
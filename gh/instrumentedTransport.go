@@ -0,0 +1,106 @@
+package gh
+
+import (
+    "net/http"
+    "strconv"
+    "strings"
+
+    metrics "gh-actions-workflow-runs-sorter/metrics"
+    tracing "gh-actions-workflow-runs-sorter/tracing"
+
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+)
+
+// instrumentedTransport records every Github API call (endpoint, status and rate-limit remaining) in metrics
+// and as a span - a child of whatever span is in the request's context.
+type instrumentedTransport struct {
+    base http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+    endpoint := endpointLabel(req.URL.Path)
+
+    ctx, span := tracing.Start(req.Context(), req.Method+" "+endpoint,
+        attribute.String("http.method", req.Method),
+        attribute.String("github.endpoint", endpoint),
+    )
+    defer span.End()
+
+    res, err := t.base.RoundTrip(req.WithContext(ctx))
+
+    status, remaining := 0, -1
+
+    if res != nil {
+
+        status = res.StatusCode
+
+        if value, convErr := strconv.Atoi(res.Header.Get("X-RateLimit-Remaining")); convErr == nil {
+            remaining = value
+            span.SetAttributes(attribute.Int("github.rate_limit.remaining", remaining))
+        }
+
+        if value, convErr := strconv.Atoi(res.Header.Get("X-RateLimit-Limit")); convErr == nil {
+            span.SetAttributes(attribute.Int("github.rate_limit.limit", value))
+        }
+
+        span.SetAttributes(attribute.Int("http.status_code", status))
+
+        if status >= 400 {
+            span.SetStatus(codes.Error, res.Status)
+        }
+    }
+
+    if err != nil {
+        span.RecordError(err)
+        span.SetStatus(codes.Error, err.Error())
+    }
+
+    metrics.ObserveAPICall(endpoint, status, remaining)
+
+    return res, err
+}
+
+// endpointLabel turns a request path into a low-cardinality endpoint name, e.g.
+// '/api/v3/repos/octo/hello/actions/runs/42' becomes '/repos/{owner}/{repo}/actions/runs/{id}'.
+func endpointLabel(path string) string {
+
+    segments := strings.Split(strings.Trim(path, "/"), "/")
+
+    // drop any base path (e.g. '/api/v3' on Github Enterprise Server) before the first known resource:
+    for i, segment := range segments {
+
+        if segment == "repos" || segment == "rate_limit" || segment == "user" {
+            segments = segments[i:]
+            break
+        }
+    }
+
+    for i := range segments {
+
+        switch {
+
+        case i > 0 && segments[i-1] == "repos":
+            segments[i] = "{owner}"
+
+        case i > 1 && segments[i-2] == "repos":
+            segments[i] = "{repo}"
+
+        case i > 0 && segments[i-1] == "workflows":
+            segments[i] = "{workflow}"
+
+        case isNumeric(segments[i]):
+            segments[i] = "{id}"
+        }
+    }
+
+    return "/" + strings.Join(segments, "/")
+}
+
+func isNumeric(segment string) bool {
+
+    _, err := strconv.ParseInt(segment, 10, 64)
+
+    return err == nil
+}
@@ -0,0 +1,97 @@
+package gh
+
+import (
+    "context"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "testing"
+
+    "github.com/google/go-github/v47/github"
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    "go.opentelemetry.io/otel/sdk/trace/tracetest"
+    log "github.com/sirupsen/logrus"
+)
+
+func TestEndpointLabel(t *testing.T){
+
+    tests := []struct {
+        path string
+        want string
+    }{
+        {path: "/repos/testowner/testrepo/actions/workflows/testfile.yaml/runs", want: "/repos/{owner}/{repo}/actions/workflows/{workflow}/runs"},
+        {path: "/api-v3/repos/testowner/testrepo/actions/runs/1111111111", want: "/repos/{owner}/{repo}/actions/runs/{id}"},
+        {path: "/api/v3/repos/testowner/testrepo/actions/runs/1111111111/jobs", want: "/repos/{owner}/{repo}/actions/runs/{id}/jobs"},
+        {path: "/rate_limit", want: "/rate_limit"},
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.path, func(t *testing.T) {
+
+            if got := endpointLabel(tt.path); got != tt.want {
+                t.Errorf("endpointLabel() failed - expects '%s' but received '%s'", tt.want, got)
+            }
+
+        })
+    }
+
+}
+
+func TestInstrumentedTransport(t *testing.T){
+
+    // supress logrus
+    log.SetOutput(ioutil.Discard)
+
+    recorder := tracetest.NewSpanRecorder()
+    otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+    testClient, mux, _, teardown := Setup()
+    defer teardown()
+
+    client := github.NewClient(&http.Client{Transport: &instrumentedTransport{base: http.DefaultTransport}})
+    client.BaseURL = testClient.BaseURL
+
+    mux.HandleFunc("/repos/testowner/testrepo/actions/runs/1111111111", func(w http.ResponseWriter, r *http.Request) {
+
+        w.Header().Set("X-RateLimit-Remaining", "4321")
+        fmt.Fprint(w, `{"id": 1111111111, "run_number": 3, "status": "completed", "updated_at": "2022-12-12T23:47:06Z"}`)
+    })
+
+    if _, _, err := ReturnWorkflowRunStatus(context.Background(), client.Actions, "testowner", "testrepo", 1111111111); err != nil {
+        t.Fatalf("ReturnWorkflowRunStatus() returned error: '%v'", err)
+    }
+
+    spans := recorder.Ended()
+
+    if len(spans) != 1 {
+        t.Fatalf("expected 1 span but received %d instead", len(spans))
+    }
+
+    if spans[0].Name() != "GET /repos/{owner}/{repo}/actions/runs/{id}" {
+        t.Errorf("span name expects 'GET /repos/{owner}/{repo}/actions/runs/{id}' but received '%s'", spans[0].Name())
+    }
+
+    wantAttributes := []attribute.KeyValue{
+        attribute.Int("http.status_code", 200),
+        attribute.Int("github.rate_limit.remaining", 4321),
+    }
+
+    for _, want := range wantAttributes {
+
+        found := false
+
+        for _, got := range spans[0].Attributes() {
+            if got == want {
+                found = true
+            }
+        }
+
+        if !found {
+            t.Errorf("span is missing attribute %v - received %v", want, spans[0].Attributes())
+        }
+    }
+
+}
@@ -0,0 +1,26 @@
+package gh
+
+import (
+    "context"
+
+    log "github.com/sirupsen/logrus"
+)
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx that the calls made with it report to logger - instead of logrus' standard
+// logger.
+func WithLogger(ctx context.Context, logger log.FieldLogger) context.Context {
+
+    return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// logger returns what calls made with ctx report to.
+func logger(ctx context.Context) log.FieldLogger {
+
+    if logger, ok := ctx.Value(loggerKey{}).(log.FieldLogger); ok {
+        return logger
+    }
+
+    return log.StandardLogger()
+}
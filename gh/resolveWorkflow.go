@@ -0,0 +1,185 @@
+package gh
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "path"
+    "strconv"
+    "strings"
+    "sync"
+
+    "github.com/google/go-github/v47/github"
+
+    log "github.com/sirupsen/logrus"
+)
+
+// workflowsDir is where Github reads workflow files from - runs of a workflow in it can be listed by file name.
+const workflowsDir = ".github/workflows/"
+
+var (
+    ErrWorkflowNotFound  = errors.New("workflow not found")
+    ErrWorkflowAmbiguous = errors.New("workflow name is ambiguous")
+)
+
+// WorkflowsAPI lists the workflows of a repository - go-github's ActionsService (client.Actions) implements it.
+type WorkflowsAPI interface {
+    ListWorkflows(ctx context.Context, owner string, repo string, opts *github.ListOptions) (*github.Workflows, *github.Response, error)
+}
+
+var _ WorkflowsAPI = (*github.ActionsService)(nil)
+
+// WorkflowResolver turns the workflow a user passed - a numeric ID, a file name, a path or the workflow's display
+// name - into the identifier workflow runs are listed by. Resolutions are cached, so a command lists the workflows
+// of a repository at most once.
+type WorkflowResolver struct {
+    api      WorkflowsAPI
+    mu       sync.Mutex
+    resolved map[string]string
+}
+
+func NewWorkflowResolver(api WorkflowsAPI) *WorkflowResolver {
+
+    return &WorkflowResolver{api: api, resolved: map[string]string{}}
+}
+
+// Resolve returns the file name of the workflow spec refers to - or its ID when the workflow has no file in
+// .github/workflows. A bare file name such as 'release.yml' is returned as it is without calling the API.
+func (r *WorkflowResolver) Resolve(ctx context.Context, owner string, repo string, spec string) (string, error) {
+
+    if isWorkflowFileName(spec) {
+        return spec, nil
+    }
+
+    key := fmt.Sprintf("%s/%s/%s", owner, repo, spec)
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if resolved, ok := r.resolved[key]; ok {
+        return resolved, nil
+    }
+
+    workflows, _, err := ListWorkflows(ctx, r.api, owner, repo)
+
+    if err != nil {
+        return "", fmt.Errorf("Listing workflows of %s/%s to resolve workflow '%s' failed: %w", owner, repo, spec, err)
+    }
+
+    workflow, err := FindWorkflow(workflows, spec)
+
+    if err != nil {
+        return "", fmt.Errorf("Resolving workflow '%s' of %s/%s failed: %w", spec, owner, repo, err)
+    }
+
+    resolved := WorkflowIdentifier(workflow)
+
+    log.WithFields(log.Fields{
+        "workflow":   spec,
+        "workflowId": workflow.GetID(),
+        "name":       workflow.GetName(),
+        "path":       workflow.GetPath(),
+    }).Info("Resolved workflow ...")
+
+    r.resolved[key] = resolved
+
+    return resolved, nil
+}
+
+// FindWorkflow returns the workflow spec refers to - by ID, path, file name or display name, in that order.
+// Display names are matched ignoring case when none matches exactly.
+func FindWorkflow(workflows []*github.Workflow, spec string) (*github.Workflow, error) {
+
+    spec = strings.TrimPrefix(strings.TrimSpace(spec), "./")
+
+    matchers := []func(*github.Workflow) bool{
+        func(w *github.Workflow) bool { return strconv.FormatInt(w.GetID(), 10) == spec },
+        func(w *github.Workflow) bool { return w.GetPath() == spec },
+        func(w *github.Workflow) bool { return strings.Contains(spec, "/") && strings.HasSuffix(w.GetPath(), "/"+spec) },
+        func(w *github.Workflow) bool { return path.Base(w.GetPath()) == spec },
+        func(w *github.Workflow) bool { return w.GetName() == spec },
+        func(w *github.Workflow) bool { return strings.EqualFold(w.GetName(), spec) },
+    }
+
+    for _, matches := range matchers {
+
+        found := []*github.Workflow{}
+
+        for _, workflow := range workflows {
+
+            if matches(workflow) {
+                found = append(found, workflow)
+            }
+        }
+
+        switch len(found) {
+
+        case 0:
+            continue
+
+        case 1:
+            return found[0], nil
+        }
+
+        described := []string{}
+
+        for _, workflow := range found {
+            described = append(described, fmt.Sprintf("'%s' (id %d)", workflow.GetPath(), workflow.GetID()))
+        }
+
+        return nil, fmt.Errorf("%w: '%s' names %s - pass the file name or ID of one of them", ErrWorkflowAmbiguous, spec, strings.Join(described, ", "))
+    }
+
+    candidates := []string{}
+
+    for _, workflow := range workflows {
+        candidates = append(candidates, path.Base(workflow.GetPath()), workflow.GetName())
+    }
+
+    if matches := closeMatches(spec, candidates); len(matches) > 0 {
+        return nil, fmt.Errorf("%w: '%s' is no ID, path, file name or name of the %d workflows - did you mean '%s'?", ErrWorkflowNotFound, spec, len(workflows), strings.Join(matches, "', '"))
+    }
+
+    return nil, fmt.Errorf("%w: '%s' is no ID, path, file name or name of the %d workflows", ErrWorkflowNotFound, spec, len(workflows))
+}
+
+// WorkflowIdentifier is what runs of workflow are listed by - its file name when it is in .github/workflows,
+// its ID otherwise (e.g. dynamic workflows such as code scanning).
+func WorkflowIdentifier(workflow *github.Workflow) string {
+
+    if file := strings.TrimPrefix(workflow.GetPath(), workflowsDir); file != workflow.GetPath() && !strings.Contains(file, "/") {
+        return file
+    }
+
+    return strconv.FormatInt(workflow.GetID(), 10)
+}
+
+// ListWorkflows returns every workflow of the repository.
+func ListWorkflows(ctx context.Context, api WorkflowsAPI, owner string, repo string) ([]*github.Workflow, *github.Response, error) {
+
+    all := []*github.Workflow{}
+    opts := &github.ListOptions{PerPage: 100}
+
+    for {
+
+        workflows, res, err := api.ListWorkflows(ctx, owner, repo, opts)
+
+        if err != nil {
+            return nil, res, err
+        }
+
+        all = append(all, workflows.Workflows...)
+
+        if res.NextPage == 0 {
+            return all, res, nil
+        }
+
+        opts.Page = res.NextPage
+    }
+}
+
+// isWorkflowFileName reports whether spec is a bare workflow file name, which runs can be listed by as it is.
+func isWorkflowFileName(spec string) bool {
+
+    return !strings.Contains(spec, "/") && (strings.HasSuffix(spec, ".yml") || strings.HasSuffix(spec, ".yaml"))
+}
@@ -0,0 +1,110 @@
+package gh
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "strings"
+    "testing"
+
+    "github.com/google/go-github/v47/github"
+    log "github.com/sirupsen/logrus"
+)
+
+func TestFindWorkflow(t *testing.T){
+
+    workflows := []*github.Workflow{
+        {ID: github.Int64(101), Name: github.String("Release"), Path: github.String(".github/workflows/release.yml")},
+        {ID: github.Int64(102), Name: github.String("Deploy"), Path: github.String(".github/workflows/deploy-staging.yml")},
+        {ID: github.Int64(103), Name: github.String("Deploy"), Path: github.String(".github/workflows/deploy-production.yml")},
+        {ID: github.Int64(104), Name: github.String("CodeQL"), Path: github.String("dynamic/github-code-scanning/codeql")},
+    }
+
+    tests := []struct {
+        spec           string
+        wantID         int64
+        wantIdentifier string
+        wantErr        error
+        wantMessage    string
+    }{
+        {spec: "101", wantID: 101, wantIdentifier: "release.yml"},
+        {spec: "release.yml", wantID: 101, wantIdentifier: "release.yml"},
+        {spec: ".github/workflows/release.yml", wantID: 101, wantIdentifier: "release.yml"},
+        {spec: "./.github/workflows/release.yml", wantID: 101, wantIdentifier: "release.yml"},
+        {spec: "workflows/deploy-staging.yml", wantID: 102, wantIdentifier: "deploy-staging.yml"},
+        {spec: "Release", wantID: 101, wantIdentifier: "release.yml"},
+        {spec: "release", wantID: 101, wantIdentifier: "release.yml"},
+        {spec: "CodeQL", wantID: 104, wantIdentifier: "104"},
+        {spec: "Deploy", wantErr: ErrWorkflowAmbiguous, wantMessage: "'.github/workflows/deploy-staging.yml' (id 102), '.github/workflows/deploy-production.yml' (id 103)"},
+        {spec: "relase.yml", wantErr: ErrWorkflowNotFound, wantMessage: "did you mean 'release.yml'?"},
+        {spec: "lint.yml", wantErr: ErrWorkflowNotFound, wantMessage: "of the 4 workflows"},
+    }
+
+    for _, tt := range tests {
+
+        workflow, err := FindWorkflow(workflows, tt.spec)
+
+        if tt.wantErr != nil {
+
+            if !errors.Is(err, tt.wantErr) || !strings.Contains(err.Error(), tt.wantMessage) {
+                t.Errorf("FindWorkflow(%s) failed - expects %v containing '%s' but received %v", tt.spec, tt.wantErr, tt.wantMessage, err)
+            }
+
+            continue
+        }
+
+        if err != nil || workflow.GetID() != tt.wantID || WorkflowIdentifier(workflow) != tt.wantIdentifier {
+            t.Errorf("FindWorkflow(%s) failed - expects workflow %d listed as '%s' but received %v listed as '%s' (%v)", tt.spec, tt.wantID, tt.wantIdentifier, workflow.GetID(), WorkflowIdentifier(workflow), err)
+        }
+    }
+}
+
+func TestWorkflowResolver(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    client, mux, _, teardown := Setup()
+    defer teardown()
+
+    calls := 0
+
+    mux.HandleFunc("/repos/testowner/testrepo/actions/workflows", func(w http.ResponseWriter, r *http.Request) {
+
+        TestingMethod(t, r, "GET")
+        calls++
+
+        // two pages of workflows:
+        if r.URL.Query().Get("page") == "" {
+            w.Header().Set("Link", fmt.Sprintf(`<%s?page=2>; rel="next"`, r.URL.Path))
+            fmt.Fprint(w, `{"total_count":2,"workflows":[{"id":101,"name":"Release","path":".github/workflows/release.yml"}]}`)
+            return
+        }
+
+        fmt.Fprint(w, `{"total_count":2,"workflows":[{"id":102,"name":"Tests","path":".github/workflows/tests.yml"}]}`)
+    })
+
+    resolver := NewWorkflowResolver(client.Actions)
+
+    // file names are used as they are:
+    if got, err := resolver.Resolve(context.Background(), "testowner", "testrepo", "nightly.yml"); got != "nightly.yml" || err != nil || calls != 0 {
+        t.Errorf("Resolve() failed - expects 'nightly.yml' without calling the API but received '%s' (%v) after %d calls", got, err, calls)
+    }
+
+    for i := 0; i < 2; i++ {
+
+        if got, err := resolver.Resolve(context.Background(), "testowner", "testrepo", "Tests"); got != "tests.yml" || err != nil {
+            t.Errorf("Resolve() failed - expects 'tests.yml' but received '%s' (%v)", got, err)
+        }
+    }
+
+    // the second resolution is served from the cache:
+    if calls != 2 {
+        t.Errorf("Resolve() failed - expects both pages listed once but received %d calls", calls)
+    }
+
+    if _, err := resolver.Resolve(context.Background(), "testowner", "testrepo", "Lint"); !errors.Is(err, ErrWorkflowNotFound) {
+        t.Errorf("Resolve() failed - expects %v but received %v", ErrWorkflowNotFound, err)
+    }
+}
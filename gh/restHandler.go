@@ -0,0 +1,156 @@
+package gh
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "github.com/google/go-github/v47/github"
+)
+
+// RESTAPI is what ServeREST answers from - go-github's ActionsService (client.Actions) implements it.
+type RESTAPI interface {
+    WorkflowRunsAPI
+    WorkflowsAPI
+}
+
+var _ RESTAPI = (*github.ActionsService)(nil)
+
+// ServeREST answers r from api when it is a GET of one of the workflow-runs endpoints of the Github REST API the
+// tool calls - a '/api/v3' prefix, as on Github Enterprise Server, is accepted too - so the real client can be
+// pointed at api. authorize is asked about the repository before api is called, and answers requests it refuses.
+func ServeREST(w http.ResponseWriter, r *http.Request, api RESTAPI, authorize func(w http.ResponseWriter, r *http.Request, owner string, repo string) bool) {
+
+    if r.Method != http.MethodGet {
+        WriteError(w, http.StatusMethodNotAllowed, nil, "Method not allowed")
+        return
+    }
+
+    segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v3"), "/"), "/")
+
+    // /repos/{owner}/{repo}/actions/...
+    if len(segments) < 5 || segments[0] != "repos" || segments[3] != "actions" {
+        WriteError(w, http.StatusNotFound, nil, "Not Found")
+        return
+    }
+
+    owner, repo := segments[1], segments[2]
+
+    if !authorize(w, r, owner, repo) {
+        return
+    }
+
+    query := r.URL.Query()
+    page, _ := strconv.Atoi(query.Get("page"))
+    perPage, _ := strconv.Atoi(query.Get("per_page"))
+
+    // the API's default page size:
+    if perPage == 0 {
+        perPage = 30
+    }
+
+    var body interface{}
+    var err error
+
+    switch actions := segments[4:]; {
+
+    case len(actions) == 1 && actions[0] == "workflows":
+        body, _, err = api.ListWorkflows(r.Context(), owner, repo, &github.ListOptions{Page: page, PerPage: perPage})
+
+    case len(actions) == 3 && actions[0] == "workflows" && actions[2] == "runs":
+        body, _, err = api.ListWorkflowRunsByFileName(r.Context(), owner, repo, actions[1], &github.ListWorkflowRunsOptions{
+            Actor:       query.Get("actor"),
+            Branch:      query.Get("branch"),
+            Event:       query.Get("event"),
+            Status:      query.Get("status"),
+            Created:     query.Get("created"),
+            ListOptions: github.ListOptions{Page: page, PerPage: perPage},
+        })
+
+    case len(actions) == 2 && actions[0] == "runs":
+        body, err = withRunID(actions[1], func(id int64) (interface{}, error) {
+            run, _, err := api.GetWorkflowRunByID(r.Context(), owner, repo, id)
+            return run, err
+        })
+
+    case len(actions) == 3 && actions[0] == "runs" && actions[2] == "jobs":
+        body, err = withRunID(actions[1], func(id int64) (interface{}, error) {
+            jobs, _, err := api.ListWorkflowJobs(r.Context(), owner, repo, id, &github.ListWorkflowJobsOptions{ListOptions: github.ListOptions{Page: page, PerPage: perPage}})
+            return jobs, err
+        })
+
+    default:
+        WriteError(w, http.StatusNotFound, nil, "Not Found")
+        return
+    }
+
+    if err != nil {
+        WriteAPIError(w, r, err)
+        return
+    }
+
+    WriteJSON(w, body)
+}
+
+// WriteAPIError passes on the status code, headers and message of a failed Github call - calls that never got a
+// response are a bad gateway, unless r was cancelled and nobody is left to read one.
+func WriteAPIError(w http.ResponseWriter, r *http.Request, err error) {
+
+    var rateErr *github.RateLimitError
+    var errorResponse *github.ErrorResponse
+
+    switch {
+
+    case errors.As(err, &rateErr) && rateErr.Response != nil:
+        WriteError(w, rateErr.Response.StatusCode, rateErr.Response.Header, rateErr.Message)
+
+    case errors.As(err, &errorResponse) && errorResponse.Response != nil:
+        WriteError(w, errorResponse.Response.StatusCode, errorResponse.Response.Header, errorResponse.Message)
+
+    case r.Context().Err() != nil:
+        return
+
+    default:
+        WriteError(w, http.StatusBadGateway, nil, err.Error())
+    }
+}
+
+// WriteError answers with statusCode, header and a body in the form of Github's errors.
+func WriteError(w http.ResponseWriter, statusCode int, header http.Header, message string) {
+
+    for name, values := range header {
+
+        // the length of the upstream body does not apply to ours:
+        if name != "Content-Length" {
+            w.Header()[name] = values
+        }
+    }
+
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.WriteHeader(statusCode)
+
+    json.NewEncoder(w).Encode(map[string]string{
+        "message":           message,
+        "documentation_url": "https://docs.github.com/rest",
+    })
+}
+
+// WriteJSON answers with body encoded as JSON.
+func WriteJSON(w http.ResponseWriter, body interface{}) {
+
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    json.NewEncoder(w).Encode(body)
+}
+
+func withRunID(segment string, fn func(id int64) (interface{}, error)) (interface{}, error) {
+
+    id, err := strconv.ParseInt(segment, 10, 64)
+
+    if err != nil {
+        return nil, &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}, Message: "Not Found"}
+    }
+
+    return fn(id)
+}
@@ -0,0 +1,72 @@
+package gh
+
+import (
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestServeREST(t *testing.T){
+
+    client, mux, _, teardown := Setup()
+    defer teardown()
+
+    mux.HandleFunc("/repos/testowner/testrepo/actions/runs/3333333333", func(w http.ResponseWriter, r *http.Request) {
+        fmt.Fprint(w, `{"id": 3333333333, "status": "in_progress"}`)
+    })
+
+    mux.HandleFunc("/repos/testowner/testrepo/actions/runs/4444444444", func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Retry-After", "60")
+        w.WriteHeader(http.StatusServiceUnavailable)
+        fmt.Fprint(w, `{"message": "Service Unavailable"}`)
+    })
+
+    authorize := func(w http.ResponseWriter, r *http.Request, owner string, repo string) bool {
+
+        if owner != "testowner" {
+            WriteError(w, http.StatusForbidden, nil, "Forbidden")
+            return false
+        }
+
+        return true
+    }
+
+    tests := []struct {
+        name        string
+        method      string
+        path        string
+        wantStatus  int
+        wantBody    string
+        wantHeader  string
+    }{
+        {name: "should serve a run", method: http.MethodGet, path: "/repos/testowner/testrepo/actions/runs/3333333333", wantStatus: http.StatusOK, wantBody: `"status":"in_progress"`},
+        {name: "should serve a run on the Github Enterprise Server path", method: http.MethodGet, path: "/api/v3/repos/testowner/testrepo/actions/runs/3333333333", wantStatus: http.StatusOK, wantBody: `"id":3333333333`},
+        {name: "should pass on the status, headers and message of a failed call", method: http.MethodGet, path: "/repos/testowner/testrepo/actions/runs/4444444444", wantStatus: http.StatusServiceUnavailable, wantBody: "Service Unavailable", wantHeader: "60"},
+        {name: "should leave refused requests to authorize", method: http.MethodGet, path: "/repos/otherowner/testrepo/actions/runs/3333333333", wantStatus: http.StatusForbidden, wantBody: "Forbidden"},
+        {name: "should not find a run id that is not a number", method: http.MethodGet, path: "/repos/testowner/testrepo/actions/runs/latest", wantStatus: http.StatusNotFound, wantBody: "Not Found"},
+        {name: "should not find other endpoints", method: http.MethodGet, path: "/repos/testowner/testrepo/pulls", wantStatus: http.StatusNotFound, wantBody: "Not Found"},
+        {name: "should only answer GET", method: http.MethodPost, path: "/repos/testowner/testrepo/actions/runs/3333333333", wantStatus: http.StatusMethodNotAllowed, wantBody: "Method not allowed"},
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            w := httptest.NewRecorder()
+
+            ServeREST(w, httptest.NewRequest(tt.method, tt.path, nil), client.Actions, authorize)
+
+            if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+                t.Errorf("ServeREST() failed - expects %d '%s' but received %d '%s'", tt.wantStatus, tt.wantBody, w.Code, w.Body.String())
+            }
+
+            if got := w.Header().Get("Retry-After"); got != tt.wantHeader {
+                t.Errorf("ServeREST() failed - expects Retry-After '%s' but received '%s'", tt.wantHeader, got)
+            }
+
+        })
+    }
+
+}
@@ -0,0 +1,65 @@
+package gh
+
+import (
+    "context"
+    "fmt"
+
+    "github.com/google/go-github/v47/github"
+
+    log "github.com/sirupsen/logrus"
+)
+
+func ReturnWorkflowJobs(ctx context.Context, api WorkflowRunsAPI, owner string, repo string, workflowRunId int) ([]*github.WorkflowJob, error) {
+
+    logger(ctx).WithFields(log.Fields{
+        "repo":          repo,
+        "owner":         owner,
+        "workflowRunId": workflowRunId,
+    }).Info("Calling for jobs of a workflow run...")
+
+    opts := &github.ListWorkflowJobsOptions{
+        Filter: "latest",
+        ListOptions: github.ListOptions{
+            Page: 1,
+            PerPage: 100,
+        },
+    }
+
+    jobs, res, err := api.ListWorkflowJobs(ctx, owner, repo, int64(workflowRunId), opts)
+
+    if res == nil {
+
+        return nil, err
+    }
+
+    if res.StatusCode == 404 {
+
+        logger(ctx).WithFields(log.Fields{
+            "repo":          repo,
+            "owner":         owner,
+            "workflowRunId": workflowRunId,
+        }).Warn("Workflow run not found ...")
+
+        return nil, fmt.Errorf("Workflow run not found")
+    }
+
+    if res.StatusCode != 200 {
+
+        logger(ctx).WithFields(log.Fields{
+            "Response Status": res.StatusCode,
+            "repo":            repo,
+            "owner":           owner,
+            "workflowRunId":   workflowRunId,
+        }).Warn("Request did not succeed: Response status received was not 200 ...")
+
+        return nil, fmt.Errorf("Response status received was not 200")
+    }
+
+    if err != nil {
+
+        return nil, err
+    }
+
+    return jobs.Jobs, nil
+
+}
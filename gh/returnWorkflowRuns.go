@@ -9,9 +9,9 @@ import (
     log "github.com/sirupsen/logrus"
 )
 
-func ReturnWorkflowRuns(branchName string, ctx context.Context, client *github.Client, owner string, repo string, workflowFile string, workflowRunsToReturn int) ([]*github.WorkflowRun, error) {
+func ReturnWorkflowRuns(branchName string, ctx context.Context, api WorkflowRunsAPI, owner string, repo string, workflowFile string, workflowRunsToReturn int) ([]*github.WorkflowRun, error) {
 
-    log.WithFields(log.Fields{
+    logger(ctx).WithFields(log.Fields{
         "repo":         repo,
         "owner":        owner,
         "workflowFile": workflowFile,
@@ -26,11 +26,16 @@ func ReturnWorkflowRuns(branchName string, ctx context.Context, client *github.C
         },
     }
 
-    runs, res, err := client.Actions.ListWorkflowRunsByFileName(ctx, owner, repo, workflowFile, opts)
+    runs, res, err := api.ListWorkflowRunsByFileName(ctx, owner, repo, workflowFile, opts)
+
+    if res == nil {
+
+        return nil, err
+    }
 
     if res.StatusCode == 404 {
 
-        log.WithFields(log.Fields{
+        logger(ctx).WithFields(log.Fields{
             "Response Status":      res.StatusCode,
             "repo":                 repo,
             "owner":                owner,
@@ -44,7 +49,7 @@ func ReturnWorkflowRuns(branchName string, ctx context.Context, client *github.C
 
     if res.StatusCode == 410 {
 
-        log.WithFields(log.Fields{
+        logger(ctx).WithFields(log.Fields{
             "Response Status":      res.StatusCode,
             "repo":                 repo,
             "owner":                owner,
@@ -57,7 +62,7 @@ func ReturnWorkflowRuns(branchName string, ctx context.Context, client *github.C
 
     if res.StatusCode != 200 {
 
-        log.WithFields(log.Fields{
+        logger(ctx).WithFields(log.Fields{
             "Response Status":      res.StatusCode,
             "repo":                 repo,
             "owner":                owner,
@@ -73,7 +78,7 @@ func ReturnWorkflowRuns(branchName string, ctx context.Context, client *github.C
         return nil, err
     }
 
-    log.WithFields(log.Fields{
+    logger(ctx).WithFields(log.Fields{
         "repo":         repo,
         "owner":        owner,
         "workflowFile": workflowFile,
@@ -201,7 +201,7 @@ func TestReturnWorkflowRuns(t *testing.T){
                 fmt.Fprint(w, tt.endpoint.runs)
             })
             
-            gotRuns, gotErr := ReturnWorkflowRuns(tt.args.branch, ctx, client, tt.args.owner, tt.args.repo, tt.args.workflowFile, 20)
+            gotRuns, gotErr := ReturnWorkflowRuns(tt.args.branch, ctx, client.Actions, tt.args.owner, tt.args.repo, tt.args.workflowFile, 20)
 
             if tt.wantErr == nil {
                 
@@ -10,63 +10,82 @@ import (
     log "github.com/sirupsen/logrus"
 )
 
-func ReturnWorkflowRunStatus(ctx context.Context, client *github.Client, owner string, repo string, workflowRunId int) (string, *github.Timestamp, error) {
+func ReturnWorkflowRunStatus(ctx context.Context, api WorkflowRunsAPI, owner string, repo string, workflowRunId int) (string, *github.Timestamp, error) {
 
-    log.WithFields(log.Fields{
+    run, err := ReturnWorkflowRun(ctx, api, owner, repo, workflowRunId)
+
+    if err != nil {
+
+        return "", &github.Timestamp{Time: time.Time{}}, err
+
+    }
+
+    return *run.Status, run.UpdatedAt, nil
+
+}
+
+func ReturnWorkflowRun(ctx context.Context, api WorkflowRunsAPI, owner string, repo string, workflowRunId int) (*github.WorkflowRun, error) {
+
+    logger(ctx).WithFields(log.Fields{
         "repo":         repo,
         "owner":        owner,
         "workflowRunId": workflowRunId,
     }).Info("Calling for a previous workflow RunId...")
 
-    run, res, err := client.Actions.GetWorkflowRunByID(ctx, owner, repo, int64(workflowRunId))
+    run, res, err := api.GetWorkflowRunByID(ctx, owner, repo, int64(workflowRunId))
+
+    if res == nil {
+
+        return nil, err
+    }
 
     if res.StatusCode == 404 {
 
-        log.WithFields(log.Fields{
+        logger(ctx).WithFields(log.Fields{
             "repo":         repo,
             "owner":        owner,
             "workflowRunId": workflowRunId,
         }).Warn("Workflow not found ...")
 
-        return "", &github.Timestamp{time.Time{}}, fmt.Errorf("Workflow run not found")
+        return nil, fmt.Errorf("Workflow run not found")
 
     }
 
     if res.StatusCode == 410 {
 
-        log.WithFields(log.Fields{
+        logger(ctx).WithFields(log.Fields{
             "repo":         repo,
             "owner":        owner,
             "workflowRunId": workflowRunId,
         }).Warn("received 410 code: API Method Gone...")
 
-        return "", &github.Timestamp{time.Time{}}, fmt.Errorf("API Method Gone")
+        return nil, fmt.Errorf("API Method Gone")
     }
 
     if res.StatusCode != 200 {
 
-        log.WithFields(log.Fields{
+        logger(ctx).WithFields(log.Fields{
             "Response Status":      res.StatusCode,
             "repo":         repo,
             "owner":        owner,
             "workflowRunId": workflowRunId,
         }).Warn("Request did not succeed: Response status received was not 200 ...")
 
-        return "", &github.Timestamp{time.Time{}}, fmt.Errorf("Response status received was not 200")
+        return nil, fmt.Errorf("Response status received was not 200")
     }
 
     if err != nil {
 
-        return "", &github.Timestamp{time.Time{}}, err
+        return nil, err
 
     }
 
-    log.WithFields(log.Fields{
+    logger(ctx).WithFields(log.Fields{
         "repo":         repo,
         "owner":        owner,
         "workflowRunId": workflowRunId,
     }).Info("Workflow run was returned ...")
 
-    return *run.Status, run.UpdatedAt, nil
+    return run, nil
 
 }
@@ -248,7 +248,7 @@ func TestReturnWorkflowRunStatus(t *testing.T){
                 fmt.Fprint(w, tt.endpoint.run)
             })
             
-            gotStatus, gotUpdateTime, gotErr := ReturnWorkflowRunStatus(ctx, client, tt.args.owner, tt.args.repo, tt.args.runId)
+            gotStatus, gotUpdateTime, gotErr := ReturnWorkflowRunStatus(ctx, client.Actions, tt.args.owner, tt.args.repo, tt.args.runId)
 
             if tt.wantErr == nil {
                 
@@ -0,0 +1,275 @@
+package gh
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "time"
+
+    "github.com/google/go-github/v47/github"
+
+    log "github.com/sirupsen/logrus"
+)
+
+// RunCache is an optional file-backed cache for ReturnWorkflowRuns and ReturnWorkflowRunStatus results.
+// Processes sharing the same Dir (e.g. matrix jobs on one runner using $RUNNER_TEMP) share responses;
+// a lock file per entry makes sure only one of them calls the API while the others wait and reuse it.
+type RunCache struct {
+    Dir          string
+    RunsTTL      time.Duration
+    StatusTTL    time.Duration
+    CompletedTTL time.Duration
+
+    now func() time.Time
+}
+
+type runCacheEntry struct {
+    StoredAt time.Time       `json:"stored_at"`
+    Payload  json.RawMessage `json:"payload"`
+}
+
+func NewRunCache(dir string, runsTTL time.Duration, statusTTL time.Duration, completedTTL time.Duration) (*RunCache, error) {
+
+    if err := os.MkdirAll(dir, 0o700); err != nil {
+        return nil, fmt.Errorf("Failed to create cache directory %s: %s", dir, err.Error())
+    }
+
+    return &RunCache{
+        Dir:          dir,
+        RunsTTL:      runsTTL,
+        StatusTTL:    statusTTL,
+        CompletedTTL: completedTTL,
+        now:          time.Now,
+    }, nil
+}
+
+// ReturnWorkflowRunsCached behaves like ReturnWorkflowRuns but serves results from cache when they are younger than RunsTTL.
+// Successful runs found in the list also seed the status cache.
+func ReturnWorkflowRunsCached(cache *RunCache, branchName string, ctx context.Context, api WorkflowRunsAPI, owner string, repo string, workflowFile string, workflowRunsToReturn int) ([]*github.WorkflowRun, error) {
+
+    if cache == nil {
+        return ReturnWorkflowRuns(branchName, ctx, api, owner, repo, workflowFile, workflowRunsToReturn)
+    }
+
+    key := fmt.Sprintf("runs/%s/%s/%s/%s/%d", owner, repo, workflowFile, branchName, workflowRunsToReturn)
+
+    var runs []*github.WorkflowRun
+
+    err := cache.withLock(ctx, key, func(path string) error {
+
+        if entry, ok := cache.read(path); ok && cache.now().Sub(entry.StoredAt) < cache.RunsTTL {
+
+            if err := json.Unmarshal(entry.Payload, &runs); err == nil {
+
+                logger(ctx).WithFields(log.Fields{
+                    "repo":         repo,
+                    "owner":        owner,
+                    "workflowFile": workflowFile,
+                    "cachedAt":     entry.StoredAt,
+                }).Info("Workflow runs served from cache ...")
+
+                return nil
+            }
+        }
+
+        var ghErr error
+
+        runs, ghErr = ReturnWorkflowRuns(branchName, ctx, api, owner, repo, workflowFile, workflowRunsToReturn)
+
+        if ghErr != nil {
+            return ghErr
+        }
+
+        cache.write(ctx, path, runs)
+
+        return nil
+    })
+
+    if err != nil {
+        return nil, err
+    }
+
+    // seed the status cache with successful runs - runs that did not succeed are more likely to be re-run:
+    for _, run := range runs {
+
+        if run.ID == nil || !succeeded(run) {
+            continue
+        }
+
+        statusKey := fmt.Sprintf("status/%s/%s/%d", owner, repo, *run.ID)
+
+        cache.withLock(ctx, statusKey, func(path string) error {
+            cache.write(ctx, path, run)
+            return nil
+        })
+    }
+
+    return runs, nil
+}
+
+// ReturnWorkflowRunStatusCached behaves like ReturnWorkflowRunStatus but serves results from cache - for
+// CompletedTTL once the run has completed successfully and for StatusTTL otherwise. Any completed run can be
+// re-run, a successful one included, so CompletedTTL bounds how long a re-run goes unseen.
+func ReturnWorkflowRunStatusCached(cache *RunCache, ctx context.Context, api WorkflowRunsAPI, owner string, repo string, workflowRunId int) (string, *github.Timestamp, error) {
+
+    run, err := ReturnWorkflowRunCached(cache, ctx, api, owner, repo, workflowRunId)
+
+    if err != nil {
+        return "", &github.Timestamp{Time: time.Time{}}, err
+    }
+
+    return run.GetStatus(), run.UpdatedAt, nil
+}
+
+// ReturnWorkflowRunCached behaves like ReturnWorkflowRun with the same TTLs as ReturnWorkflowRunStatusCached.
+func ReturnWorkflowRunCached(cache *RunCache, ctx context.Context, api WorkflowRunsAPI, owner string, repo string, workflowRunId int) (*github.WorkflowRun, error) {
+
+    if cache == nil {
+        return ReturnWorkflowRun(ctx, api, owner, repo, workflowRunId)
+    }
+
+    key := fmt.Sprintf("status/%s/%s/%d", owner, repo, workflowRunId)
+
+    var run *github.WorkflowRun
+
+    err := cache.withLock(ctx, key, func(path string) error {
+
+        if entry, ok := cache.read(path); ok {
+
+            if err := json.Unmarshal(entry.Payload, &run); err == nil && run != nil {
+
+                ttl := cache.StatusTTL
+
+                if succeeded(run) {
+                    ttl = cache.CompletedTTL
+                }
+
+                if cache.now().Sub(entry.StoredAt) < ttl {
+
+                    logger(ctx).WithFields(log.Fields{
+                        "repo":          repo,
+                        "owner":         owner,
+                        "workflowRunId": workflowRunId,
+                        "cachedAt":      entry.StoredAt,
+                    }).Info("Workflow run status served from cache ...")
+
+                    return nil
+                }
+            }
+        }
+
+        var ghErr error
+
+        run, ghErr = ReturnWorkflowRun(ctx, api, owner, repo, workflowRunId)
+
+        if ghErr != nil {
+            return ghErr
+        }
+
+        cache.write(ctx, path, run)
+
+        return nil
+    })
+
+    if err != nil {
+        return nil, err
+    }
+
+    return run, nil
+}
+
+// WorkflowRuns is ReturnWorkflowRunsCached with c - so c can be a sorter.Cache.
+func (c *RunCache) WorkflowRuns(ctx context.Context, api WorkflowRunsAPI, owner string, repo string, workflowFile string, branchName string, workflowRunsToReturn int) ([]*github.WorkflowRun, error) {
+
+    return ReturnWorkflowRunsCached(c, branchName, ctx, api, owner, repo, workflowFile, workflowRunsToReturn)
+}
+
+// WorkflowRun is ReturnWorkflowRunCached with c.
+func (c *RunCache) WorkflowRun(ctx context.Context, api WorkflowRunsAPI, owner string, repo string, runID int64) (*github.WorkflowRun, error) {
+
+    return ReturnWorkflowRunCached(c, ctx, api, owner, repo, int(runID))
+}
+
+// succeeded reports whether run completed successfully - its record changes only when it is re-run, which is
+// rarer than re-running a run that did not succeed.
+func succeeded(run *github.WorkflowRun) bool {
+
+    return run.GetStatus() == "completed" && run.GetConclusion() == "success"
+}
+
+// withLock holds an exclusive lock on the entry for key while fn runs - concurrent processes
+// block here and read the response stored by whichever process got the lock first.
+func (c *RunCache) withLock(ctx context.Context, key string, fn func(path string) error) error {
+
+    path := filepath.Join(c.Dir, fmt.Sprintf("%x.json", sha256.Sum256([]byte(key))))
+
+    unlock, err := lockFile(path + ".lock")
+
+    if err != nil {
+
+        logger(ctx).WithFields(log.Fields{
+            "cacheDir": c.Dir,
+        }).Warn(fmt.Sprintf("Failed to lock cache entry - continuing without lock: %s", err.Error()))
+
+    } else {
+        defer unlock()
+    }
+
+    return fn(path)
+}
+
+func (c *RunCache) read(path string) (runCacheEntry, bool) {
+
+    return readEntry(path)
+}
+
+// write stores payload atomically; failures are logged and otherwise ignored as the cache is best-effort.
+func (c *RunCache) write(ctx context.Context, path string, payload interface{}) {
+
+    if err := writeEntry(path, payload, c.now()); err != nil {
+
+        logger(ctx).WithFields(log.Fields{
+            "cacheDir": c.Dir,
+        }).Warn(fmt.Sprintf("Failed to write cache entry: %s", err.Error()))
+    }
+}
+
+func readEntry(path string) (runCacheEntry, bool) {
+
+    var entry runCacheEntry
+
+    data, err := os.ReadFile(path)
+
+    if err != nil {
+        return entry, false
+    }
+
+    if err := json.Unmarshal(data, &entry); err != nil {
+        return entry, false
+    }
+
+    return entry, true
+}
+
+// writeEntry stores payload atomically - readers see the previous entry or this one, never part of it.
+func writeEntry(path string, payload interface{}, storedAt time.Time) error {
+
+    raw, err := json.Marshal(payload)
+
+    if err == nil {
+        raw, err = json.Marshal(runCacheEntry{StoredAt: storedAt, Payload: raw})
+    }
+
+    if err == nil {
+        tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+
+        if err = os.WriteFile(tmp, raw, 0o600); err == nil {
+            err = os.Rename(tmp, path)
+        }
+    }
+
+    return err
+}
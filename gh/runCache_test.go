@@ -0,0 +1,200 @@
+package gh
+
+import (
+    "context"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "testing"
+    "time"
+
+    log "github.com/sirupsen/logrus"
+
+)
+
+func TestReturnWorkflowRunStatusCached(t *testing.T){
+
+    tests := []struct {
+        name       string
+        status     string
+        conclusion string
+        elapsed    time.Duration
+        wantCalls  int
+    }{
+        {
+            name:      "in_progress run served from cache within StatusTTL",
+            status:    "in_progress",
+            elapsed:   2*time.Second,
+            wantCalls: 1,
+        },
+        {
+            name:      "in_progress run refetched after StatusTTL",
+            status:    "in_progress",
+            elapsed:   10*time.Second,
+            wantCalls: 2,
+        },
+        {
+            name:       "successful run served from cache within CompletedTTL",
+            status:     "completed",
+            conclusion: "success",
+            elapsed:    30*time.Minute,
+            wantCalls:  1,
+        },
+        {
+            name:       "successful run refetched after CompletedTTL",
+            status:     "completed",
+            conclusion: "success",
+            elapsed:    2*time.Hour,
+            wantCalls:  2,
+        },
+        {
+            name:       "failed run refetched after StatusTTL - it may have been re-run",
+            status:     "completed",
+            conclusion: "failure",
+            elapsed:    10*time.Second,
+            wantCalls:  2,
+        },
+        {
+            name:       "failed run served from cache within StatusTTL",
+            status:     "completed",
+            conclusion: "failure",
+            elapsed:    2*time.Second,
+            wantCalls:  1,
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            // supress logrus
+            log.SetOutput(ioutil.Discard)
+
+            client, mux, _, teardown := Setup()
+            defer teardown()
+
+            ctx := context.Background()
+
+            calls := 0
+
+            mux.HandleFunc("/repos/testowner/testrepo/actions/runs/1111111111", func(w http.ResponseWriter, r *http.Request) {
+
+                TestingMethod(t, r, "GET")
+
+                calls++
+
+                fmt.Fprintf(w, `{"id": 1111111111, "run_number": 3, "status": "%s", "conclusion": "%s", "updated_at": "2022-12-12T23:47:06Z"}`, tt.status, tt.conclusion)
+            })
+
+            cache, err := NewRunCache(t.TempDir(), 10*time.Second, 5*time.Second, time.Hour)
+
+            if err != nil {
+                t.Fatalf("NewRunCache() returned error: '%v'", err)
+            }
+
+            now := time.Date(2022, time.December, 13, 0, 0, 0, 0, time.UTC)
+            cache.now = func() time.Time { return now }
+
+            for i := 0; i < 2; i++ {
+
+                gotStatus, _, gotErr := ReturnWorkflowRunStatusCached(cache, ctx, client.Actions, "testowner", "testrepo", 1111111111)
+
+                if gotErr != nil {
+                    t.Errorf("ReturnWorkflowRunStatusCached() returned error: '%v' expect '<nil>'", gotErr)
+                }
+
+                if gotStatus != tt.status {
+                    t.Errorf("ReturnWorkflowRunStatusCached() failed - expects '%s' but received '%s'", tt.status, gotStatus)
+                }
+
+                now = now.Add(tt.elapsed)
+            }
+
+            if calls != tt.wantCalls {
+                t.Errorf("ReturnWorkflowRunStatusCached() made %d API calls but expects %d", calls, tt.wantCalls)
+            }
+
+        })
+    }
+
+}
+
+func TestReturnWorkflowRunsCached(t *testing.T){
+
+    // supress logrus
+    log.SetOutput(ioutil.Discard)
+
+    client, mux, _, teardown := Setup()
+    defer teardown()
+
+    ctx := context.Background()
+
+    listCalls, statusCalls, failedCalls := 0, 0, 0
+
+    mux.HandleFunc("/repos/testowner/testrepo/actions/workflows/testfile.yaml/runs", func(w http.ResponseWriter, r *http.Request) {
+
+        listCalls++
+
+        fmt.Fprint(w, `{"total_count":3,"workflow_runs":[
+            {"id": 2222222222, "run_number": 2, "status": "in_progress", "updated_at": "2022-12-12T22:47:06Z"},
+            {"id": 1111111111, "run_number": 1, "status": "completed", "conclusion": "success", "updated_at": "2022-12-12T21:47:06Z"},
+            {"id": 1111111110, "run_number": 0, "status": "completed", "conclusion": "failure", "updated_at": "2022-12-12T20:47:06Z"}
+        ]}`)
+    })
+
+    mux.HandleFunc("/repos/testowner/testrepo/actions/runs/1111111111", func(w http.ResponseWriter, r *http.Request) {
+
+        statusCalls++
+
+        fmt.Fprint(w, `{"id": 1111111111, "run_number": 1, "status": "completed", "conclusion": "success", "updated_at": "2022-12-12T21:47:06Z"}`)
+    })
+
+    mux.HandleFunc("/repos/testowner/testrepo/actions/runs/1111111110", func(w http.ResponseWriter, r *http.Request) {
+
+        failedCalls++
+
+        fmt.Fprint(w, `{"id": 1111111110, "run_number": 0, "status": "in_progress", "run_attempt": 2, "updated_at": "2022-12-12T23:47:06Z"}`)
+    })
+
+    cache, err := NewRunCache(t.TempDir(), 10*time.Second, 5*time.Second, time.Hour)
+
+    if err != nil {
+        t.Fatalf("NewRunCache() returned error: '%v'", err)
+    }
+
+    for i := 0; i < 3; i++ {
+
+        gotRuns, gotErr := ReturnWorkflowRunsCached(cache, "main", ctx, client.Actions, "testowner", "testrepo", "testfile.yaml", 20)
+
+        if gotErr != nil {
+            t.Errorf("ReturnWorkflowRunsCached() returned error: '%v' expect '<nil>'", gotErr)
+        }
+
+        if len(gotRuns) != 3 {
+            t.Errorf("expected 3 elements but received %d instead", len(gotRuns))
+        }
+    }
+
+    if listCalls != 1 {
+        t.Errorf("ReturnWorkflowRunsCached() made %d API calls but expects 1", listCalls)
+    }
+
+    // successful runs from the list should seed the status cache:
+    gotStatus, _, gotErr := ReturnWorkflowRunStatusCached(cache, ctx, client.Actions, "testowner", "testrepo", 1111111111)
+
+    if gotErr != nil || gotStatus != "completed" {
+        t.Errorf("ReturnWorkflowRunStatusCached() returned '%s', '%v' expect 'completed', '<nil>'", gotStatus, gotErr)
+    }
+
+    if statusCalls != 0 {
+        t.Errorf("ReturnWorkflowRunStatusCached() made %d API calls but expects 0", statusCalls)
+    }
+
+    // failed runs should not - they may have been re-run since:
+    gotStatus, _, gotErr = ReturnWorkflowRunStatusCached(cache, ctx, client.Actions, "testowner", "testrepo", 1111111110)
+
+    if gotErr != nil || gotStatus != "in_progress" || failedCalls != 1 {
+        t.Errorf("ReturnWorkflowRunStatusCached() returned '%s', '%v' with %d API calls expect 'in_progress', '<nil>' with 1", gotStatus, gotErr, failedCalls)
+    }
+
+}
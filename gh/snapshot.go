@@ -0,0 +1,160 @@
+package gh
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "os"
+    "time"
+
+    "github.com/google/go-github/v47/github"
+)
+
+// SnapshotVersion is the format version written to and accepted from snapshot files.
+const SnapshotVersion = 1
+
+// SnapshotRuns is the number of runs a snapshot captures - the most a single check can visit.
+const SnapshotRuns = 100
+
+// Snapshot is the state of the most recent runs of a workflow on a branch at one point in time. It serves
+// the workflow-runs API from that state, so decisions can be computed offline as they would have been then.
+type Snapshot struct {
+    Version    int                   `json:"version"`
+    CapturedAt time.Time             `json:"captured_at"`
+    Owner      string                `json:"owner"`
+    Repo       string                `json:"repo"`
+    Workflow   string                `json:"workflow"`
+    Branch     string                `json:"branch"`
+    Runs       []*github.WorkflowRun `json:"runs"`
+}
+
+var _ WorkflowRunsAPI = (*Snapshot)(nil)
+
+// TakeSnapshot captures the SnapshotRuns most recent runs of workflowFile on branchName - in every state,
+// the predecessor of any run among them included.
+func TakeSnapshot(ctx context.Context, api WorkflowRunsAPI, owner string, repo string, workflowFile string, branchName string) (*Snapshot, error) {
+
+    runs, err := ReturnWorkflowRuns(branchName, ctx, api, owner, repo, workflowFile, SnapshotRuns)
+
+    if err != nil {
+        return nil, err
+    }
+
+    return &Snapshot{
+        Version:    SnapshotVersion,
+        CapturedAt: time.Now().UTC(),
+        Owner:      owner,
+        Repo:       repo,
+        Workflow:   workflowFile,
+        Branch:     branchName,
+        Runs:       runs,
+    }, nil
+}
+
+// LoadSnapshot reads a snapshot written by Save.
+func LoadSnapshot(path string) (*Snapshot, error) {
+
+    data, err := os.ReadFile(path)
+
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read snapshot %s: %s", path, err.Error())
+    }
+
+    var snapshot Snapshot
+
+    if err := json.Unmarshal(data, &snapshot); err != nil {
+        return nil, fmt.Errorf("Failed to parse snapshot %s: %s", path, err.Error())
+    }
+
+    if snapshot.Version != SnapshotVersion {
+        return nil, fmt.Errorf("Snapshot %s has version %d - expected %d", path, snapshot.Version, SnapshotVersion)
+    }
+
+    return &snapshot, nil
+}
+
+// Save writes the snapshot as indented JSON - readable by its owner only, as cassettes are.
+func (s *Snapshot) Save(path string) error {
+
+    data, err := s.Marshal()
+
+    if err != nil {
+        return err
+    }
+
+    if err := os.WriteFile(path, data, 0o600); err != nil {
+        return fmt.Errorf("Failed to write snapshot %s: %s", path, err.Error())
+    }
+
+    return nil
+}
+
+// Marshal encodes the snapshot as indented JSON.
+func (s *Snapshot) Marshal() ([]byte, error) {
+
+    data, err := json.MarshalIndent(s, "", "  ")
+
+    if err != nil {
+        return nil, fmt.Errorf("Failed to encode snapshot: %s", err.Error())
+    }
+
+    return append(data, '\n'), nil
+}
+
+// ListWorkflowRunsByFileName returns the captured runs on the branch asked for, newest first.
+func (s *Snapshot) ListWorkflowRunsByFileName(ctx context.Context, owner string, repo string, workflowFileName string, opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error) {
+
+    if owner != s.Owner || repo != s.Repo || workflowFileName != s.Workflow {
+        return nil, snapshotResponse(http.StatusNotFound), fmt.Errorf("Snapshot holds runs of %s/%s %s only", s.Owner, s.Repo, s.Workflow)
+    }
+
+    runs := []*github.WorkflowRun{}
+
+    for _, run := range s.Runs {
+
+        if opts != nil && opts.Branch != "" && run.GetHeadBranch() != opts.Branch {
+            continue
+        }
+
+        runs = append(runs, run)
+    }
+
+    total := len(runs)
+
+    if opts != nil && opts.PerPage > 0 && len(runs) > opts.PerPage {
+        runs = runs[:opts.PerPage]
+    }
+
+    return &github.WorkflowRuns{TotalCount: github.Int(total), WorkflowRuns: runs}, snapshotResponse(http.StatusOK), nil
+}
+
+// GetWorkflowRunByID returns a captured run.
+func (s *Snapshot) GetWorkflowRunByID(ctx context.Context, owner string, repo string, runID int64) (*github.WorkflowRun, *github.Response, error) {
+
+    for _, run := range s.Runs {
+
+        if run.GetID() == runID {
+            return run, snapshotResponse(http.StatusOK), nil
+        }
+    }
+
+    return nil, snapshotResponse(http.StatusNotFound), fmt.Errorf("Snapshot holds no run with id %d", runID)
+}
+
+// ListWorkflowJobs fails - jobs are not captured.
+func (s *Snapshot) ListWorkflowJobs(ctx context.Context, owner string, repo string, runID int64, opts *github.ListWorkflowJobsOptions) (*github.Jobs, *github.Response, error) {
+
+    return nil, snapshotResponse(http.StatusNotFound), fmt.Errorf("Snapshot holds no jobs")
+}
+
+func snapshotResponse(statusCode int) *github.Response {
+
+    return &github.Response{Response: &http.Response{
+        StatusCode: statusCode,
+        Status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+        Header:     http.Header{},
+        Request:    &http.Request{Method: http.MethodGet, URL: &url.URL{Scheme: "snapshot", Path: "/"}},
+    }}
+}
@@ -0,0 +1,110 @@
+package gh
+
+import (
+    "context"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "os"
+    "path/filepath"
+    "runtime"
+    "testing"
+
+    log "github.com/sirupsen/logrus"
+)
+
+func TestSnapshot(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    client, mux, _, teardown := Setup()
+    defer teardown()
+
+    mux.HandleFunc("/repos/testowner/testrepo/actions/workflows/testfile.yaml/runs", func(w http.ResponseWriter, r *http.Request) {
+
+        if got := r.URL.Query().Get("per_page"); got != fmt.Sprint(SnapshotRuns) {
+            t.Errorf("TakeSnapshot() failed - expects per_page %d but received %s", SnapshotRuns, got)
+        }
+
+        fmt.Fprint(w, `{"total_count": 3, "workflow_runs": [
+            {"id": 3333333333, "run_number": 3, "head_branch": "main", "status": "queued"},
+            {"id": 2222222222, "run_number": 2, "head_branch": "main", "status": "in_progress"},
+            {"id": 1111111111, "run_number": 1, "head_branch": "main", "status": "completed", "conclusion": "success"}
+        ]}`)
+    })
+
+    taken, err := TakeSnapshot(context.Background(), client.Actions, "testowner", "testrepo", "testfile.yaml", "main")
+
+    if err != nil {
+        t.Fatalf("TakeSnapshot() failed - %s", err.Error())
+    }
+
+    path := filepath.Join(t.TempDir(), "snapshot.json")
+
+    if err := taken.Save(path); err != nil {
+        t.Fatalf("Save() failed - %s", err.Error())
+    }
+
+    if info, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+        t.Errorf("Save() failed - expects mode %v but received %v", os.FileMode(0o600), info.Mode().Perm())
+    }
+
+    snapshot, err := LoadSnapshot(path)
+
+    if err != nil {
+        t.Fatalf("LoadSnapshot() failed - %s", err.Error())
+    }
+
+    tests := []struct {
+        name         string
+        workflowFile string
+        branch       string
+        runsToReturn int
+        wantIDs      []int64
+        wantErr      string
+    }{
+        {name: "should serve captured runs newest first", workflowFile: "testfile.yaml", branch: "main", runsToReturn: 20, wantIDs: []int64{3333333333, 2222222222, 1111111111}},
+        {name: "should serve no more runs than asked for", workflowFile: "testfile.yaml", branch: "main", runsToReturn: 2, wantIDs: []int64{3333333333, 2222222222}},
+        {name: "should serve no runs of another branch", workflowFile: "testfile.yaml", branch: "feature", runsToReturn: 20, wantIDs: []int64{}},
+        {name: "should not serve another workflow", workflowFile: "other.yaml", branch: "main", runsToReturn: 20, wantErr: "Workflow not found"},
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            runs, err := ReturnWorkflowRuns(tt.branch, context.Background(), snapshot, "testowner", "testrepo", tt.workflowFile, tt.runsToReturn)
+
+            if tt.wantErr != "" {
+
+                if err == nil || err.Error() != tt.wantErr {
+                    t.Errorf("ReturnWorkflowRuns() failed - expects error '%s' but received %v", tt.wantErr, err)
+                }
+
+                return
+            }
+
+            gotIDs := []int64{}
+
+            for _, run := range runs {
+                gotIDs = append(gotIDs, run.GetID())
+            }
+
+            if fmt.Sprint(gotIDs) != fmt.Sprint(tt.wantIDs) {
+                t.Errorf("ReturnWorkflowRuns() failed - expects %v but received %v", tt.wantIDs, gotIDs)
+            }
+
+        })
+    }
+
+    run, err := ReturnWorkflowRun(context.Background(), snapshot, "testowner", "testrepo", 2222222222)
+
+    if err != nil || run.GetStatus() != "in_progress" {
+        t.Errorf("ReturnWorkflowRun() failed - expects the captured 'in_progress' run but received %v (%v)", run, err)
+    }
+
+    if _, _, err := snapshot.GetWorkflowRunByID(context.Background(), "testowner", "testrepo", 4444444444); err == nil {
+        t.Errorf("GetWorkflowRunByID() failed - expects an error for a run that was not captured")
+    }
+
+}
@@ -0,0 +1,186 @@
+package gh
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "net/url"
+    "os"
+    "path/filepath"
+    "runtime"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+// TokenOptions are the places FindToken looks for a token in, besides the environment.
+type TokenOptions struct {
+    // File is a file holding the token (e.g. a Kubernetes or Docker secret mount).
+    File string
+
+    // Stdin is read for the token when not nil.
+    Stdin io.Reader
+
+    // APIURL selects the host of enterprise tokens and of the Github CLI's hosts.yml - github.com when empty.
+    APIURL string
+
+    // HostsFile is the Github CLI's hosts.yml - its default location when empty.
+    HostsFile string
+
+    // Getenv reads the environment - os.Getenv when nil.
+    Getenv func(string) string
+}
+
+// FindToken returns the token and where it was found - the first of, in order: File, Stdin, GH_TOKEN,
+// GITHUB_TOKEN, GH_ENTERPRISE_TOKEN or GITHUB_ENTERPRISE_TOKEN (for hosts other than github.com) and the token
+// the Github CLI stored for the host in hosts.yml. The token is empty when none was found; a file or stdin that
+// was asked for but holds no token is an error.
+func FindToken(opts TokenOptions) (string, string, error) {
+
+    getenv := opts.Getenv
+
+    if getenv == nil {
+        getenv = os.Getenv
+    }
+
+    if opts.File != "" && opts.Stdin != nil {
+        return "", "", fmt.Errorf("--token-file and --token-stdin cannot be combined")
+    }
+
+    if opts.File != "" {
+
+        data, err := os.ReadFile(opts.File)
+
+        if err != nil {
+            return "", "", fmt.Errorf("Reading token file failed: %s", err.Error())
+        }
+
+        if token := strings.TrimSpace(string(data)); token != "" {
+            return token, fmt.Sprintf("token file %s", opts.File), nil
+        }
+
+        return "", "", fmt.Errorf("Token file %s is empty", opts.File)
+    }
+
+    if opts.Stdin != nil {
+
+        // the first line only - a token piped in needs no end of input:
+        line, err := bufio.NewReader(opts.Stdin).ReadString('\n')
+
+        if err != nil && err != io.EOF {
+            return "", "", fmt.Errorf("Reading token from stdin failed: %s", err.Error())
+        }
+
+        if token := strings.TrimSpace(line); token != "" {
+            return token, "stdin", nil
+        }
+
+        return "", "", fmt.Errorf("No token was passed on stdin")
+    }
+
+    host := tokenHost(opts.APIURL)
+
+    names := []string{"GH_TOKEN", "GITHUB_TOKEN"}
+
+    if host != "github.com" {
+        names = append(names, "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN")
+    }
+
+    for _, name := range names {
+
+        if token := strings.TrimSpace(getenv(name)); token != "" {
+            return token, name, nil
+        }
+    }
+
+    hostsFile := opts.HostsFile
+
+    if hostsFile == "" {
+        hostsFile = defaultHostsFile(getenv)
+    }
+
+    token, err := hostsFileToken(hostsFile, host)
+
+    if err != nil || token == "" {
+        return "", "", err
+    }
+
+    return token, fmt.Sprintf("Github CLI %s (%s)", hostsFile, host), nil
+}
+
+// hostsFileToken reads the token stored for host in the Github CLI's hosts.yml - a file that does not exist
+// holds none. Tokens the Github CLI keeps in the system keyring are not in the file.
+func hostsFileToken(hostsFile string, host string) (string, error) {
+
+    data, err := os.ReadFile(hostsFile)
+
+    if os.IsNotExist(err) {
+        return "", nil
+    }
+
+    if err != nil {
+        return "", fmt.Errorf("Reading Github CLI hosts file failed: %s", err.Error())
+    }
+
+    type account struct {
+        OAuthToken string `yaml:"oauth_token"`
+    }
+
+    hosts := map[string]struct {
+        OAuthToken string             `yaml:"oauth_token"`
+        User       string             `yaml:"user"`
+        Users      map[string]account `yaml:"users"`
+    }{}
+
+    if err := yaml.Unmarshal(data, &hosts); err != nil {
+        return "", fmt.Errorf("Invalid Github CLI hosts file %s: %s", hostsFile, err.Error())
+    }
+
+    entry := hosts[host]
+
+    // newer versions of the Github CLI keep a token per account, the active one named by 'user':
+    if entry.OAuthToken == "" {
+        return entry.Users[entry.User].OAuthToken, nil
+    }
+
+    return entry.OAuthToken, nil
+}
+
+// defaultHostsFile is where the Github CLI keeps hosts.yml.
+func defaultHostsFile(getenv func(string) string) string {
+
+    switch {
+
+    case getenv("GH_CONFIG_DIR") != "":
+        return filepath.Join(getenv("GH_CONFIG_DIR"), "hosts.yml")
+
+    case getenv("XDG_CONFIG_HOME") != "":
+        return filepath.Join(getenv("XDG_CONFIG_HOME"), "gh", "hosts.yml")
+
+    case runtime.GOOS == "windows" && getenv("AppData") != "":
+        return filepath.Join(getenv("AppData"), "GitHub CLI", "hosts.yml")
+    }
+
+    home, _ := os.UserHomeDir()
+
+    return filepath.Join(home, ".config", "gh", "hosts.yml")
+}
+
+// tokenHost is the host tokens are kept for - github.com for api.github.com, the host of the API otherwise
+// (without the 'api.' of a ghe.com subdomain).
+func tokenHost(apiURL string) string {
+
+    parsed, err := url.Parse(apiURL)
+
+    if apiURL == "" || err != nil || parsed.Hostname() == "" || parsed.Hostname() == "api.github.com" {
+        return "github.com"
+    }
+
+    host := parsed.Hostname()
+
+    if strings.HasSuffix(host, ".ghe.com") {
+        return strings.TrimPrefix(host, "api.")
+    }
+
+    return host
+}
@@ -0,0 +1,169 @@
+package gh
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestFindToken(t *testing.T){
+
+    dir := t.TempDir()
+
+    write := func(name string, content string) string {
+        file := filepath.Join(dir, name)
+        os.WriteFile(file, []byte(content), 0600)
+        return file
+    }
+
+    secret := write("secret", "ghp_fromfile\n")
+    empty := write("empty", "\n")
+
+    hosts := write("hosts.yml", `
+github.com:
+    user: octocat
+    oauth_token: gho_github
+    git_protocol: https
+ghe.example.com:
+    user: monalisa
+    users:
+        monalisa:
+            oauth_token: gho_enterprise
+`)
+
+    tests := []struct {
+        name       string
+        opts       TokenOptions
+        env        map[string]string
+        wantToken  string
+        wantSource string
+        wantErr    string
+    }{
+        {
+            name:       "should prefer --token-file over the environment",
+            opts:       TokenOptions{File: secret},
+            env:        map[string]string{"GH_TOKEN": "ghp_env"},
+            wantToken:  "ghp_fromfile",
+            wantSource: "token file " + secret,
+        },
+        {
+            name:       "should read the first line of stdin",
+            opts:       TokenOptions{Stdin: strings.NewReader("ghp_stdin\nrest\n")},
+            env:        map[string]string{"GH_TOKEN": "ghp_env"},
+            wantToken:  "ghp_stdin",
+            wantSource: "stdin",
+        },
+        {
+            name:       "should prefer GH_TOKEN over GITHUB_TOKEN",
+            env:        map[string]string{"GH_TOKEN": "ghp_gh", "GITHUB_TOKEN": "ghp_github"},
+            wantToken:  "ghp_gh",
+            wantSource: "GH_TOKEN",
+        },
+        {
+            name:       "should read GITHUB_TOKEN",
+            env:        map[string]string{"GITHUB_TOKEN": "ghp_github", "GH_ENTERPRISE_TOKEN": "ghp_enterprise"},
+            wantToken:  "ghp_github",
+            wantSource: "GITHUB_TOKEN",
+        },
+        {
+            name:      "should ignore GH_ENTERPRISE_TOKEN for github.com",
+            opts:      TokenOptions{HostsFile: filepath.Join(dir, "missing.yml")},
+            env:       map[string]string{"GH_ENTERPRISE_TOKEN": "ghp_enterprise"},
+            wantToken: "",
+        },
+        {
+            name:       "should read GH_ENTERPRISE_TOKEN for an enterprise server",
+            opts:       TokenOptions{APIURL: "https://ghe.example.com/api/v3"},
+            env:        map[string]string{"GH_ENTERPRISE_TOKEN": "ghp_enterprise"},
+            wantToken:  "ghp_enterprise",
+            wantSource: "GH_ENTERPRISE_TOKEN",
+        },
+        {
+            name:       "should read the Github CLI's token for github.com",
+            opts:       TokenOptions{HostsFile: hosts},
+            wantToken:  "gho_github",
+            wantSource: "Github CLI " + hosts + " (github.com)",
+        },
+        {
+            name:       "should read the Github CLI's token of the active account",
+            opts:       TokenOptions{HostsFile: hosts, APIURL: "https://ghe.example.com/api/v3/"},
+            wantToken:  "gho_enterprise",
+            wantSource: "Github CLI " + hosts + " (ghe.example.com)",
+        },
+        {
+            name:      "should find no token for an unknown host",
+            opts:      TokenOptions{HostsFile: hosts, APIURL: "https://other.example.com/api/v3"},
+            wantToken: "",
+        },
+        {
+            name:    "should fail on an empty token file",
+            opts:    TokenOptions{File: empty},
+            env:     map[string]string{"GH_TOKEN": "ghp_env"},
+            wantErr: "is empty",
+        },
+        {
+            name:    "should fail on a missing token file",
+            opts:    TokenOptions{File: filepath.Join(dir, "missing")},
+            wantErr: "Reading token file failed",
+        },
+        {
+            name:    "should fail on empty stdin",
+            opts:    TokenOptions{Stdin: strings.NewReader("")},
+            wantErr: "No token was passed on stdin",
+        },
+        {
+            name:    "should fail on both a token file and stdin",
+            opts:    TokenOptions{File: secret, Stdin: strings.NewReader("ghp_stdin")},
+            wantErr: "cannot be combined",
+        },
+        {
+            name:    "should fail on an invalid hosts file",
+            opts:    TokenOptions{HostsFile: write("invalid.yml", "github.com: [")},
+            wantErr: "Invalid Github CLI hosts file",
+        },
+    }
+
+    for _, tt := range tests {
+
+        env := tt.env
+        tt.opts.Getenv = func(name string) string { return env[name] }
+
+        if tt.opts.HostsFile == "" {
+            tt.opts.HostsFile = filepath.Join(dir, "missing.yml")
+        }
+
+        token, source, err := FindToken(tt.opts)
+
+        if tt.wantErr != "" {
+
+            if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+                t.Errorf("FindToken() %s failed - expects an error containing '%s' but received %v", tt.name, tt.wantErr, err)
+            }
+
+            continue
+        }
+
+        if err != nil || token != tt.wantToken || source != tt.wantSource {
+            t.Errorf("FindToken() %s failed - expects '%s' from '%s' but received '%s' from '%s' (%v)", tt.name, tt.wantToken, tt.wantSource, token, source, err)
+        }
+    }
+}
+
+func TestDefaultHostsFile(t *testing.T){
+
+    tests := []struct {
+        env  map[string]string
+        want string
+    }{
+        {env: map[string]string{"GH_CONFIG_DIR": "/config/gh", "XDG_CONFIG_HOME": "/xdg"}, want: filepath.Join("/config/gh", "hosts.yml")},
+        {env: map[string]string{"XDG_CONFIG_HOME": "/xdg"}, want: filepath.Join("/xdg", "gh", "hosts.yml")},
+    }
+
+    for _, tt := range tests {
+
+        if got := defaultHostsFile(func(name string) string { return tt.env[name] }); got != tt.want {
+            t.Errorf("defaultHostsFile(%v) failed - expects %s but received %s", tt.env, tt.want, got)
+        }
+    }
+}
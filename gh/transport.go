@@ -0,0 +1,208 @@
+package gh
+
+import (
+    "crypto/sha256"
+    "crypto/tls"
+    "crypto/x509"
+    "fmt"
+    "net"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    log "github.com/sirupsen/logrus"
+)
+
+// TransportOptions configure the connection to the API - zero values keep Go's defaults.
+type TransportOptions struct {
+    // CACert is a PEM file, or a directory of PEM files, with certificates trusted besides the system's.
+    CACert string
+
+    // ClientCert and ClientKey authenticate the client for mTLS - ClientKey defaults to ClientCert, for a
+    // file holding both.
+    ClientCert string
+    ClientKey  string
+
+    // ConnectTimeout limits connecting and the TLS handshake, ResponseTimeout waiting for the response headers.
+    ConnectTimeout  time.Duration
+    ResponseTimeout time.Duration
+
+    // DebugTLS logs the certificate chain the server presented - as an error when it fails verification.
+    DebugTLS bool
+}
+
+// NewTransport returns the transport for API calls - through the proxy set in HTTPS_PROXY (HTTP_PROXY for
+// plain http) unless the host is in NO_PROXY, trusting opts.CACert besides the system's certificates.
+func NewTransport(opts TransportOptions) (*http.Transport, error) {
+
+    transport := http.DefaultTransport.(*http.Transport).Clone()
+    transport.Proxy = http.ProxyFromEnvironment
+
+    if opts.ConnectTimeout > 0 {
+        transport.DialContext = (&net.Dialer{Timeout: opts.ConnectTimeout, KeepAlive: 30 * time.Second}).DialContext
+        transport.TLSHandshakeTimeout = opts.ConnectTimeout
+    }
+
+    transport.ResponseHeaderTimeout = opts.ResponseTimeout
+
+    if opts.ClientKey != "" && opts.ClientCert == "" {
+        return nil, fmt.Errorf("A client key needs a client certificate - pass --client-cert")
+    }
+
+    if opts.CACert == "" && opts.ClientCert == "" && !opts.DebugTLS {
+        return transport, nil
+    }
+
+    tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+    if opts.CACert != "" {
+
+        roots, err := loadCertPool(opts.CACert)
+
+        if err != nil {
+            return nil, err
+        }
+
+        tlsConfig.RootCAs = roots
+    }
+
+    if opts.ClientCert != "" {
+
+        keyFile := opts.ClientKey
+
+        if keyFile == "" {
+            keyFile = opts.ClientCert
+        }
+
+        cert, err := tls.LoadX509KeyPair(opts.ClientCert, keyFile)
+
+        if err != nil {
+            return nil, fmt.Errorf("Loading client certificate '%s' failed: %s", opts.ClientCert, err.Error())
+        }
+
+        tlsConfig.Certificates = []tls.Certificate{cert}
+    }
+
+    // the chain is verified here instead of by crypto/tls, so it can be logged when verification fails:
+    if opts.DebugTLS {
+        tlsConfig.InsecureSkipVerify = true
+        tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+            return verifyConnection(state, tlsConfig.RootCAs)
+        }
+    }
+
+    transport.TLSClientConfig = tlsConfig
+
+    return transport, nil
+}
+
+// verifyConnection verifies the server's chain like crypto/tls does and logs it.
+func verifyConnection(state tls.ConnectionState, roots *x509.CertPool) error {
+
+    if len(state.PeerCertificates) == 0 {
+        return fmt.Errorf("TLS handshake with %s failed: the server presented no certificate", state.ServerName)
+    }
+
+    intermediates := x509.NewCertPool()
+
+    for _, cert := range state.PeerCertificates[1:] {
+        intermediates.AddCert(cert)
+    }
+
+    _, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+        DNSName:       state.ServerName,
+        Roots:         roots,
+        Intermediates: intermediates,
+    })
+
+    for i, cert := range state.PeerCertificates {
+
+        entry := log.WithFields(log.Fields{
+            "server":      state.ServerName,
+            "position":    i,
+            "subject":     cert.Subject.String(),
+            "issuer":      cert.Issuer.String(),
+            "notBefore":   cert.NotBefore.UTC().Format(time.RFC3339),
+            "notAfter":    cert.NotAfter.UTC().Format(time.RFC3339),
+            "dnsNames":    strings.Join(cert.DNSNames, ","),
+            "fingerprint": fmt.Sprintf("%x", sha256.Sum256(cert.Raw)),
+        })
+
+        if err != nil {
+            entry.Error("Certificate presented ...")
+        } else {
+            entry.Debug("Certificate presented ...")
+        }
+    }
+
+    if err != nil {
+
+        log.WithFields(log.Fields{
+            "server": state.ServerName,
+        }).Error(fmt.Sprintf("TLS verification failed: %s - trust the issuing CA with --ca-cert", err.Error()))
+
+        return err
+    }
+
+    return nil
+}
+
+// loadCertPool returns the system's certificates and those in path - a PEM file or a directory of them.
+func loadCertPool(path string) (*x509.CertPool, error) {
+
+    pool, err := x509.SystemCertPool()
+
+    if err != nil || pool == nil {
+        pool = x509.NewCertPool()
+    }
+
+    info, err := os.Stat(path)
+
+    if err != nil {
+        return nil, fmt.Errorf("Reading CA certificates failed: %s", err.Error())
+    }
+
+    files := []string{path}
+
+    if info.IsDir() {
+
+        entries, err := os.ReadDir(path)
+
+        if err != nil {
+            return nil, fmt.Errorf("Reading CA certificates failed: %s", err.Error())
+        }
+
+        files = []string{}
+
+        for _, entry := range entries {
+
+            if !entry.IsDir() {
+                files = append(files, filepath.Join(path, entry.Name()))
+            }
+        }
+    }
+
+    added := 0
+
+    for _, file := range files {
+
+        data, err := os.ReadFile(file)
+
+        if err != nil {
+            return nil, fmt.Errorf("Reading CA certificates failed: %s", err.Error())
+        }
+
+        // files of a directory that hold no certificates (e.g. a README) are skipped:
+        if pool.AppendCertsFromPEM(data) {
+            added++
+        }
+    }
+
+    if added == 0 {
+        return nil, fmt.Errorf("No PEM certificates found in '%s'", path)
+    }
+
+    return pool, nil
+}
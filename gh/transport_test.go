@@ -0,0 +1,228 @@
+package gh
+
+import (
+    "bytes"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/tls"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "encoding/pem"
+    "io/ioutil"
+    "math/big"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    log "github.com/sirupsen/logrus"
+)
+
+func TestNewTransport(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    dir := t.TempDir()
+
+    server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+    defer server.Close()
+
+    serverCA := writePEM(t, filepath.Join(dir, "server-ca.pem"), "CERTIFICATE", server.Certificate().Raw)
+
+    caDir := filepath.Join(dir, "cas")
+    os.Mkdir(caDir, 0700)
+    writePEM(t, filepath.Join(caDir, "server-ca.crt"), "CERTIFICATE", server.Certificate().Raw)
+    os.WriteFile(filepath.Join(caDir, "README"), []byte("internal CAs"), 0600)
+
+    // a server that only talks to clients with a certificate of clientCA:
+    clientCA, clientCert, clientKey := newClientCertificate(t, dir)
+
+    mtlsServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+    mtlsServer.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCA}
+    mtlsServer.StartTLS()
+    defer mtlsServer.Close()
+
+    writePEM(t, filepath.Join(caDir, "mtls-ca.pem"), "CERTIFICATE", mtlsServer.Certificate().Raw)
+
+    tests := []struct {
+        name    string
+        opts    TransportOptions
+        url     string
+        wantErr string
+    }{
+        {
+            name:    "should reject an unknown CA",
+            opts:    TransportOptions{},
+            url:     server.URL,
+            wantErr: "certificate",
+        },
+        {
+            name: "should trust a CA file",
+            opts: TransportOptions{CACert: serverCA},
+            url:  server.URL,
+        },
+        {
+            name: "should trust a directory of CAs",
+            opts: TransportOptions{CACert: caDir},
+            url:  server.URL,
+        },
+        {
+            name: "should verify the chain itself with --tls-debug",
+            opts: TransportOptions{CACert: serverCA, DebugTLS: true},
+            url:  server.URL,
+        },
+        {
+            name:    "should fail verification with --tls-debug",
+            opts:    TransportOptions{DebugTLS: true},
+            url:     server.URL,
+            wantErr: "certificate",
+        },
+        {
+            name:    "should fail without a client certificate",
+            opts:    TransportOptions{CACert: caDir},
+            url:     mtlsServer.URL,
+            wantErr: "certificate",
+        },
+        {
+            name: "should authenticate with a client certificate",
+            opts: TransportOptions{CACert: caDir, ClientCert: clientCert, ClientKey: clientKey},
+            url:  mtlsServer.URL,
+        },
+    }
+
+    for _, tt := range tests {
+
+        transport, err := NewTransport(tt.opts)
+
+        if err != nil {
+            t.Errorf("NewTransport() %s failed - %s", tt.name, err.Error())
+            continue
+        }
+
+        res, err := (&http.Client{Transport: transport, Timeout: 5 * time.Second}).Get(tt.url)
+
+        if res != nil {
+            res.Body.Close()
+        }
+
+        switch {
+
+        case tt.wantErr == "" && err != nil:
+            t.Errorf("NewTransport() %s failed - expects the request to succeed but received %s", tt.name, err.Error())
+
+        case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+            t.Errorf("NewTransport() %s failed - expects an error containing '%s' but received %v", tt.name, tt.wantErr, err)
+        }
+    }
+}
+
+func TestNewTransportErrors(t *testing.T){
+
+    dir := t.TempDir()
+
+    notPEM := filepath.Join(dir, "not.pem")
+    os.WriteFile(notPEM, []byte("not a certificate"), 0600)
+
+    tests := []struct {
+        name    string
+        opts    TransportOptions
+        wantErr string
+    }{
+        {name: "missing CA file", opts: TransportOptions{CACert: filepath.Join(dir, "missing.pem")}, wantErr: "Reading CA certificates failed"},
+        {name: "CA file without certificates", opts: TransportOptions{CACert: notPEM}, wantErr: "No PEM certificates found"},
+        {name: "empty CA directory", opts: TransportOptions{CACert: t.TempDir()}, wantErr: "No PEM certificates found"},
+        {name: "invalid client certificate", opts: TransportOptions{ClientCert: notPEM}, wantErr: "Loading client certificate"},
+        {name: "client key without certificate", opts: TransportOptions{ClientKey: notPEM}, wantErr: "needs a client certificate"},
+    }
+
+    for _, tt := range tests {
+
+        if _, err := NewTransport(tt.opts); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+            t.Errorf("NewTransport() %s failed - expects an error containing '%s' but received %v", tt.name, tt.wantErr, err)
+        }
+    }
+}
+
+func TestVerifyConnectionLogsChain(t *testing.T){
+
+    var out bytes.Buffer
+    log.SetOutput(&out)
+    defer log.SetOutput(ioutil.Discard)
+
+    server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+    defer server.Close()
+
+    transport, _ := NewTransport(TransportOptions{DebugTLS: true})
+
+    if _, err := (&http.Client{Transport: transport}).Get(server.URL); err == nil {
+        t.Fatalf("verifyConnection() failed - expects an untrusted chain to fail")
+    }
+
+    for _, want := range []string{"Certificate presented", "fingerprint=", "issuer=\"O=Acme Co\"", "TLS verification failed", "--ca-cert"} {
+
+        if !strings.Contains(out.String(), want) {
+            t.Errorf("verifyConnection() failed - expects the log to contain %q but received %q", want, out.String())
+        }
+    }
+}
+
+func writePEM(t *testing.T, file string, blockType string, der []byte) string {
+
+    t.Helper()
+
+    if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+        t.Fatalf("writing %s failed - %s", file, err.Error())
+    }
+
+    return file
+}
+
+// newClientCertificate creates a CA and a client certificate it issued - returning the CA's pool and the files
+// of the certificate and its key.
+func newClientCertificate(t *testing.T, dir string) (*x509.CertPool, string, string) {
+
+    t.Helper()
+
+    caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+    caTemplate := &x509.Certificate{
+        SerialNumber:          big.NewInt(1),
+        Subject:               pkix.Name{CommonName: "client CA"},
+        NotBefore:             time.Now().Add(-time.Hour),
+        NotAfter:              time.Now().Add(time.Hour),
+        IsCA:                  true,
+        KeyUsage:              x509.KeyUsageCertSign,
+        BasicConstraintsValid: true,
+    }
+
+    caDER, _ := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+    ca, _ := x509.ParseCertificate(caDER)
+
+    key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+    template := &x509.Certificate{
+        SerialNumber: big.NewInt(2),
+        Subject:      pkix.Name{CommonName: "sorter"},
+        NotBefore:    time.Now().Add(-time.Hour),
+        NotAfter:     time.Now().Add(time.Hour),
+        KeyUsage:     x509.KeyUsageDigitalSignature,
+        ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+    }
+
+    der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+
+    if err != nil {
+        t.Fatalf("creating client certificate failed - %s", err.Error())
+    }
+
+    keyDER, _ := x509.MarshalECPrivateKey(key)
+
+    pool := x509.NewCertPool()
+    pool.AddCert(ca)
+
+    return pool, writePEM(t, filepath.Join(dir, "client.pem"), "CERTIFICATE", der), writePEM(t, filepath.Join(dir, "client-key.pem"), "EC PRIVATE KEY", keyDER)
+}
@@ -0,0 +1,17 @@
+package gh
+
+import (
+    "context"
+
+    "github.com/google/go-github/v47/github"
+)
+
+// WorkflowRunsAPI covers the Github Actions API calls the tool makes. go-github's ActionsService
+// (client.Actions) implements it; ghfake.API is an in-memory implementation for tests.
+type WorkflowRunsAPI interface {
+    ListWorkflowRunsByFileName(ctx context.Context, owner string, repo string, workflowFileName string, opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error)
+    GetWorkflowRunByID(ctx context.Context, owner string, repo string, runID int64) (*github.WorkflowRun, *github.Response, error)
+    ListWorkflowJobs(ctx context.Context, owner string, repo string, runID int64, opts *github.ListWorkflowJobsOptions) (*github.Jobs, *github.Response, error)
+}
+
+var _ WorkflowRunsAPI = (*github.ActionsService)(nil)
@@ -0,0 +1,579 @@
+// Package ghfake is an in-memory gh.WorkflowRunsAPI for tests. Runs are added up front and scripted to change
+// state after a number of polls or at a point in time - e.g. a predecessor that completes on its fourth check:
+//
+//  api := ghfake.New()
+//  api.AddRun(ghfake.Run{ID: 3333333333, RunNumber: 30, Workflow: "release.yml", Branch: "main", Status: "in_progress"})
+//  api.Script(3333333333, ghfake.Change{AfterPolls: 3, Status: "completed", Conclusion: "success"})
+package ghfake
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "net/url"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/google/go-github/v47/github"
+)
+
+// Run is a workflow run held by the fake.
+type Run struct {
+    ID           int64
+    RunNumber    int
+    RunAttempt   int
+    Workflow     string
+    Branch       string
+    Event        string
+    HeadSHA      string
+    Status       string
+    Conclusion   string
+    CreatedAt    time.Time
+    RunStartedAt time.Time
+    UpdatedAt    time.Time
+    Jobs         []Job
+}
+
+// Job is a job of a Run.
+type Job struct {
+    Name        string
+    Status      string
+    Conclusion  string
+    StartedAt   time.Time
+    CompletedAt time.Time
+}
+
+// Change is a scripted change to a run - applied once the run was polled AfterPolls times (GetWorkflowRunByID)
+// and At has passed; a Change with neither applies on the next call. Empty fields are left as they are - except
+// Conclusion, which is cleared by a change of Status - and UpdatedAt defaults to the time the change applied.
+type Change struct {
+    AfterPolls int
+    At         time.Time
+    Status     string
+    Conclusion string
+    RunAttempt int
+    UpdatedAt  time.Time
+}
+
+// Fault is an injected failure or delay of calls - calls to Method (any method when empty) for RunID (any run when 0)
+// made between From and Until (zero for no bound) wait Latency and then fail with StatusCode (not at all when 0).
+// Times limits the number of calls the fault applies to - every call in the window when 0. A 403 fails like an
+// exhausted rate limit.
+type Fault struct {
+    Method     string
+    RunID      int64
+    From       time.Time
+    Until      time.Time
+    Times      int
+    StatusCode int
+    Latency    time.Duration
+}
+
+// Call is a call made to the fake.
+type Call struct {
+    Method string
+    RunID  int64
+}
+
+// API is an in-memory gh.WorkflowRunsAPI for a single repository - safe for concurrent use.
+type API struct {
+    // Now is the fake's clock - time.Now when nil.
+    Now func() time.Time
+
+    mu        sync.Mutex
+    runs      map[int64]*Run
+    workflows map[string]bool
+    scripts   map[int64][]Change
+    polls     map[int64]int
+    faults    []*Fault
+    calls     []Call
+}
+
+func New() *API {
+
+    return &API{
+        runs:      map[int64]*Run{},
+        workflows: map[string]bool{},
+        scripts:   map[int64][]Change{},
+        polls:     map[int64]int{},
+    }
+}
+
+// AddRun adds or replaces a run - Status defaults to 'queued', RunAttempt to 1 and CreatedAt/UpdatedAt to now.
+// A run created in the future does not exist until then.
+func (a *API) AddRun(run Run) {
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    if run.Status == "" {
+        run.Status = "queued"
+    }
+
+    if run.RunAttempt == 0 {
+        run.RunAttempt = 1
+    }
+
+    if run.CreatedAt.IsZero() {
+        run.CreatedAt = a.now()
+    }
+
+    if run.UpdatedAt.IsZero() {
+        run.UpdatedAt = run.CreatedAt
+    }
+
+    a.runs[run.ID] = &run
+    a.workflows[run.Workflow] = true
+}
+
+// AddWorkflow makes a workflow known without adding runs - listing runs of an unknown workflow returns 404.
+func (a *API) AddWorkflow(workflowFile string) {
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    a.workflows[workflowFile] = true
+}
+
+// Script queues changes to the run with id, applied in order.
+func (a *API) Script(id int64, changes ...Change) {
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    a.scripts[id] = append(a.scripts[id], changes...)
+}
+
+// Update changes the status and conclusion of the run with id right away.
+func (a *API) Update(id int64, status string, conclusion string) {
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    if run, ok := a.runs[id]; ok {
+        a.apply(run, Change{Status: status, Conclusion: conclusion})
+    }
+}
+
+// FailNext makes the next call fail with statusCode - once per statusCode passed.
+func (a *API) FailNext(statusCodes ...int) {
+
+    for _, statusCode := range statusCodes {
+        a.AddFault(Fault{StatusCode: statusCode, Times: 1})
+    }
+}
+
+// AddFault injects a fault - faults are checked in the order they were added and the first that applies is used.
+func (a *API) AddFault(fault Fault) {
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    a.faults = append(a.faults, &fault)
+}
+
+// Run returns the current state of the run with id.
+func (a *API) Run(id int64) (Run, bool) {
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    run, ok := a.runs[id]
+
+    if !ok {
+        return Run{}, false
+    }
+
+    return *run, true
+}
+
+// Polls is the number of times the run with id was fetched by GetWorkflowRunByID.
+func (a *API) Polls(id int64) int {
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    return a.polls[id]
+}
+
+// Calls returns every call made so far, in order.
+func (a *API) Calls() []Call {
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    return append([]Call{}, a.calls...)
+}
+
+func (a *API) ListWorkflowRunsByFileName(ctx context.Context, owner string, repo string, workflowFileName string, opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error) {
+
+    path := fmt.Sprintf("repos/%s/%s/actions/workflows/%s/runs", owner, repo, workflowFileName)
+
+    if res, err := a.call(ctx, "ListWorkflowRunsByFileName", 0, path); err != nil {
+        return nil, res, err
+    }
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    if !a.workflows[workflowFileName] {
+        return nil, response(http.StatusNotFound, path), errorResponse(http.StatusNotFound, path)
+    }
+
+    matching := []*Run{}
+
+    for id, run := range a.runs {
+
+        a.applyDue(id, false)
+
+        if !a.exists(run) || run.Workflow != workflowFileName || opts != nil && opts.Branch != "" && run.Branch != opts.Branch {
+            continue
+        }
+
+        matching = append(matching, run)
+    }
+
+    // newest first, like the API:
+    sort.Slice(matching, func(i, j int) bool {
+        return matching[i].RunNumber > matching[j].RunNumber
+    })
+
+    runs := &github.WorkflowRuns{TotalCount: github.Int(len(matching))}
+
+    if opts != nil {
+        matching = page(matching, opts.Page, opts.PerPage)
+    }
+
+    for _, run := range matching {
+        runs.WorkflowRuns = append(runs.WorkflowRuns, workflowRun(owner, repo, run))
+    }
+
+    return runs, response(http.StatusOK, path), nil
+}
+
+// ListWorkflows lists the known workflows in file name order - each named after its file, with its position
+// as its ID.
+func (a *API) ListWorkflows(ctx context.Context, owner string, repo string, opts *github.ListOptions) (*github.Workflows, *github.Response, error) {
+
+    path := fmt.Sprintf("repos/%s/%s/actions/workflows", owner, repo)
+
+    if res, err := a.call(ctx, "ListWorkflows", 0, path); err != nil {
+        return nil, res, err
+    }
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    files := []string{}
+
+    for file := range a.workflows {
+        files = append(files, file)
+    }
+
+    sort.Strings(files)
+
+    workflows := &github.Workflows{TotalCount: github.Int(len(files))}
+
+    for i, file := range files {
+        workflows.Workflows = append(workflows.Workflows, &github.Workflow{
+            ID:    github.Int64(int64(i + 1)),
+            Name:  github.String(strings.TrimSuffix(file, filepath.Ext(file))),
+            Path:  github.String(".github/workflows/" + file),
+            State: github.String("active"),
+        })
+    }
+
+    return workflows, response(http.StatusOK, path), nil
+}
+
+func (a *API) GetWorkflowRunByID(ctx context.Context, owner string, repo string, runID int64) (*github.WorkflowRun, *github.Response, error) {
+
+    path := fmt.Sprintf("repos/%s/%s/actions/runs/%d", owner, repo, runID)
+
+    if res, err := a.call(ctx, "GetWorkflowRunByID", runID, path); err != nil {
+        return nil, res, err
+    }
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    run, ok := a.runs[runID]
+
+    if !ok || !a.exists(run) {
+        return nil, response(http.StatusNotFound, path), errorResponse(http.StatusNotFound, path)
+    }
+
+    a.applyDue(runID, true)
+    a.polls[runID]++
+
+    return workflowRun(owner, repo, run), response(http.StatusOK, path), nil
+}
+
+func (a *API) ListWorkflowJobs(ctx context.Context, owner string, repo string, runID int64, opts *github.ListWorkflowJobsOptions) (*github.Jobs, *github.Response, error) {
+
+    path := fmt.Sprintf("repos/%s/%s/actions/runs/%d/jobs", owner, repo, runID)
+
+    if res, err := a.call(ctx, "ListWorkflowJobs", runID, path); err != nil {
+        return nil, res, err
+    }
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    run, ok := a.runs[runID]
+
+    if !ok || !a.exists(run) {
+        return nil, response(http.StatusNotFound, path), errorResponse(http.StatusNotFound, path)
+    }
+
+    jobs := &github.Jobs{TotalCount: github.Int(len(run.Jobs))}
+
+    for _, job := range run.Jobs {
+        jobs.Jobs = append(jobs.Jobs, workflowJob(run, job))
+    }
+
+    return jobs, response(http.StatusOK, path), nil
+}
+
+// call records a call, waits the latency of an injected fault and returns the error the call fails with -
+// ctx being done or an injected fault.
+func (a *API) call(ctx context.Context, method string, runID int64, path string) (*github.Response, error) {
+
+    a.mu.Lock()
+
+    a.calls = append(a.calls, Call{Method: method, RunID: runID})
+
+    fault := a.fault(method, runID)
+
+    a.mu.Unlock()
+
+    if err := ctx.Err(); err != nil {
+        return nil, err
+    }
+
+    if fault == nil {
+        return nil, nil
+    }
+
+    // latency is waited without holding the lock - other calls go on meanwhile:
+    if fault.Latency > 0 {
+
+        timer := time.NewTimer(fault.Latency)
+        defer timer.Stop()
+
+        select {
+        case <-ctx.Done():
+            return nil, ctx.Err()
+        case <-timer.C:
+        }
+    }
+
+    if fault.StatusCode == 0 {
+        return nil, nil
+    }
+
+    if fault.StatusCode == http.StatusForbidden {
+        return rateLimitResponse(a.now(), path), rateLimitError(a.now(), path)
+    }
+
+    return response(fault.StatusCode, path), errorResponse(fault.StatusCode, path)
+}
+
+// fault returns the first fault that applies to a call and uses it up.
+func (a *API) fault(method string, runID int64) *Fault {
+
+    now := a.now()
+
+    for i, fault := range a.faults {
+
+        if fault.Method != "" && fault.Method != method || fault.RunID != 0 && fault.RunID != runID {
+            continue
+        }
+
+        if !fault.From.IsZero() && now.Before(fault.From) || !fault.Until.IsZero() && !now.Before(fault.Until) {
+            continue
+        }
+
+        if fault.Times > 0 {
+
+            fault.Times--
+
+            if fault.Times == 0 {
+                a.faults = append(a.faults[:i:i], a.faults[i+1:]...)
+            }
+        }
+
+        return fault
+    }
+
+    return nil
+}
+
+// exists is false for runs created in the future.
+func (a *API) exists(run *Run) bool {
+
+    return !run.CreatedAt.After(a.now())
+}
+
+// applyDue applies the scripted changes of the run with id that are due - polled counts a poll in progress.
+func (a *API) applyDue(id int64, polled bool) {
+
+    run := a.runs[id]
+
+    for len(a.scripts[id]) > 0 {
+
+        change := a.scripts[id][0]
+
+        if change.AfterPolls > a.polls[id] || change.AfterPolls > 0 && !polled || !change.At.IsZero() && a.now().Before(change.At) {
+            return
+        }
+
+        a.apply(run, change)
+        a.scripts[id] = a.scripts[id][1:]
+    }
+}
+
+func (a *API) apply(run *Run, change Change) {
+
+    // only completed runs have a conclusion:
+    if change.Status != "" {
+        run.Status = change.Status
+        run.Conclusion = ""
+    }
+
+    if change.Conclusion != "" {
+        run.Conclusion = change.Conclusion
+    }
+
+    if change.RunAttempt != 0 {
+        run.RunAttempt = change.RunAttempt
+        run.RunStartedAt = a.now()
+    }
+
+    run.UpdatedAt = change.UpdatedAt
+
+    if run.UpdatedAt.IsZero() {
+        run.UpdatedAt = a.now()
+    }
+}
+
+func (a *API) now() time.Time {
+
+    if a.Now != nil {
+        return a.Now()
+    }
+
+    return time.Now()
+}
+
+func workflowRun(owner string, repo string, run *Run) *github.WorkflowRun {
+
+    workflowRun := &github.WorkflowRun{
+        ID:         github.Int64(run.ID),
+        RunNumber:  github.Int(run.RunNumber),
+        RunAttempt: github.Int(run.RunAttempt),
+        HeadBranch: github.String(run.Branch),
+        HeadSHA:    github.String(run.HeadSHA),
+        Event:      github.String(run.Event),
+        Status:     github.String(run.Status),
+        HTMLURL:    github.String(fmt.Sprintf("https://github.com/%s/%s/actions/runs/%d", owner, repo, run.ID)),
+        CreatedAt:  &github.Timestamp{Time: run.CreatedAt},
+        UpdatedAt:  &github.Timestamp{Time: run.UpdatedAt},
+    }
+
+    if run.Conclusion != "" {
+        workflowRun.Conclusion = github.String(run.Conclusion)
+    }
+
+    if !run.RunStartedAt.IsZero() {
+        workflowRun.RunStartedAt = &github.Timestamp{Time: run.RunStartedAt}
+    }
+
+    return workflowRun
+}
+
+func workflowJob(run *Run, job Job) *github.WorkflowJob {
+
+    workflowJob := &github.WorkflowJob{
+        RunID:  github.Int64(run.ID),
+        Name:   github.String(job.Name),
+        Status: github.String(job.Status),
+    }
+
+    if job.Conclusion != "" {
+        workflowJob.Conclusion = github.String(job.Conclusion)
+    }
+
+    if !job.StartedAt.IsZero() {
+        workflowJob.StartedAt = &github.Timestamp{Time: job.StartedAt}
+    }
+
+    if !job.CompletedAt.IsZero() {
+        workflowJob.CompletedAt = &github.Timestamp{Time: job.CompletedAt}
+    }
+
+    return workflowJob
+}
+
+func response(statusCode int, path string) *github.Response {
+
+    return &github.Response{Response: &http.Response{
+        StatusCode: statusCode,
+        Status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+        Header:     http.Header{},
+        Request:    &http.Request{Method: http.MethodGet, URL: &url.URL{Scheme: "https", Host: "api.github.com", Path: "/" + path}},
+    }}
+}
+
+func errorResponse(statusCode int, path string) error {
+
+    return &github.ErrorResponse{Response: response(statusCode, path).Response, Message: http.StatusText(statusCode)}
+}
+
+// rateLimitResponse is a 403 with the headers of an exhausted rate limit that resets in a minute.
+func rateLimitResponse(now time.Time, path string) *github.Response {
+
+    res := response(http.StatusForbidden, path)
+
+    res.Header.Set("X-RateLimit-Limit", "5000")
+    res.Header.Set("X-RateLimit-Remaining", "0")
+    res.Header.Set("X-RateLimit-Reset", fmt.Sprintf("%d", now.Add(time.Minute).Unix()))
+
+    res.Rate = github.Rate{Limit: 5000, Remaining: 0, Reset: github.Timestamp{Time: now.Add(time.Minute).Truncate(time.Second)}}
+
+    return res
+}
+
+func rateLimitError(now time.Time, path string) error {
+
+    res := rateLimitResponse(now, path)
+
+    return &github.RateLimitError{Rate: res.Rate, Response: res.Response, Message: "API rate limit exceeded"}
+}
+
+// page returns the runs on page (counting from 1) of perPage runs - all runs when perPage is 0.
+func page(runs []*Run, page int, perPage int) []*Run {
+
+    if perPage <= 0 {
+        return runs
+    }
+
+    if page < 1 {
+        page = 1
+    }
+
+    start := (page - 1) * perPage
+
+    if start >= len(runs) {
+        return []*Run{}
+    }
+
+    if start+perPage < len(runs) {
+        return runs[start : start+perPage]
+    }
+
+    return runs[start:]
+}
@@ -0,0 +1,140 @@
+package ghfake
+
+import (
+    "context"
+    "io/ioutil"
+    "reflect"
+    "testing"
+    "time"
+
+    gh "gh-actions-workflow-runs-sorter/gh"
+
+    log "github.com/sirupsen/logrus"
+)
+
+func TestScript(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    now := time.Date(2022, time.December, 13, 0, 0, 0, 0, time.UTC)
+
+    api := New()
+    api.Now = func() time.Time { return now }
+
+    api.AddRun(Run{ID: 3333333333, RunNumber: 30, Workflow: "release.yml", Branch: "main", Status: "in_progress"})
+    api.Script(3333333333,
+        Change{AfterPolls: 2, Status: "completed", Conclusion: "failure"},
+        Change{At: now.Add(time.Minute), Status: "in_progress", RunAttempt: 2},
+    )
+
+    gotStatuses := []string{}
+
+    for i := 0; i < 4; i++ {
+
+        status, _, err := gh.ReturnWorkflowRunStatus(context.Background(), api, "testowner", "testrepo", 3333333333)
+
+        if err != nil {
+            t.Fatalf("ReturnWorkflowRunStatus() failed - %s", err.Error())
+        }
+
+        gotStatuses = append(gotStatuses, status)
+    }
+
+    if want := []string{"in_progress", "in_progress", "completed", "completed"}; !reflect.DeepEqual(gotStatuses, want) {
+        t.Errorf("Script() failed - statuses expect %v but received %v", want, gotStatuses)
+    }
+
+    // the re-run is due a minute later - listing runs applies it too:
+    now = now.Add(time.Minute)
+
+    runs, err := gh.ReturnWorkflowRuns("main", context.Background(), api, "testowner", "testrepo", "release.yml", 20)
+
+    if err != nil || len(runs) != 1 {
+        t.Fatalf("ReturnWorkflowRuns() failed - expects 1 run but received %d (%v)", len(runs), err)
+    }
+
+    if runs[0].GetStatus() != "in_progress" || runs[0].GetConclusion() != "" || runs[0].GetRunAttempt() != 2 || !runs[0].GetUpdatedAt().Time.Equal(now) {
+        t.Errorf("Script() failed - re-run was not applied: %+v", runs[0])
+    }
+
+    if api.Polls(3333333333) != 4 {
+        t.Errorf("Polls() failed - expects 4 but received %d", api.Polls(3333333333))
+    }
+}
+
+func TestListWorkflowRuns(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    api := New()
+
+    api.AddRun(Run{ID: 1, RunNumber: 1, Workflow: "release.yml", Branch: "main", Status: "completed", Conclusion: "success"})
+    api.AddRun(Run{ID: 3, RunNumber: 3, Workflow: "release.yml", Branch: "main"})
+    api.AddRun(Run{ID: 2, RunNumber: 2, Workflow: "release.yml", Branch: "feature"})
+    api.AddRun(Run{ID: 4, RunNumber: 4, Workflow: "ci.yml", Branch: "main"})
+    api.AddRun(Run{ID: 5, RunNumber: 5, Workflow: "release.yml", Branch: "main"})
+
+    tests := []struct {
+        name         string
+        workflowFile string
+        runsToReturn int
+        faults       []int
+        wantIDs      []int64
+        wantErr      string
+    }{
+        {
+            name:         "should return runs of the workflow on the branch newest first",
+            workflowFile: "release.yml",
+            runsToReturn: 20,
+            wantIDs:      []int64{5, 3, 1},
+        },
+        {
+            name:         "should return at most the runs asked for",
+            workflowFile: "release.yml",
+            runsToReturn: 2,
+            wantIDs:      []int64{5, 3},
+        },
+        {
+            name:         "should fail for an unknown workflow",
+            workflowFile: "unknown.yml",
+            runsToReturn: 20,
+            wantErr:      "Workflow not found",
+        },
+        {
+            name:         "should fail with an injected fault",
+            workflowFile: "release.yml",
+            runsToReturn: 20,
+            faults:       []int{502},
+            wantErr:      "Response status received was not 200",
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            api.FailNext(tt.faults...)
+
+            runs, err := gh.ReturnWorkflowRuns("main", context.Background(), api, "testowner", "testrepo", tt.workflowFile, tt.runsToReturn)
+
+            if tt.wantErr != "" {
+
+                if err == nil || err.Error() != tt.wantErr {
+                    t.Errorf("ReturnWorkflowRuns() failed - error expects %s but received %v", tt.wantErr, err)
+                }
+
+                return
+            }
+
+            gotIDs := []int64{}
+
+            for _, run := range runs {
+                gotIDs = append(gotIDs, run.GetID())
+            }
+
+            if !reflect.DeepEqual(gotIDs, tt.wantIDs) {
+                t.Errorf("ReturnWorkflowRuns() failed - ids expect %v but received %v", tt.wantIDs, gotIDs)
+            }
+        })
+    }
+}
@@ -0,0 +1,65 @@
+package ghfake
+
+import (
+    "context"
+    "sync"
+    "time"
+)
+
+// Clock is a fake clock - Sleep returns right away after advancing the time and recording the duration.
+// Pass Now to API.Now so scripted changes follow the same time.
+type Clock struct {
+    mu     sync.Mutex
+    now    time.Time
+    sleeps []time.Duration
+}
+
+func NewClock(start time.Time) *Clock {
+
+    return &Clock{now: start}
+}
+
+func (c *Clock) Now() time.Time {
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    return c.now
+}
+
+// Sleep advances the clock by d - unless ctx is done, in which case it returns ctx's error like a real sleep would.
+func (c *Clock) Sleep(ctx context.Context, d time.Duration) error {
+
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.sleeps = append(c.sleeps, d)
+
+    if d > 0 {
+        c.now = c.now.Add(d)
+    }
+
+    return nil
+}
+
+// Advance moves the clock forward by d without recording a sleep.
+func (c *Clock) Advance(d time.Duration) {
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.now = c.now.Add(d)
+}
+
+// Sleeps returns every duration slept, in order.
+func (c *Clock) Sleeps() []time.Duration {
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    return append([]time.Duration{}, c.sleeps...)
+}
@@ -0,0 +1,37 @@
+package ghfake
+
+import (
+    "net/http"
+
+    gh "gh-actions-workflow-runs-sorter/gh"
+)
+
+// Handler serves the workflow-runs endpoints of the Github REST API from api for the repository owner/repo, so
+// the real client can be pointed at it via its base URL - a '/api/v3' prefix, as on Github Enterprise Server,
+// is accepted too. Faults injected into api fail requests with the status code, headers and message Github uses.
+func Handler(api *API, owner string, repo string) http.Handler {
+
+    return &handler{api: api, owner: owner, repo: repo}
+}
+
+type handler struct {
+    api   *API
+    owner string
+    repo  string
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+    gh.ServeREST(w, r, h.api, h.pinned)
+}
+
+// pinned answers requests for any repository but owner/repo as Github answers for one that does not exist.
+func (h *handler) pinned(w http.ResponseWriter, r *http.Request, owner string, repo string) bool {
+
+    if owner != h.owner || repo != h.repo {
+        gh.WriteError(w, http.StatusNotFound, nil, "Not Found")
+        return false
+    }
+
+    return true
+}
@@ -0,0 +1,144 @@
+package ghfake
+
+import (
+    "context"
+    "errors"
+    "io/ioutil"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "testing"
+    "time"
+
+    "github.com/google/go-github/v47/github"
+    log "github.com/sirupsen/logrus"
+)
+
+func TestHandler(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    api := New()
+
+    api.AddRun(Run{ID: 1111111111, RunNumber: 11, Workflow: "release.yml", Branch: "main", Status: "completed", Conclusion: "success"})
+    api.AddRun(Run{ID: 2222222222, RunNumber: 12, Workflow: "release.yml", Branch: "main", Status: "in_progress", Jobs: []Job{{Name: "deploy", Status: "in_progress"}}})
+    api.AddRun(Run{ID: 3333333333, RunNumber: 13, Workflow: "release.yml", Branch: "main", CreatedAt: time.Now().Add(time.Hour)})
+
+    server := httptest.NewServer(Handler(api, "testowner", "testrepo"))
+    defer server.Close()
+
+    client := github.NewClient(nil)
+    client.BaseURL, _ = url.Parse(server.URL + "/api/v3/")
+
+    ctx := context.Background()
+
+    t.Run("should list runs created so far newest first, a page at a time", func(t *testing.T) {
+
+        runs, _, err := client.Actions.ListWorkflowRunsByFileName(ctx, "testowner", "testrepo", "release.yml", &github.ListWorkflowRunsOptions{Branch: "main", ListOptions: github.ListOptions{Page: 2, PerPage: 1}})
+
+        if err != nil {
+            t.Fatalf("ListWorkflowRunsByFileName() failed - %s", err.Error())
+        }
+
+        if runs.GetTotalCount() != 2 || len(runs.WorkflowRuns) != 1 || runs.WorkflowRuns[0].GetID() != 1111111111 || runs.WorkflowRuns[0].GetConclusion() != "success" {
+            t.Errorf("ListWorkflowRunsByFileName() failed - expects run 1111111111 of 2 but received %+v", runs)
+        }
+    })
+
+    t.Run("should return a run and its jobs", func(t *testing.T) {
+
+        run, _, err := client.Actions.GetWorkflowRunByID(ctx, "testowner", "testrepo", 2222222222)
+
+        if err != nil || run.GetStatus() != "in_progress" || run.GetRunNumber() != 12 {
+            t.Errorf("GetWorkflowRunByID() failed - expects an in_progress run 12 but received %+v (%v)", run, err)
+        }
+
+        jobs, _, err := client.Actions.ListWorkflowJobs(ctx, "testowner", "testrepo", 2222222222, nil)
+
+        if err != nil || len(jobs.Jobs) != 1 || jobs.Jobs[0].GetName() != "deploy" {
+            t.Errorf("ListWorkflowJobs() failed - expects job 'deploy' but received %+v (%v)", jobs, err)
+        }
+    })
+
+    tests := []struct {
+        name       string
+        fault      *Fault
+        owner      string
+        runID      int64
+        wantStatus int
+        wantRate   bool
+    }{
+        {
+            name:       "should return 404 for a run created in the future",
+            owner:      "testowner",
+            runID:      3333333333,
+            wantStatus: http.StatusNotFound,
+        },
+        {
+            name:       "should return 404 for another repository",
+            owner:      "otherowner",
+            runID:      1111111111,
+            wantStatus: http.StatusNotFound,
+        },
+        {
+            name:       "should fail with an injected 5xx",
+            fault:      &Fault{Method: "GetWorkflowRunByID", StatusCode: http.StatusBadGateway, Times: 1},
+            owner:      "testowner",
+            runID:      1111111111,
+            wantStatus: http.StatusBadGateway,
+        },
+        {
+            name:       "should fail with an exhausted rate limit on an injected 403",
+            fault:      &Fault{StatusCode: http.StatusForbidden, Times: 1},
+            owner:      "testowner",
+            runID:      1111111111,
+            wantStatus: http.StatusForbidden,
+            wantRate:   true,
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            if tt.fault != nil {
+                api.AddFault(*tt.fault)
+            }
+
+            _, res, err := client.Actions.GetWorkflowRunByID(ctx, tt.owner, "testrepo", tt.runID)
+
+            if res == nil || res.StatusCode != tt.wantStatus {
+                t.Fatalf("GetWorkflowRunByID() failed - expects status %d but received %v (%v)", tt.wantStatus, res, err)
+            }
+
+            var rateErr *github.RateLimitError
+
+            if errors.As(err, &rateErr) != tt.wantRate {
+                t.Errorf("GetWorkflowRunByID() failed - expects a rate limit error %t but received %v", tt.wantRate, err)
+            }
+
+            if tt.wantRate && rateErr.Rate.Remaining != 0 {
+                t.Errorf("GetWorkflowRunByID() failed - expects no rate limit remaining but received %d", rateErr.Rate.Remaining)
+            }
+
+        })
+    }
+}
+
+func TestFaultLatency(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    api := New()
+    api.AddRun(Run{ID: 1111111111, RunNumber: 11, Workflow: "release.yml", Branch: "main"})
+    api.AddFault(Fault{Latency: time.Hour})
+
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+
+    _, _, err := api.GetWorkflowRunByID(ctx, "testowner", "testrepo", 1111111111)
+
+    if !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("GetWorkflowRunByID() failed - expects the call to wait out the latency until %v but received %v", context.DeadlineExceeded, err)
+    }
+}
@@ -0,0 +1,274 @@
+package ghfake
+
+import (
+    "fmt"
+    "os"
+    "time"
+
+    "gopkg.in/yaml.v3"
+)
+
+// Scenario is a YAML description of a repository's workflow runs, how they change over time and the faults its
+// API suffers - times are offsets from the start of the scenario, e.g.:
+//
+//  owner: octo
+//  repo: hello
+//  runs:
+//    - id: 41
+//      number: 41
+//      workflow: release.yml
+//      status: in_progress
+//      created: -2m
+//      changes:
+//        - at: 30s
+//          status: completed
+//          conclusion: failure
+//    - id: 43
+//      number: 43
+//      workflow: release.yml
+//      created: 10s
+//  faults:
+//    - endpoint: get_run
+//      from: 5s
+//      until: 15s
+//      status: 503
+//      latency: 2s
+type Scenario struct {
+    Owner     string          `yaml:"owner"`
+    Repo      string          `yaml:"repo"`
+    Workflows []string        `yaml:"workflows"`
+    Runs      []ScenarioRun   `yaml:"runs"`
+    Faults    []ScenarioFault `yaml:"faults"`
+}
+
+// ScenarioRun is a Run of a Scenario - branch defaults to 'main' and event to 'push'.
+type ScenarioRun struct {
+    ID         int64            `yaml:"id"`
+    Number     int              `yaml:"number"`
+    Attempt    int              `yaml:"attempt"`
+    Workflow   string           `yaml:"workflow"`
+    Branch     string           `yaml:"branch"`
+    Event      string           `yaml:"event"`
+    SHA        string           `yaml:"sha"`
+    Status     string           `yaml:"status"`
+    Conclusion string           `yaml:"conclusion"`
+    Created    Offset           `yaml:"created"`
+    Updated    *Offset          `yaml:"updated"`
+    Jobs       []ScenarioJob    `yaml:"jobs"`
+    Changes    []ScenarioChange `yaml:"changes"`
+}
+
+// ScenarioJob is a Job of a ScenarioRun.
+type ScenarioJob struct {
+    Name       string  `yaml:"name"`
+    Status     string  `yaml:"status"`
+    Conclusion string  `yaml:"conclusion"`
+    Started    *Offset `yaml:"started"`
+    Completed  *Offset `yaml:"completed"`
+}
+
+// ScenarioChange is a Change of a ScenarioRun.
+type ScenarioChange struct {
+    At         *Offset `yaml:"at"`
+    AfterPolls int     `yaml:"after_polls"`
+    Status     string  `yaml:"status"`
+    Conclusion string  `yaml:"conclusion"`
+    Attempt    int     `yaml:"attempt"`
+}
+
+// ScenarioFault is a Fault of a Scenario - endpoint is 'list_runs', 'get_run' or 'list_jobs' (all when empty)
+// and status 403 fails like an exhausted rate limit.
+type ScenarioFault struct {
+    Endpoint string  `yaml:"endpoint"`
+    RunID    int64   `yaml:"run_id"`
+    From     *Offset `yaml:"from"`
+    Until    *Offset `yaml:"until"`
+    Times    int     `yaml:"times"`
+    Status   int     `yaml:"status"`
+    Latency  Offset  `yaml:"latency"`
+}
+
+// Offset is a duration such as '30s' or '-2m' - from the start of a scenario where it is a point in time.
+type Offset time.Duration
+
+func (o *Offset) UnmarshalYAML(value *yaml.Node) error {
+
+    d, err := time.ParseDuration(value.Value)
+
+    if err != nil {
+        return fmt.Errorf("Invalid duration '%s' on line %d - expected e.g. '30s' or '-2m'", value.Value, value.Line)
+    }
+
+    *o = Offset(d)
+
+    return nil
+}
+
+var scenarioEndpoints = map[string]string{
+    "list_runs": "ListWorkflowRunsByFileName",
+    "get_run":   "GetWorkflowRunByID",
+    "list_jobs": "ListWorkflowJobs",
+}
+
+// LoadScenario reads and validates a scenario file.
+func LoadScenario(path string) (*Scenario, error) {
+
+    data, err := os.ReadFile(path)
+
+    if err != nil {
+        return nil, fmt.Errorf("Failed to read scenario %s: %s", path, err.Error())
+    }
+
+    scenario, err := ParseScenario(data)
+
+    if err != nil {
+        return nil, fmt.Errorf("Invalid scenario %s: %s", path, err.Error())
+    }
+
+    return scenario, nil
+}
+
+// ParseScenario parses and validates a scenario.
+func ParseScenario(data []byte) (*Scenario, error) {
+
+    var scenario Scenario
+
+    if err := yaml.Unmarshal(data, &scenario); err != nil {
+        return nil, err
+    }
+
+    if scenario.Owner == "" || scenario.Repo == "" {
+        return nil, fmt.Errorf("owner and repo are required")
+    }
+
+    ids := map[int64]bool{}
+
+    for _, run := range scenario.Runs {
+
+        if run.ID == 0 || run.Workflow == "" {
+            return nil, fmt.Errorf("every run needs an id and a workflow")
+        }
+
+        if ids[run.ID] {
+            return nil, fmt.Errorf("run id %d is used more than once", run.ID)
+        }
+
+        ids[run.ID] = true
+    }
+
+    for _, fault := range scenario.Faults {
+
+        if _, ok := scenarioEndpoints[fault.Endpoint]; fault.Endpoint != "" && !ok {
+            return nil, fmt.Errorf("unknown fault endpoint '%s' - must be 'list_runs', 'get_run' or 'list_jobs'", fault.Endpoint)
+        }
+
+        if fault.Status == 0 && fault.Latency == 0 {
+            return nil, fmt.Errorf("every fault needs a status or a latency")
+        }
+    }
+
+    return &scenario, nil
+}
+
+// API returns a fake holding the scenario's runs and faults, with the scenario starting at start.
+func (s *Scenario) API(start time.Time) *API {
+
+    at := func(offset Offset) time.Time {
+        return start.Add(time.Duration(offset))
+    }
+
+    api := New()
+
+    for _, workflow := range s.Workflows {
+        api.AddWorkflow(workflow)
+    }
+
+    for _, scenarioRun := range s.Runs {
+
+        run := Run{
+            ID:         scenarioRun.ID,
+            RunNumber:  scenarioRun.Number,
+            RunAttempt: scenarioRun.Attempt,
+            Workflow:   scenarioRun.Workflow,
+            Branch:     scenarioRun.Branch,
+            Event:      scenarioRun.Event,
+            HeadSHA:    scenarioRun.SHA,
+            Status:     scenarioRun.Status,
+            Conclusion: scenarioRun.Conclusion,
+            CreatedAt:  at(scenarioRun.Created),
+        }
+
+        if run.Branch == "" {
+            run.Branch = "main"
+        }
+
+        if run.Event == "" {
+            run.Event = "push"
+        }
+
+        if scenarioRun.Updated != nil {
+            run.UpdatedAt = at(*scenarioRun.Updated)
+        }
+
+        for _, scenarioJob := range scenarioRun.Jobs {
+
+            job := Job{Name: scenarioJob.Name, Status: scenarioJob.Status, Conclusion: scenarioJob.Conclusion}
+
+            if scenarioJob.Started != nil {
+                job.StartedAt = at(*scenarioJob.Started)
+            }
+
+            if scenarioJob.Completed != nil {
+                job.CompletedAt = at(*scenarioJob.Completed)
+            }
+
+            run.Jobs = append(run.Jobs, job)
+        }
+
+        api.AddRun(run)
+
+        for _, scenarioChange := range scenarioRun.Changes {
+
+            change := Change{
+                AfterPolls: scenarioChange.AfterPolls,
+                Status:     scenarioChange.Status,
+                Conclusion: scenarioChange.Conclusion,
+                RunAttempt: scenarioChange.Attempt,
+            }
+
+            if scenarioChange.At != nil {
+                change.At = at(*scenarioChange.At)
+            }
+
+            // a change due at a point in time updated the run then - not when it was first seen:
+            if scenarioChange.At != nil && scenarioChange.AfterPolls == 0 {
+                change.UpdatedAt = change.At
+            }
+
+            api.Script(run.ID, change)
+        }
+    }
+
+    for _, scenarioFault := range s.Faults {
+
+        fault := Fault{
+            Method:     scenarioEndpoints[scenarioFault.Endpoint],
+            RunID:      scenarioFault.RunID,
+            Times:      scenarioFault.Times,
+            StatusCode: scenarioFault.Status,
+            Latency:    time.Duration(scenarioFault.Latency),
+        }
+
+        if scenarioFault.From != nil {
+            fault.From = at(*scenarioFault.From)
+        }
+
+        if scenarioFault.Until != nil {
+            fault.Until = at(*scenarioFault.Until)
+        }
+
+        api.AddFault(fault)
+    }
+
+    return api
+}
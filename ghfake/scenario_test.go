@@ -0,0 +1,130 @@
+package ghfake
+
+import (
+    "context"
+    "io/ioutil"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/google/go-github/v47/github"
+    log "github.com/sirupsen/logrus"
+)
+
+func TestScenario(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    scenario, err := ParseScenario([]byte(`
+owner: octo
+repo: hello
+runs:
+  - id: 41
+    number: 41
+    workflow: release.yml
+    status: in_progress
+    created: -2m
+    changes:
+      - at: 30s
+        status: completed
+        conclusion: failure
+  - id: 43
+    number: 43
+    workflow: release.yml
+    created: 10s
+faults:
+  - endpoint: get_run
+    from: 40s
+    times: 1
+    status: 503
+`))
+
+    if err != nil {
+        t.Fatalf("ParseScenario() failed - %s", err.Error())
+    }
+
+    start := time.Date(2022, time.December, 13, 0, 0, 0, 0, time.UTC)
+    now := start
+
+    api := scenario.API(start)
+    api.Now = func() time.Time { return now }
+
+    tests := []struct {
+        at             time.Duration
+        wantRuns       int
+        wantStatus     string
+        wantConclusion string
+        wantErrStatus  int
+    }{
+        {at: 0, wantRuns: 1, wantStatus: "in_progress"},
+        {at: 10 * time.Second, wantRuns: 2, wantStatus: "in_progress"},
+        {at: 30 * time.Second, wantRuns: 2, wantStatus: "completed", wantConclusion: "failure"},
+        {at: 40 * time.Second, wantRuns: 2, wantErrStatus: http.StatusServiceUnavailable},
+        {at: 41 * time.Second, wantRuns: 2, wantStatus: "completed", wantConclusion: "failure"},
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.at.String(), func(t *testing.T) {
+
+            now = start.Add(tt.at)
+
+            runs, _, err := api.ListWorkflowRunsByFileName(context.Background(), "octo", "hello", "release.yml", &github.ListWorkflowRunsOptions{Branch: "main"})
+
+            if err != nil || len(runs.WorkflowRuns) != tt.wantRuns {
+                t.Fatalf("ListWorkflowRunsByFileName() failed - expects %d runs but received %+v (%v)", tt.wantRuns, runs, err)
+            }
+
+            run, res, err := api.GetWorkflowRunByID(context.Background(), "octo", "hello", 41)
+
+            if tt.wantErrStatus != 0 {
+
+                if err == nil || res.StatusCode != tt.wantErrStatus {
+                    t.Errorf("GetWorkflowRunByID() failed - expects status %d but received %v", tt.wantErrStatus, err)
+                }
+
+                return
+            }
+
+            if err != nil || run.GetStatus() != tt.wantStatus || run.GetConclusion() != tt.wantConclusion {
+                t.Errorf("GetWorkflowRunByID() failed - expects %s/%s but received %s/%s (%v)", tt.wantStatus, tt.wantConclusion, run.GetStatus(), run.GetConclusion(), err)
+            }
+
+            // the change is dated when it was due:
+            if tt.wantStatus == "completed" && !run.GetUpdatedAt().Time.Equal(start.Add(30*time.Second)) {
+                t.Errorf("GetWorkflowRunByID() failed - expects updated_at %s but received %s", start.Add(30*time.Second), run.GetUpdatedAt().Time)
+            }
+
+        })
+    }
+}
+
+func TestParseScenario(t *testing.T){
+
+    tests := []struct {
+        name    string
+        data    string
+        wantErr string
+    }{
+        {name: "should require owner and repo", data: "runs: []", wantErr: "owner and repo are required"},
+        {name: "should require run ids", data: "owner: o\nrepo: r\nruns:\n  - workflow: release.yml", wantErr: "every run needs an id and a workflow"},
+        {name: "should reject duplicate run ids", data: "owner: o\nrepo: r\nruns:\n  - {id: 1, workflow: a.yml}\n  - {id: 1, workflow: a.yml}", wantErr: "run id 1 is used more than once"},
+        {name: "should reject invalid offsets", data: "owner: o\nrepo: r\nruns:\n  - {id: 1, workflow: a.yml, created: soon}", wantErr: "Invalid duration 'soon'"},
+        {name: "should reject unknown endpoints", data: "owner: o\nrepo: r\nfaults:\n  - {endpoint: get_runs, status: 500}", wantErr: "unknown fault endpoint 'get_runs'"},
+        {name: "should accept a valid scenario", data: "owner: o\nrepo: r\nfaults:\n  - {latency: 1s}"},
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            _, err := ParseScenario([]byte(tt.data))
+
+            if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+                t.Errorf("ParseScenario() failed - expects error '%s' but received %v", tt.wantErr, err)
+            }
+
+        })
+    }
+}
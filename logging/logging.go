@@ -0,0 +1,132 @@
+package logging
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strings"
+
+    log "github.com/sirupsen/logrus"
+)
+
+// log formats supported by Setup:
+const (
+    FormatText   = "text"
+    FormatJSON   = "json"
+    FormatGithub = "github"
+)
+
+// output is where workflow commands are written - the same stream logrus writes to, so stdout stays
+// reserved for the variables exported by 'shouldExecute' mode.
+var output io.Writer = os.Stderr
+
+var githubMode = false
+
+// Setup configures logrus with format and level. In github format warnings and errors become
+// workflow-command annotations and token is masked in all later output of the job.
+func Setup(format string, level string, token string) error {
+
+    parsedLevel, err := log.ParseLevel(level)
+
+    if err != nil {
+        return fmt.Errorf("log level passed is %s - allowed values are panic, fatal, error, warn, info, debug or trace", level)
+    }
+
+    log.SetLevel(parsedLevel)
+
+    switch format {
+
+    case FormatText:
+        log.SetFormatter(&log.TextFormatter{})
+
+    case FormatJSON:
+        log.SetFormatter(&log.JSONFormatter{})
+
+    case FormatGithub:
+        log.SetFormatter(&GithubFormatter{})
+        githubMode = true
+
+        if token != "" {
+            fmt.Fprintf(output, "::add-mask::%s\n", token)
+        }
+
+    default:
+        return fmt.Errorf("log format passed is %s - allowed values are %s, %s or %s", format, FormatText, FormatJSON, FormatGithub)
+    }
+
+    return nil
+}
+
+// StartGroup folds all following output into a collapsible group in the Actions UI - a no-op outside github format.
+func StartGroup(title string) {
+
+    if githubMode {
+        fmt.Fprintf(output, "::group::%s\n", escapeData(title))
+    }
+}
+
+// EndGroup closes the group opened by StartGroup.
+func EndGroup() {
+
+    if githubMode {
+        fmt.Fprintln(output, "::endgroup::")
+    }
+}
+
+// GithubFormatter writes warnings and errors as '::warning::'/'::error::' workflow commands so they show up
+// as annotations; other levels are written as plain lines.
+type GithubFormatter struct{}
+
+func (f *GithubFormatter) Format(entry *log.Entry) ([]byte, error) {
+
+    var b bytes.Buffer
+
+    message := entry.Message
+
+    keys := make([]string, 0, len(entry.Data))
+
+    for key := range entry.Data {
+        keys = append(keys, key)
+    }
+
+    sort.Strings(keys)
+
+    fields := []string{}
+
+    for _, key := range keys {
+        fields = append(fields, fmt.Sprintf("%s=%v", key, entry.Data[key]))
+    }
+
+    if len(fields) > 0 {
+        message = fmt.Sprintf("%s (%s)", strings.TrimSpace(message), strings.Join(fields, " "))
+    }
+
+    switch entry.Level {
+
+    case log.PanicLevel, log.FatalLevel, log.ErrorLevel:
+        fmt.Fprintf(&b, "::error::%s\n", escapeData(message))
+
+    case log.WarnLevel:
+        fmt.Fprintf(&b, "::warning::%s\n", escapeData(message))
+
+    case log.DebugLevel, log.TraceLevel:
+        fmt.Fprintf(&b, "::debug::%s\n", escapeData(message))
+
+    default:
+        fmt.Fprintln(&b, message)
+    }
+
+    return b.Bytes(), nil
+}
+
+// escapeData escapes characters workflow commands treat specially.
+func escapeData(value string) string {
+
+    value = strings.ReplaceAll(value, "%", "%25")
+    value = strings.ReplaceAll(value, "\r", "%0D")
+    value = strings.ReplaceAll(value, "\n", "%0A")
+
+    return value
+}
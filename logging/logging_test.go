@@ -0,0 +1,89 @@
+package logging
+
+import (
+    "bytes"
+    "testing"
+
+    log "github.com/sirupsen/logrus"
+)
+
+func TestGithubFormatter(t *testing.T){
+
+    tests := []struct {
+        name    string
+        level   log.Level
+        message string
+        fields  log.Fields
+        want    string
+    }{
+        {
+            name:    "warning becomes an annotation",
+            level:   log.WarnLevel,
+            message: "Workflow not found ...",
+            fields:  log.Fields{"repo": "testrepo", "owner": "testowner"},
+            want:    "::warning::Workflow not found ... (owner=testowner repo=testrepo)\n",
+        },
+        {
+            name:    "error becomes an annotation with escaped new lines",
+            level:   log.ErrorLevel,
+            message: "first line\nsecond line at 100%",
+            fields:  log.Fields{},
+            want:    "::error::first line%0Asecond line at 100%25\n",
+        },
+        {
+            name:    "info stays a plain line",
+            level:   log.InfoLevel,
+            message: "Good to complete this workflow ...",
+            fields:  log.Fields{"currentRunNumber": 31},
+            want:    "Good to complete this workflow ... (currentRunNumber=31)\n",
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            entry := log.NewEntry(log.StandardLogger()).WithFields(tt.fields)
+            entry.Level = tt.level
+            entry.Message = tt.message
+
+            got, err := (&GithubFormatter{}).Format(entry)
+
+            if err != nil {
+                t.Fatalf("Format() returned error: '%v'", err)
+            }
+
+            if string(got) != tt.want {
+                t.Errorf("Format() failed - expects %q but received %q", tt.want, string(got))
+            }
+
+        })
+    }
+
+}
+
+func TestSetupMasksToken(t *testing.T){
+
+    var out bytes.Buffer
+
+    output = &out
+    defer func() { githubMode = false; log.SetFormatter(&log.TextFormatter{}); log.SetLevel(log.InfoLevel) }()
+
+    if err := Setup(FormatGithub, "debug", "ghp_secret"); err != nil {
+        t.Fatalf("Setup() returned error: '%v'", err)
+    }
+
+    StartGroup("waiting on previous run")
+    EndGroup()
+
+    want := "::add-mask::ghp_secret\n::group::waiting on previous run\n::endgroup::\n"
+
+    if out.String() != want {
+        t.Errorf("Setup() failed - expects %q but received %q", want, out.String())
+    }
+
+    if err := Setup("xml", "info", ""); err == nil {
+        t.Errorf("Setup() expects an error for an unknown format")
+    }
+
+}
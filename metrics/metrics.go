@@ -0,0 +1,133 @@
+package metrics
+
+import (
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+    "github.com/prometheus/client_golang/prometheus/push"
+
+    log "github.com/sirupsen/logrus"
+)
+
+const namespace = "workflow_runs_sorter"
+
+// wait phases of 'shouldComplete' mode:
+const (
+    PhasePredecessor    = "predecessor"
+    PhasePostCompletion = "post_completion"
+)
+
+// Registry holds every metric of this tool - kept apart from the default registry so
+// exports only contain what the tool itself instruments.
+var Registry = prometheus.NewRegistry()
+
+var (
+    apiCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "github_api_calls_total",
+        Help:      "Github API calls by endpoint and response status.",
+    }, []string{"endpoint", "status"})
+
+    rateLimitRemaining = prometheus.NewGauge(prometheus.GaugeOpts{
+        Namespace: namespace,
+        Name:      "github_rate_limit_remaining",
+        Help:      "Requests remaining in the current Github API rate limit window as of the last response.",
+    })
+
+    waitSeconds = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "wait_seconds_total",
+        Help:      "Time spent waiting by phase.",
+    }, []string{"phase"})
+
+    decisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "decisions_total",
+        Help:      "Decisions made by mode and outcome.",
+    }, []string{"mode", "outcome"})
+)
+
+func init() {
+
+    Registry.MustRegister(apiCalls, rateLimitRemaining, waitSeconds, decisions)
+}
+
+// ObserveAPICall records a Github API call - status is the HTTP status code, or 0 when no response was received.
+// remaining is the X-RateLimit-Remaining header value, or -1 when it was not present.
+func ObserveAPICall(endpoint string, status int, remaining int) {
+
+    statusLabel := "error"
+
+    if status > 0 {
+        statusLabel = strconv.Itoa(status)
+    }
+
+    apiCalls.WithLabelValues(endpoint, statusLabel).Inc()
+
+    if remaining >= 0 {
+        rateLimitRemaining.Set(float64(remaining))
+    }
+}
+
+// ObserveWait records time spent waiting in phase.
+func ObserveWait(phase string, waited time.Duration) {
+
+    waitSeconds.WithLabelValues(phase).Add(waited.Seconds())
+}
+
+// ObserveDecision records the outcome of a decision made by mode.
+func ObserveDecision(mode string, outcome string) {
+
+    decisions.WithLabelValues(mode, outcome).Inc()
+}
+
+// WriteTextfile writes all metrics to path in the format read by node_exporter's textfile collector.
+// The file is written atomically so the collector never reads a partial file.
+func WriteTextfile(path string) error {
+
+    return prometheus.WriteToTextfile(path, Registry)
+}
+
+// Push sends all metrics to a Pushgateway-compatible endpoint, grouped by job and the given labels
+// (e.g. repo and workflow so many repositories can push to one gateway).
+func Push(url string, job string, grouping map[string]string) error {
+
+    pusher := push.New(url, job).Gatherer(Registry)
+
+    for name, value := range grouping {
+        pusher = pusher.Grouping(name, value)
+    }
+
+    return pusher.Push()
+}
+
+// Handler serves all metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+
+    return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Serve exposes all metrics on /metrics at addr for long-running modes - the returned server is already listening.
+func Serve(addr string) *http.Server {
+
+    mux := http.NewServeMux()
+    mux.Handle("/metrics", Handler())
+
+    server := &http.Server{Addr: addr, Handler: mux}
+
+    go func() {
+
+        if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+
+            log.WithFields(log.Fields{
+                "addr": addr,
+            }).Warn(fmt.Sprintf("metrics server stopped: %s", err.Error()))
+        }
+    }()
+
+    return server
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestWriteTextfile(t *testing.T){
+
+    ObserveAPICall("/repos/{owner}/{repo}/actions/runs/{id}", 200, 4999)
+    ObserveAPICall("/repos/{owner}/{repo}/actions/runs/{id}", 0, -1)
+    ObserveWait(PhasePostCompletion, 45*time.Second)
+    ObserveDecision("shouldExecute", "wait")
+
+    path := filepath.Join(t.TempDir(), "sorter.prom")
+
+    if err := WriteTextfile(path); err != nil {
+        t.Fatalf("WriteTextfile() returned error: '%v'", err)
+    }
+
+    data, err := os.ReadFile(path)
+
+    if err != nil {
+        t.Fatalf("failed to read textfile: '%v'", err)
+    }
+
+    wantLines := []string{
+        `workflow_runs_sorter_github_api_calls_total{endpoint="/repos/{owner}/{repo}/actions/runs/{id}",status="200"} 1`,
+        `workflow_runs_sorter_github_api_calls_total{endpoint="/repos/{owner}/{repo}/actions/runs/{id}",status="error"} 1`,
+        `workflow_runs_sorter_github_rate_limit_remaining 4999`,
+        `workflow_runs_sorter_wait_seconds_total{phase="post_completion"} 45`,
+        `workflow_runs_sorter_decisions_total{mode="shouldExecute",outcome="wait"} 1`,
+    }
+
+    for _, line := range wantLines {
+
+        if !strings.Contains(string(data), line) {
+            t.Errorf("WriteTextfile() failed - expects line '%s' in:\n%s", line, string(data))
+        }
+    }
+
+}
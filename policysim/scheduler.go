@@ -0,0 +1,130 @@
+package policysim
+
+import (
+    "context"
+    "sort"
+    "sync"
+    "time"
+)
+
+// scheduler is a virtual clock shared by the simulated runs of one trial. Exactly one run executes at a time -
+// the one that is due first, in the order runs went to sleep on a tie - and time jumps straight to the next
+// wake-up once it sleeps, so a trial takes no real time and plays out the same way on every execution.
+type scheduler struct {
+    mu       sync.Mutex
+    idle     *sync.Cond
+    now      time.Time
+    running  bool
+    seq      int
+    sleepers []sleeper
+}
+
+type sleeper struct {
+    at   time.Time
+    seq  int
+    wake chan struct{}
+}
+
+func newScheduler(start time.Time) *scheduler {
+
+    s := &scheduler{now: start}
+    s.idle = sync.NewCond(&s.mu)
+
+    return s
+}
+
+func (s *scheduler) Now() time.Time {
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return s.now
+}
+
+// Sleep gives way to other runs until the virtual time is d from now.
+func (s *scheduler) Sleep(ctx context.Context, d time.Duration) error {
+
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+
+    wake := s.enqueue(s.now.Add(d))
+
+    s.running = false
+    s.idle.Broadcast()
+
+    s.mu.Unlock()
+
+    <-wake
+
+    return nil
+}
+
+// Go adds a run that starts at the current virtual time, once the runs due before it gave way.
+func (s *scheduler) Go(fn func()) {
+
+    s.mu.Lock()
+    wake := s.enqueue(s.now)
+    s.mu.Unlock()
+
+    go func() {
+
+        <-wake
+
+        fn()
+
+        s.mu.Lock()
+        s.running = false
+        s.idle.Broadcast()
+        s.mu.Unlock()
+    }()
+}
+
+// Run wakes runs in order until none is left.
+func (s *scheduler) Run() {
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for {
+
+        for s.running {
+            s.idle.Wait()
+        }
+
+        if len(s.sleepers) == 0 {
+            return
+        }
+
+        sort.Slice(s.sleepers, func(i, j int) bool {
+
+            if s.sleepers[i].at.Equal(s.sleepers[j].at) {
+                return s.sleepers[i].seq < s.sleepers[j].seq
+            }
+
+            return s.sleepers[i].at.Before(s.sleepers[j].at)
+        })
+
+        next := s.sleepers[0]
+        s.sleepers = s.sleepers[1:]
+
+        if next.at.After(s.now) {
+            s.now = next.at
+        }
+
+        s.running = true
+        close(next.wake)
+    }
+}
+
+func (s *scheduler) enqueue(at time.Time) chan struct{} {
+
+    wake := make(chan struct{})
+
+    s.sleepers = append(s.sleepers, sleeper{at: at, seq: s.seq, wake: wake})
+    s.seq++
+
+    return wake
+}
@@ -0,0 +1,404 @@
+// Package policysim compares ordering policies on random workloads before they are rolled out. Every trial
+// draws run arrivals, durations and failures, and drives the real decision and wait logic of the sorter
+// package for each policy against a fake API and a virtual clock - the same workloads for every policy, so
+// differences between results come from the policies alone:
+//
+//  results, err := policysim.Run(policysim.Options{
+//      Workload: policysim.DefaultWorkload,
+//      Policies: []policysim.Policy{{Name: "always", CompletionPolicy: "always"}, {Name: "success", CompletionPolicy: "success"}},
+//      Trials:   500,
+//  })
+//  policysim.WriteResults(os.Stdout, results)
+package policysim
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math"
+    "math/rand"
+    "runtime"
+    "sync"
+    "time"
+
+    ghfake "gh-actions-workflow-runs-sorter/ghfake"
+    sorter "gh-actions-workflow-runs-sorter/sorter"
+)
+
+const (
+    simulatedOwner    = "simulated"
+    simulatedRepo     = "simulated"
+    simulatedWorkflow = "simulated.yml"
+    simulatedBranch   = "main"
+)
+
+// Workload describes the random runs of a trial - arrivals are exponentially distributed around MeanInterval,
+// durations normally distributed around MeanDuration (and at least 10s) and each run fails with FailureRate.
+type Workload struct {
+    Runs           int
+    MeanInterval   time.Duration
+    MeanDuration   time.Duration
+    DurationStdDev time.Duration
+    FailureRate    float64
+}
+
+// DefaultWorkload is a busy release workflow - a run every 5 minutes taking 10 minutes, one in ten failing.
+var DefaultWorkload = Workload{
+    Runs:           20,
+    MeanInterval:   5 * time.Minute,
+    MeanDuration:   10 * time.Minute,
+    DurationStdDev: 3 * time.Minute,
+    FailureRate:    0.1,
+}
+
+// Policy is a set of ordering settings to evaluate - zero values take the defaults of the sorter package.
+type Policy struct {
+    Name               string
+    CompletionPolicy   string
+    WaitBetweenChecks  time.Duration
+    WaitBeforeComplete time.Duration
+    RunsToReturn       int
+}
+
+// Options configure a simulation - Seed makes it reproducible and StarvationAfter is the wait after which a run
+// counts as starved (an hour when 0).
+type Options struct {
+    Workload        Workload
+    Policies        []Policy
+    Trials          int
+    Seed            int64
+    StarvationAfter time.Duration
+
+    // Parallelism is the number of trials simulated at once - the number of CPUs when 0.
+    Parallelism int
+}
+
+// Result sums up the trials of a policy.
+type Result struct {
+    Policy Policy
+    Trials int
+    Runs   int
+
+    // Violations are executed runs that completed after a later executed run - out of order.
+    Violations int
+
+    // Starved are runs that waited on their predecessor longer than StarvationAfter.
+    Starved int
+
+    Skipped int
+    Failed  int
+
+    // Errors are runs whose decision or wait failed.
+    Errors int
+
+    WaitTotal time.Duration
+    MaxWait   time.Duration
+    APICalls  int
+}
+
+// MeanWaitMinutes is the wait of all runs of a trial, in minutes, on average over the trials.
+func (r Result) MeanWaitMinutes() float64 {
+
+    if r.Trials == 0 {
+        return 0
+    }
+
+    return r.WaitTotal.Minutes() / float64(r.Trials)
+}
+
+// MeanAPICalls is the number of API calls of a trial on average.
+func (r Result) MeanAPICalls() float64 {
+
+    if r.Trials == 0 {
+        return 0
+    }
+
+    return float64(r.APICalls) / float64(r.Trials)
+}
+
+// plannedRun is a run of a trial as drawn from the workload.
+type plannedRun struct {
+    number   int
+    arrival  time.Duration
+    duration time.Duration
+    fails    bool
+}
+
+// Run simulates opts.Trials workloads under every policy and returns a result per policy, in order.
+func Run(opts Options) ([]Result, error) {
+
+    if opts.Trials < 1 || opts.Workload.Runs < 1 {
+        return nil, fmt.Errorf("Invalid simulation: trials and runs must be at least 1")
+    }
+
+    if len(opts.Policies) == 0 {
+        return nil, fmt.Errorf("Invalid simulation: at least one policy is required")
+    }
+
+    if opts.Workload.FailureRate < 0 || opts.Workload.FailureRate > 1 {
+        return nil, fmt.Errorf("Invalid simulation: failure rate must be between 0 and 1")
+    }
+
+    if opts.StarvationAfter == 0 {
+        opts.StarvationAfter = time.Hour
+    }
+
+    if opts.Parallelism < 1 {
+        opts.Parallelism = runtime.NumCPU()
+    }
+
+    // a policy the sorter rejects fails the simulation up front rather than every run:
+    for _, policy := range opts.Policies {
+
+        if _, err := newGate(policy, ghfake.New(), newScheduler(time.Time{}), 1); err != nil {
+            return nil, fmt.Errorf("Invalid policy '%s': %s", policy.Name, err.Error())
+        }
+    }
+
+    results := make([]Result, len(opts.Policies))
+
+    for i, policy := range opts.Policies {
+        results[i] = Result{Policy: policy, Trials: opts.Trials}
+    }
+
+    var mu sync.Mutex
+    var wg sync.WaitGroup
+
+    trials := make(chan int)
+
+    for worker := 0; worker < opts.Parallelism; worker++ {
+
+        wg.Add(1)
+
+        go func() {
+
+            defer wg.Done()
+
+            for trial := range trials {
+
+                plan := planTrial(opts.Workload, rand.New(rand.NewSource(opts.Seed+int64(trial))))
+
+                for i, policy := range opts.Policies {
+
+                    result := simulateTrial(plan, policy, opts.StarvationAfter)
+
+                    mu.Lock()
+                    results[i].add(result)
+                    mu.Unlock()
+                }
+            }
+        }()
+    }
+
+    for trial := 0; trial < opts.Trials; trial++ {
+        trials <- trial
+    }
+
+    close(trials)
+    wg.Wait()
+
+    return results, nil
+}
+
+func (r *Result) add(trial Result) {
+
+    r.Runs += trial.Runs
+    r.Violations += trial.Violations
+    r.Starved += trial.Starved
+    r.Skipped += trial.Skipped
+    r.Failed += trial.Failed
+    r.Errors += trial.Errors
+    r.WaitTotal += trial.WaitTotal
+    r.APICalls += trial.APICalls
+
+    if trial.MaxWait > r.MaxWait {
+        r.MaxWait = trial.MaxWait
+    }
+}
+
+// planTrial draws the runs of a trial.
+func planTrial(workload Workload, rng *rand.Rand) []plannedRun {
+
+    plan := []plannedRun{}
+    arrival := time.Duration(0)
+
+    for number := 1; number <= workload.Runs; number++ {
+
+        arrival += time.Duration(rng.ExpFloat64() * float64(workload.MeanInterval))
+
+        duration := time.Duration(rng.NormFloat64()*float64(workload.DurationStdDev)) + workload.MeanDuration
+        duration = time.Duration(math.Max(float64(duration), float64(10*time.Second)))
+
+        plan = append(plan, plannedRun{
+            number:   number,
+            arrival:  arrival,
+            duration: duration,
+            fails:    rng.Float64() < workload.FailureRate,
+        })
+    }
+
+    return plan
+}
+
+// simulateTrial plays out a trial under policy - each run decides when it arrives, works for its duration, waits
+// for its predecessor when told to and completes.
+func simulateTrial(plan []plannedRun, policy Policy, starvationAfter time.Duration) Result {
+
+    start := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+    clock := newScheduler(start)
+
+    api := ghfake.New()
+    api.Now = clock.Now
+
+    // the baseline - a run that completed before the trial:
+    api.AddRun(ghfake.Run{ID: 1000, RunNumber: 0, Workflow: simulatedWorkflow, Branch: simulatedBranch, Status: "completed", Conclusion: "success", CreatedAt: start.Add(-time.Hour), UpdatedAt: start.Add(-time.Minute)})
+
+    for _, run := range plan {
+        api.AddRun(ghfake.Run{ID: runID(run.number), RunNumber: run.number, Workflow: simulatedWorkflow, Branch: simulatedBranch, Status: "in_progress", CreatedAt: start.Add(run.arrival)})
+    }
+
+    result := Result{Policy: policy, Trials: 1, Runs: len(plan)}
+
+    var mu sync.Mutex
+
+    // executed runs in the order they completed:
+    completed := []int{}
+
+    for _, run := range plan {
+
+        run := run
+
+        clock.Go(func() {
+
+            ctx := context.Background()
+
+            clock.Sleep(ctx, run.arrival)
+
+            executed, failed, waited, err := playRun(ctx, api, clock, policy, run)
+
+            conclusion := "success"
+
+            if failed {
+                conclusion = "failure"
+            }
+
+            api.Update(runID(run.number), "completed", conclusion)
+
+            mu.Lock()
+            defer mu.Unlock()
+
+            result.WaitTotal += waited
+
+            if waited > result.MaxWait {
+                result.MaxWait = waited
+            }
+
+            switch {
+
+            case err != nil:
+                result.Errors++
+
+            case !executed:
+                result.Skipped++
+
+            case failed:
+                result.Failed++
+            }
+
+            if waited > starvationAfter {
+                result.Starved++
+            }
+
+            if executed {
+                completed = append(completed, run.number)
+            }
+        })
+    }
+
+    clock.Run()
+
+    result.Violations = violations(completed)
+    result.APICalls = len(api.Calls())
+
+    return result
+}
+
+// playRun is the life of one run - it returns whether it executed and failed, and how long it waited.
+func playRun(ctx context.Context, api *ghfake.API, clock *scheduler, policy Policy, run plannedRun) (bool, bool, time.Duration, error) {
+
+    gate, err := newGate(policy, api, clock, run.number)
+
+    if err != nil {
+        return false, false, 0, err
+    }
+
+    decision, err := gate.Decide(ctx)
+
+    if err != nil {
+        return false, false, 0, err
+    }
+
+    if !decision.Execute() {
+        return false, false, 0, nil
+    }
+
+    clock.Sleep(ctx, run.duration)
+
+    if !decision.WaitForPastRun() {
+        return true, run.fails, 0, nil
+    }
+
+    began := clock.Now()
+
+    _, err = gate.WaitForTurn(ctx, decision.PastRunID)
+
+    waited := clock.Now().Sub(began)
+
+    if errors.Is(err, sorter.ErrPredecessorFailed) {
+        return true, true, waited, nil
+    }
+
+    return true, run.fails, waited, err
+}
+
+func newGate(policy Policy, api *ghfake.API, clock *scheduler, runNumber int) (*sorter.Gate, error) {
+
+    return sorter.New(sorter.Options{
+        API:                api,
+        Owner:              simulatedOwner,
+        Repo:               simulatedRepo,
+        Workflow:           simulatedWorkflow,
+        Branch:             simulatedBranch,
+        RunNumber:          runNumber,
+        RunsToReturn:       policy.RunsToReturn,
+        WaitBetweenChecks:  policy.WaitBetweenChecks,
+        WaitBeforeComplete: policy.WaitBeforeComplete,
+        CompletionPolicy:   policy.CompletionPolicy,
+        StopOnAPIError:     true,
+        Clock:              clock,
+    })
+}
+
+// violations counts executed runs that completed after a later executed run.
+func violations(completed []int) int {
+
+    count := 0
+    highest := 0
+
+    for _, number := range completed {
+
+        if number < highest {
+            count++
+            continue
+        }
+
+        highest = number
+    }
+
+    return count
+}
+
+func runID(runNumber int) int64 {
+
+    return int64(1000 + runNumber)
+}
@@ -0,0 +1,126 @@
+package policysim
+
+import (
+    "context"
+    "io/ioutil"
+    "reflect"
+    "sync"
+    "testing"
+    "time"
+
+    log "github.com/sirupsen/logrus"
+)
+
+func TestScheduler(t *testing.T){
+
+    start := time.Date(2022, time.December, 13, 0, 0, 0, 0, time.UTC)
+    clock := newScheduler(start)
+
+    var mu sync.Mutex
+    got := []string{}
+
+    record := func(name string) {
+        mu.Lock()
+        got = append(got, name+"@"+clock.Now().Sub(start).String())
+        mu.Unlock()
+    }
+
+    clock.Go(func() {
+        record("a")
+        clock.Sleep(context.Background(), 30*time.Second)
+        record("a")
+        clock.Sleep(context.Background(), 30*time.Second)
+        record("a")
+    })
+
+    clock.Go(func() {
+        record("b")
+        clock.Sleep(context.Background(), 45*time.Second)
+        record("b")
+        clock.Sleep(context.Background(), 15*time.Second)
+        record("b")
+    })
+
+    clock.Run()
+
+    // b wakes at 60s after a since it went to sleep later:
+    want := []string{"a@0s", "b@0s", "a@30s", "b@45s", "a@1m0s", "b@1m0s"}
+
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("scheduler failed - expects %v but received %v", want, got)
+    }
+}
+
+func TestRun(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    policies := []Policy{
+        {Name: "always", CompletionPolicy: "always", WaitBeforeComplete: time.Minute},
+        {Name: "success", CompletionPolicy: "success", WaitBeforeComplete: time.Minute},
+        {Name: "fail", CompletionPolicy: "fail", WaitBeforeComplete: time.Minute},
+        {Name: "slow polling", CompletionPolicy: "always", WaitBeforeComplete: time.Minute, WaitBetweenChecks: time.Minute},
+    }
+
+    opts := Options{Workload: DefaultWorkload, Policies: policies, Trials: 20, Seed: 7}
+
+    results, err := Run(opts)
+
+    if err != nil {
+        t.Fatalf("Run() failed - %s", err.Error())
+    }
+
+    // the same seed gives the same results - whatever the order trials were simulated in:
+    opts.Parallelism = 1
+
+    again, err := Run(opts)
+
+    if err != nil || !reflect.DeepEqual(results, again) {
+        t.Errorf("Run() failed - expects the same results for the same seed but received %+v and %+v (%v)", results, again, err)
+    }
+
+    always, success, fail, slowPolling := results[0], results[1], results[2], results[3]
+
+    for _, result := range results {
+
+        if result.Runs != 20*DefaultWorkload.Runs || result.Violations != 0 || result.Errors != 0 {
+            t.Errorf("Run() failed - expects %d runs completing in order without errors under '%s' but received %+v", 20*DefaultWorkload.Runs, result.Policy.Name, result)
+        }
+    }
+
+    if success.WaitTotal > always.WaitTotal {
+        t.Errorf("Run() failed - 'success' skips waits after failures so expects no more wait than 'always' (%s) but received %s", always.WaitTotal, success.WaitTotal)
+    }
+
+    if fail.Failed <= always.Failed {
+        t.Errorf("Run() failed - 'fail' fails runs after failed ones so expects more failures than 'always' (%d) but received %d", always.Failed, fail.Failed)
+    }
+
+    if slowPolling.APICalls >= always.APICalls {
+        t.Errorf("Run() failed - polling less often expects fewer API calls than %d but received %d", always.APICalls, slowPolling.APICalls)
+    }
+
+    if _, err := Run(Options{Workload: DefaultWorkload, Policies: []Policy{{Name: "invalid", CompletionPolicy: "sometimes"}}, Trials: 1}); err == nil {
+        t.Errorf("Run() failed - expects an error for an invalid policy")
+    }
+}
+
+func TestViolations(t *testing.T){
+
+    tests := []struct {
+        completed []int
+        want      int
+    }{
+        {completed: []int{1, 2, 3, 4}, want: 0},
+        {completed: []int{1, 3, 2, 4}, want: 1},
+        {completed: []int{4, 1, 2, 3}, want: 3},
+        {completed: []int{}, want: 0},
+    }
+
+    for _, tt := range tests {
+
+        if got := violations(tt.completed); got != tt.want {
+            t.Errorf("violations(%v) failed - expects %d but received %d", tt.completed, tt.want, got)
+        }
+    }
+}
@@ -0,0 +1,35 @@
+package policysim
+
+import (
+    "fmt"
+    "io"
+    "text/tabwriter"
+    "time"
+)
+
+// WriteResults prints results side by side as a table, one policy per row.
+func WriteResults(w io.Writer, results []Result) {
+
+    tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+    fmt.Fprintln(tw, "POLICY\tTRIALS\tRUNS\tVIOLATIONS\tSTARVED\tSKIPPED\tFAILED\tERRORS\tWAIT MIN/TRIAL\tMAX WAIT\tAPI CALLS/TRIAL")
+
+    for _, result := range results {
+
+        fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%.1f\t%s\t%.1f\n",
+            result.Policy.Name,
+            result.Trials,
+            result.Runs,
+            result.Violations,
+            result.Starved,
+            result.Skipped,
+            result.Failed,
+            result.Errors,
+            result.MeanWaitMinutes(),
+            result.MaxWait.Truncate(time.Second),
+            result.MeanAPICalls(),
+        )
+    }
+
+    tw.Flush()
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+    "crypto/subtle"
+    "fmt"
+    "net/http"
+    "path"
+    "strings"
+
+    gh "gh-actions-workflow-runs-sorter/gh"
+)
+
+// authorize reports whether r may have the server call Github for owner/repo with its token - writing 403 when it
+// may not. Requests must carry Options.Secret as a bearer token, when one is set, and name a repository of
+// Options.AllowRepos.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, owner string, repo string) bool {
+
+    if s.opts.Secret != "" {
+
+        token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+        if subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Secret)) != 1 {
+            gh.WriteError(w, http.StatusForbidden, nil, "Forbidden: the server secret is missing or wrong")
+            return false
+        }
+    }
+
+    if !s.allowed(owner, repo) {
+        gh.WriteError(w, http.StatusForbidden, nil, fmt.Sprintf("Forbidden: repository %s/%s is not served", owner, repo))
+        return false
+    }
+
+    return true
+}
+
+// allowed reports whether owner/repo matches one of Options.AllowRepos - like Github, case is ignored.
+func (s *Server) allowed(owner string, repo string) bool {
+
+    if owner == "" || repo == "" {
+        return false
+    }
+
+    name := strings.ToLower(owner + "/" + repo)
+
+    for _, pattern := range s.opts.AllowRepos {
+
+        if matched, err := path.Match(strings.ToLower(pattern), name); err == nil && matched {
+            return true
+        }
+    }
+
+    return false
+}
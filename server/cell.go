@@ -0,0 +1,101 @@
+package server
+
+import (
+    "context"
+    "sync"
+    "time"
+
+    "github.com/google/go-github/v47/github"
+)
+
+// fetchTimeout limits a call to Github made on behalf of every request waiting for it - it is not tied to the
+// request that happened to make it, so that request going away does not fail the others.
+const fetchTimeout = 30 * time.Second
+
+// cell holds one Github response shared by every request for it. Requests arriving while it is being fetched
+// wait for that call instead of making their own.
+type cell struct {
+    mu        sync.Mutex
+    value     interface{}
+    res       *github.Response
+    err       error
+    fetchedAt time.Time
+    inflight  chan struct{}
+}
+
+// load returns the value when fresh reports it still is - fetching it otherwise, once for all concurrent callers.
+func (c *cell) load(ctx context.Context, now func() time.Time, fresh func(value interface{}, age time.Duration) bool, fetch func(context.Context) (interface{}, *github.Response, error)) (interface{}, *github.Response, error) {
+
+    c.mu.Lock()
+
+    if c.value != nil && fresh(c.value, now().Sub(c.fetchedAt)) {
+        defer c.mu.Unlock()
+        return c.value, c.res, nil
+    }
+
+    if wait := c.inflight; wait != nil {
+
+        c.mu.Unlock()
+
+        select {
+
+        case <-wait:
+
+        case <-ctx.Done():
+            return nil, nil, ctx.Err()
+        }
+
+        c.mu.Lock()
+        defer c.mu.Unlock()
+
+        if c.err != nil {
+            return nil, c.res, c.err
+        }
+
+        return c.value, c.res, nil
+    }
+
+    wait := make(chan struct{})
+    c.inflight = wait
+
+    c.mu.Unlock()
+
+    fetchCtx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+    defer cancel()
+
+    value, res, err := fetch(fetchCtx)
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.res, c.err = res, err
+
+    if err == nil {
+        c.value, c.fetchedAt = value, now()
+    }
+
+    c.inflight = nil
+    close(wait)
+
+    return value, res, err
+}
+
+// set stores a value received from elsewhere (e.g. a webhook) as if it was just fetched.
+func (c *cell) set(value interface{}, now time.Time) {
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.value, c.fetchedAt, c.err = value, now, nil
+}
+
+// update replaces the value held with change(value) in one step - nothing is changed when there is no value.
+func (c *cell) update(change func(value interface{}) interface{}) {
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if c.value != nil {
+        c.value = change(c.value)
+    }
+}
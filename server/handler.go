@@ -0,0 +1,160 @@
+package server
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+
+    gh "gh-actions-workflow-runs-sorter/gh"
+    sorter "gh-actions-workflow-runs-sorter/sorter"
+)
+
+// DecisionResponse is the body of GET /decision.
+type DecisionResponse struct {
+    Outcome              string            `json:"outcome"`
+    ShouldExecute        bool              `json:"should_execute"`
+    ShouldWaitForPastRun bool              `json:"should_wait_for_past_run"`
+    PastRunID            int64             `json:"past_run_id,omitempty"`
+    Rule                 string            `json:"rule"`
+    Runs                 []DecisionRun     `json:"runs"`
+}
+
+// DecisionRun is how a run was treated in a decision.
+type DecisionRun struct {
+    RunNumber  int    `json:"run_number"`
+    ID         int64  `json:"id"`
+    Status     string `json:"status"`
+    Conclusion string `json:"conclusion,omitempty"`
+    HeadSHA    string `json:"head_sha,omitempty"`
+    Branch     string `json:"branch"`
+    HTMLURL    string `json:"html_url"`
+    Role       string `json:"role"`
+}
+
+// Handler serves the server's endpoints:
+//
+//  GET  /repos/{owner}/{repo}/actions/...  the workflow-runs endpoints of the Github REST API the cli calls -
+//                                          a '/api/v3' prefix, as on Github Enterprise Server, is accepted too
+//  GET  /decision                          the decision for ?owner, repo, workflow, branch and run_number -
+//                                          workflow_runs_to_return and events (comma-separated) are optional
+//  POST /webhook                           workflow_run events of Github webhooks - with Options.WebhookSecret only
+//  GET  /healthz                           the Stats of the server
+//
+// Runs and decisions are served only for Options.AllowRepos, to requests carrying Options.Secret - the server
+// calls Github with its own token.
+func (s *Server) Handler() http.Handler {
+
+    mux := http.NewServeMux()
+
+    mux.HandleFunc("/decision", s.serveDecision)
+    mux.HandleFunc("/healthz", s.serveHealth)
+    mux.HandleFunc("/repos/", s.serveAPI)
+    mux.HandleFunc("/api/v3/repos/", s.serveAPI)
+
+    if s.opts.WebhookSecret != "" {
+        mux.HandleFunc("/webhook", s.serveWebhook)
+    }
+
+    return mux
+}
+
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request) {
+
+    gh.ServeREST(w, r, s, s.authorize)
+}
+
+func (s *Server) serveDecision(w http.ResponseWriter, r *http.Request) {
+
+    if r.Method != http.MethodGet {
+        gh.WriteError(w, http.StatusMethodNotAllowed, nil, "Method not allowed")
+        return
+    }
+
+    query := r.URL.Query()
+
+    req := DecisionRequest{
+        Owner:    query.Get("owner"),
+        Repo:     query.Get("repo"),
+        Workflow: query.Get("workflow"),
+        Branch:   query.Get("branch"),
+    }
+
+    problems := []string{}
+
+    if req.Owner == "" || req.Repo == "" || req.Workflow == "" {
+        problems = append(problems, "owner, repo and workflow are required")
+    }
+
+    var err error
+
+    if req.RunNumber, err = strconv.Atoi(query.Get("run_number")); err != nil || req.RunNumber < 1 {
+        problems = append(problems, "run_number must be a positive number")
+    }
+
+    if value := query.Get("workflow_runs_to_return"); value != "" {
+
+        if req.RunsToReturn, err = strconv.Atoi(value); err != nil {
+            problems = append(problems, "workflow_runs_to_return must be a number")
+        }
+    }
+
+    for _, event := range strings.Split(query.Get("events"), ",") {
+
+        if strings.TrimSpace(event) != "" {
+            req.Events = append(req.Events, strings.TrimSpace(event))
+        }
+    }
+
+    if len(problems) > 0 {
+        gh.WriteError(w, http.StatusUnprocessableEntity, nil, fmt.Sprintf("Invalid request: %s", strings.Join(problems, "; ")))
+        return
+    }
+
+    if !s.authorize(w, r, req.Owner, req.Repo) {
+        return
+    }
+
+    decision, err := s.Decide(r.Context(), req)
+
+    if err != nil && !errors.Is(err, sorter.ErrNoRuns) {
+        gh.WriteAPIError(w, r, err)
+        return
+    }
+
+    // no runs to order against is a decision to skip, as in the cli:
+    gh.WriteJSON(w, decisionResponse(decision))
+}
+
+func decisionResponse(decision sorter.Decision) DecisionResponse {
+
+    response := DecisionResponse{
+        Outcome:              decision.Outcome,
+        ShouldExecute:        decision.Execute(),
+        ShouldWaitForPastRun: decision.WaitForPastRun(),
+        PastRunID:            decision.PastRunID,
+        Rule:                 decision.Trace.Rule,
+        Runs:                 []DecisionRun{},
+    }
+
+    for _, run := range decision.Trace.Runs {
+        response.Runs = append(response.Runs, DecisionRun{
+            RunNumber:  run.RunNumber,
+            ID:         run.ID,
+            Status:     run.Status,
+            Conclusion: run.Conclusion,
+            HeadSHA:    run.HeadSHA,
+            Branch:     run.Branch,
+            HTMLURL:    run.HTMLURL,
+            Role:       run.Role,
+        })
+    }
+
+    return response
+}
+
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request) {
+
+    gh.WriteJSON(w, s.Stats())
+}
@@ -0,0 +1,420 @@
+// Package server is the ordering service behind 'gh-actions-workflow-runs-sorter serve'. It keeps one shared view
+// of the runs of every workflow it is asked about - refreshed from Github at most once per RefreshInterval however
+// many runners ask, and updated by workflow_run webhooks in between - and answers with the decisions the cli
+// computes. The views are served on the Github REST API paths the cli uses, so the cli talks to the service by
+// pointing its client at it:
+//
+//  srv := server.New(server.Options{API: client.Actions, Workflows: client.Actions, AllowRepos: []string{"octo/*"}})
+//  http.ListenAndServe(":8080", srv.Handler())
+package server
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    gh "gh-actions-workflow-runs-sorter/gh"
+    sorter "gh-actions-workflow-runs-sorter/sorter"
+
+    "github.com/google/go-github/v47/github"
+)
+
+// viewRuns is the number of recent runs kept per workflow and branch - the most the API returns in one page.
+const viewRuns = 100
+
+// defaults for options left unset:
+const (
+    DefaultRefreshInterval = 10 * time.Second
+    DefaultCompletedTTL    = time.Minute
+)
+
+// Options configure a Server - API is required.
+type Options struct {
+    // API is where runs are fetched from - client.Actions.
+    API gh.WorkflowRunsAPI
+
+    // Workflows lists the workflows of a repository, for clients resolving workflows by name - optional.
+    Workflows gh.WorkflowsAPI
+
+    // RefreshInterval is how long a view of runs, and the state of a run that has not completed, is served before
+    // it is fetched again - DefaultRefreshInterval when 0.
+    RefreshInterval time.Duration
+
+    // CompletedTTL is how long the state of a completed run is served - it changes only when the run is re-run,
+    // which a webhook reports right away. DefaultCompletedTTL when 0.
+    CompletedTTL time.Duration
+
+    // WebhookSecret verifies the signature of webhook deliveries - /webhook is not served when empty, as unsigned
+    // deliveries could change the state of any run.
+    WebhookSecret string
+
+    // AllowRepos are the repositories served over HTTP - 'owner/repo', or a pattern such as 'owner/*'. Requests
+    // for other repositories are forbidden, and so is every request when it is empty.
+    AllowRepos []string
+
+    // Secret is the bearer token HTTP requests for runs and decisions must carry - not checked when empty.
+    Secret string
+
+    // Now is the server's clock - time.Now when nil.
+    Now func() time.Time
+}
+
+// Server serves shared views of workflow runs - safe for concurrent use.
+type Server struct {
+    opts Options
+
+    mu        sync.Mutex
+    views     map[string]*cell
+    runs      map[string]*cell
+    jobs      map[string]*cell
+    workflows map[string]*cell
+    stats     Stats
+}
+
+// Stats count the requests the server answered and the calls it made to Github for them.
+type Stats struct {
+    Requests    int `json:"requests"`
+    GithubCalls int `json:"github_calls"`
+    Webhooks    int `json:"webhooks"`
+    Views       int `json:"views"`
+}
+
+// DecisionRequest asks whether run RunNumber of Workflow on Branch may proceed.
+type DecisionRequest struct {
+    Owner        string
+    Repo         string
+    Workflow     string
+    Branch       string
+    RunNumber    int
+    RunsToReturn int
+    Events       []string
+}
+
+func New(opts Options) *Server {
+
+    if opts.RefreshInterval == 0 {
+        opts.RefreshInterval = DefaultRefreshInterval
+    }
+
+    if opts.CompletedTTL == 0 {
+        opts.CompletedTTL = DefaultCompletedTTL
+    }
+
+    if opts.Now == nil {
+        opts.Now = time.Now
+    }
+
+    return &Server{
+        opts:      opts,
+        views:     map[string]*cell{},
+        runs:      map[string]*cell{},
+        jobs:      map[string]*cell{},
+        workflows: map[string]*cell{},
+    }
+}
+
+// Stats returns the counts so far.
+func (s *Server) Stats() Stats {
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    stats := s.stats
+    stats.Views = len(s.views)
+
+    return stats
+}
+
+// Decide computes the decision for req over the shared view - the decision 'should-execute' makes.
+func (s *Server) Decide(ctx context.Context, req DecisionRequest) (sorter.Decision, error) {
+
+    gate, err := sorter.New(sorter.Options{
+        API:          s,
+        Owner:        req.Owner,
+        Repo:         req.Repo,
+        Workflow:     req.Workflow,
+        Branch:       req.Branch,
+        RunNumber:    req.RunNumber,
+        RunsToReturn: req.RunsToReturn,
+        Events:       req.Events,
+    })
+
+    if err != nil {
+        return sorter.Decision{}, err
+    }
+
+    return gate.Decide(ctx)
+}
+
+// ListWorkflowRunsByFileName serves the runs of a workflow on a branch from its shared view. Listings the view
+// cannot answer - later pages or other filters - go to Github.
+func (s *Server) ListWorkflowRunsByFileName(ctx context.Context, owner string, repo string, workflowFileName string, opts *github.ListWorkflowRunsOptions) (*github.WorkflowRuns, *github.Response, error) {
+
+    if opts == nil {
+        opts = &github.ListWorkflowRunsOptions{}
+    }
+
+    if opts.Page > 1 || opts.Actor != "" || opts.Event != "" || opts.Status != "" || opts.Created != "" {
+        s.count(true)
+        return s.opts.API.ListWorkflowRunsByFileName(ctx, owner, repo, workflowFileName, opts)
+    }
+
+    s.count(false)
+
+    view := s.cell(s.views, viewKey(owner, repo, workflowFileName, opts.Branch))
+
+    value, res, err := view.load(ctx, s.opts.Now, func(value interface{}, age time.Duration) bool {
+        return age < s.opts.RefreshInterval
+    }, func(ctx context.Context) (interface{}, *github.Response, error) {
+
+        s.count(true)
+
+        runs, res, err := s.opts.API.ListWorkflowRunsByFileName(ctx, owner, repo, workflowFileName, &github.ListWorkflowRunsOptions{
+            Branch:      opts.Branch,
+            ListOptions: github.ListOptions{PerPage: viewRuns},
+        })
+
+        if err != nil {
+            return nil, res, err
+        }
+
+        // the states of these runs are as fresh as the view:
+        for _, run := range runs.WorkflowRuns {
+            s.cell(s.runs, runKey(owner, repo, run.GetID())).set(run, s.opts.Now())
+        }
+
+        return runs, res, nil
+    })
+
+    if err != nil {
+        return nil, res, err
+    }
+
+    all := value.(*github.WorkflowRuns)
+
+    perPage := opts.PerPage
+
+    if perPage == 0 {
+        perPage = 30
+    }
+
+    runs := &github.WorkflowRuns{TotalCount: all.TotalCount}
+
+    if perPage < len(all.WorkflowRuns) {
+        runs.WorkflowRuns = all.WorkflowRuns[:perPage]
+    } else {
+        runs.WorkflowRuns = all.WorkflowRuns
+    }
+
+    return runs, response(http.StatusOK), nil
+}
+
+// GetWorkflowRunByID serves the state of a run - fetched again after RefreshInterval, or CompletedTTL once it
+// has completed.
+func (s *Server) GetWorkflowRunByID(ctx context.Context, owner string, repo string, runID int64) (*github.WorkflowRun, *github.Response, error) {
+
+    s.count(false)
+
+    value, res, err := s.cell(s.runs, runKey(owner, repo, runID)).load(ctx, s.opts.Now, func(value interface{}, age time.Duration) bool {
+
+        if value.(*github.WorkflowRun).GetStatus() == "completed" {
+            return age < s.opts.CompletedTTL
+        }
+
+        return age < s.opts.RefreshInterval
+    }, func(ctx context.Context) (interface{}, *github.Response, error) {
+
+        s.count(true)
+
+        return s.opts.API.GetWorkflowRunByID(ctx, owner, repo, runID)
+    })
+
+    if err != nil {
+        return nil, res, err
+    }
+
+    return value.(*github.WorkflowRun), response(http.StatusOK), nil
+}
+
+// ListWorkflowJobs serves the jobs of a run, fetched again after RefreshInterval.
+func (s *Server) ListWorkflowJobs(ctx context.Context, owner string, repo string, runID int64, opts *github.ListWorkflowJobsOptions) (*github.Jobs, *github.Response, error) {
+
+    if opts != nil && opts.Page > 1 {
+        s.count(true)
+        return s.opts.API.ListWorkflowJobs(ctx, owner, repo, runID, opts)
+    }
+
+    s.count(false)
+
+    value, res, err := s.cell(s.jobs, runKey(owner, repo, runID)).load(ctx, s.opts.Now, func(value interface{}, age time.Duration) bool {
+        return age < s.opts.RefreshInterval
+    }, func(ctx context.Context) (interface{}, *github.Response, error) {
+
+        s.count(true)
+
+        return s.opts.API.ListWorkflowJobs(ctx, owner, repo, runID, &github.ListWorkflowJobsOptions{ListOptions: github.ListOptions{PerPage: viewRuns}})
+    })
+
+    if err != nil {
+        return nil, res, err
+    }
+
+    return value.(*github.Jobs), response(http.StatusOK), nil
+}
+
+// ListWorkflows serves the workflows of a repository, fetched again after CompletedTTL.
+func (s *Server) ListWorkflows(ctx context.Context, owner string, repo string, opts *github.ListOptions) (*github.Workflows, *github.Response, error) {
+
+    if s.opts.Workflows == nil {
+        return nil, response(http.StatusNotFound), fmt.Errorf("Listing workflows is not supported by this server")
+    }
+
+    if opts != nil && opts.Page > 1 {
+        s.count(true)
+        return s.opts.Workflows.ListWorkflows(ctx, owner, repo, opts)
+    }
+
+    s.count(false)
+
+    value, res, err := s.cell(s.workflows, repoKey(owner, repo)).load(ctx, s.opts.Now, func(value interface{}, age time.Duration) bool {
+        return age < s.opts.CompletedTTL
+    }, func(ctx context.Context) (interface{}, *github.Response, error) {
+
+        s.count(true)
+
+        workflows, res, err := gh.ListWorkflows(ctx, s.opts.Workflows, owner, repo)
+
+        if err != nil {
+            return nil, res, err
+        }
+
+        return &github.Workflows{TotalCount: github.Int(len(workflows)), Workflows: workflows}, res, nil
+    })
+
+    if err != nil {
+        return nil, res, err
+    }
+
+    return value.(*github.Workflows), response(http.StatusOK), nil
+}
+
+// update applies the state of a run reported by a webhook - to the run and to the views it appears in.
+func (s *Server) update(owner string, repo string, workflows []string, run *github.WorkflowRun) {
+
+    now := s.opts.Now()
+
+    s.cell(s.runs, runKey(owner, repo, run.GetID())).set(run, now)
+
+    for _, workflow := range workflows {
+
+        for _, branch := range []string{run.GetHeadBranch(), ""} {
+
+            s.mu.Lock()
+            view, ok := s.views[viewKey(owner, repo, workflow, branch)]
+            s.mu.Unlock()
+
+            // a view nobody asked for yet is fetched when somebody does:
+            if !ok {
+                continue
+            }
+
+            view.update(func(value interface{}) interface{} {
+
+                if current, ok := value.(*github.WorkflowRuns); ok {
+                    return upsert(current, run)
+                }
+
+                return value
+            })
+        }
+    }
+}
+
+// upsert returns runs with run added or replaced, newest first like the API.
+func upsert(runs *github.WorkflowRuns, run *github.WorkflowRun) *github.WorkflowRuns {
+
+    updated := &github.WorkflowRuns{TotalCount: runs.TotalCount}
+    found := false
+
+    for _, existing := range runs.WorkflowRuns {
+
+        if existing.GetID() == run.GetID() {
+            existing, found = run, true
+        }
+
+        updated.WorkflowRuns = append(updated.WorkflowRuns, existing)
+    }
+
+    if !found {
+
+        updated.WorkflowRuns = append(updated.WorkflowRuns, run)
+        updated.TotalCount = github.Int(runs.GetTotalCount() + 1)
+
+        sort.SliceStable(updated.WorkflowRuns, func(i, j int) bool {
+            return updated.WorkflowRuns[i].GetRunNumber() > updated.WorkflowRuns[j].GetRunNumber()
+        })
+
+        if len(updated.WorkflowRuns) > viewRuns {
+            updated.WorkflowRuns = updated.WorkflowRuns[:viewRuns]
+        }
+    }
+
+    return updated
+}
+
+func (s *Server) cell(cells map[string]*cell, key string) *cell {
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    c, ok := cells[key]
+
+    if !ok {
+        c = &cell{}
+        cells[key] = c
+    }
+
+    return c
+}
+
+// count records a request answered - or a call made to Github.
+func (s *Server) count(githubCall bool) {
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if githubCall {
+        s.stats.GithubCalls++
+    } else {
+        s.stats.Requests++
+    }
+}
+
+// repoKey names a repository in keys - like Github, and allowed, it ignores case, so a webhook reporting the
+// repository's own case updates the views clients asked for in any other.
+func repoKey(owner string, repo string) string {
+
+    return strings.ToLower(owner + "/" + repo)
+}
+
+func viewKey(owner string, repo string, workflow string, branch string) string {
+
+    return strings.Join([]string{repoKey(owner, repo), workflow, branch}, "/")
+}
+
+func runKey(owner string, repo string, runID int64) string {
+
+    return fmt.Sprintf("%s/%d", repoKey(owner, repo), runID)
+}
+
+func response(status int) *github.Response {
+
+    return &github.Response{Response: &http.Response{StatusCode: status, Header: http.Header{}}}
+}
+
+var _ gh.WorkflowRunsAPI = (*Server)(nil)
+var _ gh.WorkflowsAPI = (*Server)(nil)
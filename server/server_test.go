@@ -0,0 +1,478 @@
+package server
+
+import (
+    "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "reflect"
+    "sync"
+    "testing"
+    "time"
+
+    ghfake "gh-actions-workflow-runs-sorter/ghfake"
+    sorter "gh-actions-workflow-runs-sorter/sorter"
+
+    "github.com/google/go-github/v47/github"
+    log "github.com/sirupsen/logrus"
+)
+
+func newFake() *ghfake.API {
+
+    api := ghfake.New()
+
+    api.AddRun(ghfake.Run{ID: 1111111111, RunNumber: 11, Workflow: "release.yml", Branch: "main", Event: "push", Status: "completed", Conclusion: "success"})
+    api.AddRun(ghfake.Run{ID: 2222222222, RunNumber: 12, Workflow: "release.yml", Branch: "main", Event: "push", Status: "in_progress"})
+    api.AddRun(ghfake.Run{ID: 3333333333, RunNumber: 13, Workflow: "release.yml", Branch: "main", Event: "push", Status: "queued"})
+
+    return api
+}
+
+func countCalls(api *ghfake.API, method string) int {
+
+    count := 0
+
+    for _, call := range api.Calls() {
+
+        if call.Method == method {
+            count++
+        }
+    }
+
+    return count
+}
+
+func TestDecideShared(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    api := newFake()
+
+    // slow enough for every query to arrive while the first one is fetching:
+    api.AddFault(ghfake.Fault{Method: "ListWorkflowRunsByFileName", Latency: 50 * time.Millisecond})
+
+    now := time.Date(2022, time.December, 13, 0, 0, 0, 0, time.UTC)
+    srv := New(Options{API: api, Now: func() time.Time { return now }})
+
+    gate, _ := sorter.New(sorter.Options{API: newFake(), Owner: "testowner", Repo: "testrepo", Workflow: "release.yml", Branch: "main", RunNumber: 13})
+    want, _ := gate.Decide(context.Background())
+
+    var wg sync.WaitGroup
+    decisions := make([]sorter.Decision, 20)
+    errs := make([]error, 20)
+
+    for i := range decisions {
+
+        wg.Add(1)
+
+        go func(i int) {
+            defer wg.Done()
+            decisions[i], errs[i] = srv.Decide(context.Background(), DecisionRequest{Owner: "testowner", Repo: "testrepo", Workflow: "release.yml", Branch: "main", RunNumber: 13})
+        }(i)
+    }
+
+    wg.Wait()
+
+    for i, decision := range decisions {
+
+        if errs[i] != nil {
+            t.Fatalf("Decide() failed - %s", errs[i].Error())
+        }
+
+        if decision.Outcome != want.Outcome || decision.PastRunID != want.PastRunID {
+            t.Errorf("Decide() failed - expects %s on %d but received %s on %d", want.Outcome, want.PastRunID, decision.Outcome, decision.PastRunID)
+        }
+    }
+
+    if got := countCalls(api, "ListWorkflowRunsByFileName"); got != 1 {
+        t.Errorf("Decide() failed - 20 concurrent queries expect 1 call to Github but received %d", got)
+    }
+
+    // the view is served until it is due a refresh:
+    srv.Decide(context.Background(), DecisionRequest{Owner: "testowner", Repo: "testrepo", Workflow: "release.yml", Branch: "main", RunNumber: 13})
+
+    now = now.Add(DefaultRefreshInterval)
+
+    srv.Decide(context.Background(), DecisionRequest{Owner: "testowner", Repo: "testrepo", Workflow: "release.yml", Branch: "main", RunNumber: 13})
+
+    if got := countCalls(api, "ListWorkflowRunsByFileName"); got != 2 {
+        t.Errorf("Decide() failed - expects the view to be fetched again after the refresh interval (2 calls) but received %d calls", got)
+    }
+
+    if stats := srv.Stats(); stats.Requests != 22 || stats.GithubCalls != 2 || stats.Views != 1 {
+        t.Errorf("Stats() failed - expects 22 requests, 2 Github calls and 1 view but received %+v", stats)
+    }
+}
+
+func TestGetWorkflowRunByID(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    var tests = []struct {
+        name      string
+        id        int64
+        age       time.Duration
+        wantCalls int
+    }{
+        {"should serve a run that has not completed within the refresh interval", 2222222222, DefaultRefreshInterval - time.Second, 1},
+        {"should fetch a run that has not completed after the refresh interval", 2222222222, DefaultRefreshInterval, 2},
+        {"should serve a completed run past the refresh interval", 1111111111, DefaultRefreshInterval, 1},
+        {"should fetch a completed run after the completed TTL", 1111111111, DefaultCompletedTTL, 2},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+
+            api := newFake()
+
+            now := time.Date(2022, time.December, 13, 0, 0, 0, 0, time.UTC)
+            srv := New(Options{API: api, Now: func() time.Time { return now }})
+
+            srv.GetWorkflowRunByID(context.Background(), "testowner", "testrepo", tt.id)
+
+            now = now.Add(tt.age)
+
+            run, res, err := srv.GetWorkflowRunByID(context.Background(), "testowner", "testrepo", tt.id)
+
+            if err != nil || res.StatusCode != http.StatusOK || run.GetID() != tt.id {
+                t.Fatalf("GetWorkflowRunByID() failed - expects run %d but received %v (%v)", tt.id, run, err)
+            }
+
+            if got := countCalls(api, "GetWorkflowRunByID"); got != tt.wantCalls {
+                t.Errorf("GetWorkflowRunByID() failed - expects %d calls to Github but received %d", tt.wantCalls, got)
+            }
+        })
+    }
+}
+
+func TestHandler(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    srv := New(Options{API: newFake(), Workflows: newFake(), WebhookSecret: "s3cr3t", AllowRepos: []string{"testowner/testrepo"}})
+
+    server := httptest.NewServer(srv.Handler())
+    defer server.Close()
+
+    ctx := context.Background()
+
+    gate, _ := sorter.New(sorter.Options{API: newFake(), Owner: "testowner", Repo: "testrepo", Workflow: "release.yml", Branch: "main", RunNumber: 13})
+    want, _ := gate.Decide(ctx)
+
+    t.Run("should answer with the decision the cli computes", func(t *testing.T) {
+
+        res, err := http.Get(server.URL + "/decision?owner=testowner&repo=testrepo&workflow=release.yml&branch=main&run_number=13&events=push")
+
+        if err != nil {
+            t.Fatalf("GET /decision failed - %s", err.Error())
+        }
+
+        defer res.Body.Close()
+
+        var got DecisionResponse
+        json.NewDecoder(res.Body).Decode(&got)
+
+        wantResponse := decisionResponse(want)
+
+        if res.StatusCode != http.StatusOK || !reflect.DeepEqual(got, wantResponse) || len(got.Runs) != 3 || got.Runs[0].RunNumber != 13 {
+            t.Errorf("GET /decision failed - expects %+v but received %d %+v", wantResponse, res.StatusCode, got)
+        }
+    })
+
+    t.Run("should reject a decision request without a run number", func(t *testing.T) {
+
+        res, err := http.Get(server.URL + "/decision?owner=testowner&repo=testrepo&workflow=release.yml")
+
+        if err != nil {
+            t.Fatalf("GET /decision failed - %s", err.Error())
+        }
+
+        res.Body.Close()
+
+        if res.StatusCode != http.StatusUnprocessableEntity {
+            t.Errorf("GET /decision failed - expects status 422 but received %d", res.StatusCode)
+        }
+    })
+
+    t.Run("should decide the same for a client pointed at the server", func(t *testing.T) {
+
+        client := github.NewClient(nil)
+        client.BaseURL, _ = url.Parse(server.URL + "/api/v3/")
+
+        gate, _ := sorter.New(sorter.Options{Client: client, Owner: "testowner", Repo: "testrepo", Workflow: "release.yml", Branch: "main", RunNumber: 13})
+
+        got, err := gate.Decide(ctx)
+
+        if err != nil {
+            t.Fatalf("Decide() failed - %s", err.Error())
+        }
+
+        if got.Outcome != want.Outcome || got.PastRunID != want.PastRunID || got.Trace.Rule != want.Trace.Rule {
+            t.Errorf("Decide() failed - expects %s on %d but received %s on %d", want.Outcome, want.PastRunID, got.Outcome, got.PastRunID)
+        }
+
+        workflows, _, err := client.Actions.ListWorkflows(ctx, "testowner", "testrepo", nil)
+
+        if err != nil || workflows.GetTotalCount() != 1 || workflows.Workflows[0].GetPath() != ".github/workflows/release.yml" {
+            t.Errorf("ListWorkflows() failed - expects release.yml but received %+v (%v)", workflows, err)
+        }
+    })
+
+    t.Run("should pass on the status of failed Github calls", func(t *testing.T) {
+
+        res, err := http.Get(server.URL + "/repos/testowner/testrepo/actions/workflows/unknown.yml/runs?branch=main")
+
+        if err != nil {
+            t.Fatalf("GET runs failed - %s", err.Error())
+        }
+
+        res.Body.Close()
+
+        if res.StatusCode != http.StatusNotFound {
+            t.Errorf("GET runs failed - expects status 404 but received %d", res.StatusCode)
+        }
+    })
+
+    t.Run("should apply a signed workflow_run delivery to the view", func(t *testing.T) {
+
+        payload, _ := json.Marshal(github.WorkflowRunEvent{
+            Action:   github.String("completed"),
+            Workflow: &github.Workflow{ID: github.Int64(1), Path: github.String(".github/workflows/release.yml")},
+            WorkflowRun: &github.WorkflowRun{
+                ID:         github.Int64(2222222222),
+                RunNumber:  github.Int(12),
+                WorkflowID: github.Int64(1),
+                HeadBranch: github.String("main"),
+                Event:      github.String("push"),
+                Status:     github.String("completed"),
+                Conclusion: github.String("success"),
+            },
+            Repo: &github.Repository{Name: github.String("testrepo"), Owner: &github.User{Login: github.String("testowner")}},
+        })
+
+        if status := deliver(t, server.URL, payload, "wrong"); status != http.StatusUnauthorized {
+            t.Errorf("POST /webhook failed - expects status 401 for a wrong signature but received %d", status)
+        }
+
+        if status := deliver(t, server.URL, payload, "s3cr3t"); status != http.StatusNoContent {
+            t.Fatalf("POST /webhook failed - expects status 204 but received %d", status)
+        }
+
+        runs, _, err := srv.ListWorkflowRunsByFileName(ctx, "testowner", "testrepo", "release.yml", &github.ListWorkflowRunsOptions{Branch: "main"})
+
+        if err != nil || len(runs.WorkflowRuns) != 3 || runs.WorkflowRuns[1].GetConclusion() != "success" {
+            t.Fatalf("ListWorkflowRunsByFileName() failed - expects run 12 completed by the delivery but received %+v (%v)", runs, err)
+        }
+
+        run, _, err := srv.GetWorkflowRunByID(ctx, "testowner", "testrepo", 2222222222)
+
+        if err != nil || run.GetStatus() != "completed" {
+            t.Errorf("GetWorkflowRunByID() failed - expects run 12 completed by the delivery but received %+v (%v)", run, err)
+        }
+
+        if stats := srv.Stats(); stats.Webhooks != 1 {
+            t.Errorf("Stats() failed - expects 1 webhook but received %d", stats.Webhooks)
+        }
+    })
+}
+
+func TestHandlerAccess(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    api := newFake()
+    srv := New(Options{API: api, Workflows: api, AllowRepos: []string{"otherowner/otherrepo", "TestOwner/*"}, Secret: "s3cr3t"})
+
+    server := httptest.NewServer(srv.Handler())
+    defer server.Close()
+
+    decision := "/decision?owner=%s&repo=%s&workflow=release.yml&branch=main&run_number=13"
+    runs := "/repos/%s/%s/actions/workflows/release.yml/runs?branch=main"
+
+    tests := []struct {
+        name          string
+        path          string
+        owner         string
+        repo          string
+        authorization string
+        wantStatus    int
+    }{
+        {"should serve a decision for an allowed repository", decision, "testowner", "testrepo", "Bearer s3cr3t", http.StatusOK},
+        {"should serve runs of an allowed repository", runs, "testowner", "testrepo", "Bearer s3cr3t", http.StatusOK},
+        {"should serve runs on the Github Enterprise Server path", "/api/v3" + runs, "testowner", "testrepo", "Bearer s3cr3t", http.StatusOK},
+        {"should forbid a decision without the secret", decision, "testowner", "testrepo", "", http.StatusForbidden},
+        {"should forbid runs with a wrong secret", runs, "testowner", "testrepo", "Bearer wrong", http.StatusForbidden},
+        {"should forbid a decision for a repository not allowed", decision, "octo", "hello", "Bearer s3cr3t", http.StatusForbidden},
+        {"should forbid runs of a repository not allowed", runs, "octo", "hello", "Bearer s3cr3t", http.StatusForbidden},
+        {"should keep the health check open", "/healthz%s%s", "", "", "", http.StatusOK},
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            req, _ := http.NewRequest(http.MethodGet, server.URL+fmt.Sprintf(tt.path, tt.owner, tt.repo), nil)
+
+            if tt.authorization != "" {
+                req.Header.Set("Authorization", tt.authorization)
+            }
+
+            res, err := http.DefaultClient.Do(req)
+
+            if err != nil {
+                t.Fatalf("GET %s failed - %s", tt.path, err.Error())
+            }
+
+            res.Body.Close()
+
+            if res.StatusCode != tt.wantStatus {
+                t.Errorf("GET %s failed - expects status %d but received %d", req.URL.Path, tt.wantStatus, res.StatusCode)
+            }
+
+        })
+    }
+
+    if got := countCalls(api, "ListWorkflowRunsByFileName"); got != 1 {
+        t.Errorf("Handler() failed - expects 1 call to Github for the allowed repository but received %d", got)
+    }
+
+    // unsigned deliveries could change the state of any run:
+    if status := deliver(t, server.URL, []byte("{}"), ""); status != http.StatusNotFound {
+        t.Errorf("POST /webhook failed - expects status 404 without a webhook secret but received %d", status)
+    }
+}
+
+func TestUpsert(t *testing.T){
+
+    runs := &github.WorkflowRuns{TotalCount: github.Int(2), WorkflowRuns: []*github.WorkflowRun{
+        {ID: github.Int64(2), RunNumber: github.Int(2)},
+        {ID: github.Int64(1), RunNumber: github.Int(1)},
+    }}
+
+    var tests = []struct {
+        name      string
+        run       *github.WorkflowRun
+        wantIDs   []int64
+        wantTotal int
+    }{
+        {"should replace a run in the view", &github.WorkflowRun{ID: github.Int64(1), RunNumber: github.Int(1)}, []int64{2, 1}, 2},
+        {"should add a new run newest first", &github.WorkflowRun{ID: github.Int64(3), RunNumber: github.Int(3)}, []int64{3, 2, 1}, 3},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+
+            got := upsert(runs, tt.run)
+
+            ids := []int64{}
+
+            for _, run := range got.WorkflowRuns {
+                ids = append(ids, run.GetID())
+            }
+
+            if !reflect.DeepEqual(ids, tt.wantIDs) || got.GetTotalCount() != tt.wantTotal {
+                t.Errorf("upsert() failed - expects %v of %d but received %v of %d", tt.wantIDs, tt.wantTotal, ids, got.GetTotalCount())
+            }
+        })
+    }
+}
+
+func TestUpdateConcurrent(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    now := time.Date(2022, time.December, 13, 0, 0, 0, 0, time.UTC)
+    srv := New(Options{API: newFake(), Now: func() time.Time { return now }})
+
+    listOpts := &github.ListWorkflowRunsOptions{Branch: "main", ListOptions: github.ListOptions{PerPage: 100}}
+
+    if _, _, err := srv.ListWorkflowRunsByFileName(context.Background(), "testowner", "testrepo", "release.yml", listOpts); err != nil {
+        t.Fatalf("ListWorkflowRunsByFileName() failed - %s", err.Error())
+    }
+
+    // every run delivered at the same time makes it into the view:
+    var wg sync.WaitGroup
+
+    for i := 0; i < 20; i++ {
+
+        wg.Add(1)
+
+        go func(i int) {
+            defer wg.Done()
+            srv.update("testowner", "testrepo", []string{"release.yml"}, &github.WorkflowRun{ID: github.Int64(int64(4000000000 + i)), RunNumber: github.Int(14 + i), HeadBranch: github.String("main"), Status: github.String("queued")})
+        }(i)
+    }
+
+    wg.Wait()
+
+    runs, _, err := srv.ListWorkflowRunsByFileName(context.Background(), "testowner", "testrepo", "release.yml", listOpts)
+
+    if err != nil {
+        t.Fatalf("ListWorkflowRunsByFileName() failed - %s", err.Error())
+    }
+
+    if len(runs.WorkflowRuns) != 23 || runs.GetTotalCount() != 23 {
+        t.Errorf("update() failed - expects 23 runs in the view but received %d of %d", len(runs.WorkflowRuns), runs.GetTotalCount())
+    }
+}
+
+func TestUpdateIgnoresCase(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    api := newFake()
+    srv := New(Options{API: api})
+
+    listOpts := &github.ListWorkflowRunsOptions{Branch: "main"}
+
+    if _, _, err := srv.ListWorkflowRunsByFileName(context.Background(), "testowner", "testrepo", "release.yml", listOpts); err != nil {
+        t.Fatalf("ListWorkflowRunsByFileName() failed - %s", err.Error())
+    }
+
+    // webhooks name the repository in its own case - clients in any:
+    srv.update("TestOwner", "TestRepo", []string{"release.yml"}, &github.WorkflowRun{ID: github.Int64(2222222222), RunNumber: github.Int(12), HeadBranch: github.String("main"), Status: github.String("completed"), Conclusion: github.String("success")})
+
+    runs, _, err := srv.ListWorkflowRunsByFileName(context.Background(), "testowner", "testrepo", "release.yml", listOpts)
+
+    if err != nil || len(runs.WorkflowRuns) != 3 || runs.WorkflowRuns[1].GetStatus() != "completed" {
+        t.Fatalf("ListWorkflowRunsByFileName() failed - expects run 12 completed by the update but received %+v (%v)", runs, err)
+    }
+
+    run, _, err := srv.GetWorkflowRunByID(context.Background(), "TESTOWNER", "testrepo", 2222222222)
+
+    if err != nil || run.GetStatus() != "completed" {
+        t.Errorf("GetWorkflowRunByID() failed - expects run 12 completed by the update but received %+v (%v)", run, err)
+    }
+
+    if got := len(api.Calls()); got != 1 {
+        t.Errorf("update() failed - expects 1 call to Github but received %d", got)
+    }
+}
+
+func deliver(t *testing.T, serverURL string, payload []byte, secret string) int {
+
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write(payload)
+
+    req, _ := http.NewRequest(http.MethodPost, serverURL+"/webhook", bytes.NewReader(payload))
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("X-GitHub-Event", "workflow_run")
+    req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+    res, err := http.DefaultClient.Do(req)
+
+    if err != nil {
+        t.Fatalf("POST /webhook failed - %s", err.Error())
+    }
+
+    res.Body.Close()
+
+    return res.StatusCode
+}
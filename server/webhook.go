@@ -0,0 +1,80 @@
+package server
+
+import (
+    "net/http"
+    "path"
+    "strconv"
+
+    gh "gh-actions-workflow-runs-sorter/gh"
+
+    "github.com/google/go-github/v47/github"
+    log "github.com/sirupsen/logrus"
+)
+
+// serveWebhook applies workflow_run deliveries to the shared views, so they reflect a run starting, completing
+// or being re-run before the next refresh. Deliveries of other events (e.g. 'ping') are accepted and ignored.
+func (s *Server) serveWebhook(w http.ResponseWriter, r *http.Request) {
+
+    if r.Method != http.MethodPost {
+        gh.WriteError(w, http.StatusMethodNotAllowed, nil, "Method not allowed")
+        return
+    }
+
+    payload, err := github.ValidatePayload(r, []byte(s.opts.WebhookSecret))
+
+    if err != nil {
+
+        log.WithFields(log.Fields{
+            "delivery": github.DeliveryID(r),
+        }).Warn("Rejected webhook delivery: " + err.Error())
+
+        gh.WriteError(w, http.StatusUnauthorized, nil, err.Error())
+        return
+    }
+
+    event, err := github.ParseWebHook(github.WebHookType(r), payload)
+
+    if err != nil {
+        gh.WriteError(w, http.StatusBadRequest, nil, err.Error())
+        return
+    }
+
+    runEvent, ok := event.(*github.WorkflowRunEvent)
+
+    if !ok || runEvent.GetWorkflowRun() == nil || runEvent.GetRepo().GetOwner().GetLogin() == "" {
+        w.WriteHeader(http.StatusNoContent)
+        return
+    }
+
+    run := runEvent.GetWorkflowRun()
+    owner, repo := runEvent.GetRepo().GetOwner().GetLogin(), runEvent.GetRepo().GetName()
+
+    // runs of repositories that are not served are never asked for:
+    if !s.allowed(owner, repo) {
+        w.WriteHeader(http.StatusNoContent)
+        return
+    }
+
+    // views are kept by the workflow as the cli lists its runs - its file name or its ID:
+    workflows := []string{strconv.FormatInt(run.GetWorkflowID(), 10)}
+
+    if runEvent.GetWorkflow().GetPath() != "" {
+        workflows = append(workflows, path.Base(runEvent.GetWorkflow().GetPath()))
+    }
+
+    s.update(owner, repo, workflows, run)
+
+    s.mu.Lock()
+    s.stats.Webhooks++
+    s.mu.Unlock()
+
+    log.WithFields(log.Fields{
+        "repo":       owner + "/" + repo,
+        "workflows":  workflows,
+        "runNumber":  run.GetRunNumber(),
+        "status":     run.GetStatus(),
+        "conclusion": run.GetConclusion(),
+    }).Debug("Applied workflow_run webhook ...")
+
+    w.WriteHeader(http.StatusNoContent)
+}
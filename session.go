@@ -0,0 +1,361 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "os"
+    "os/signal"
+    "path/filepath"
+    "strings"
+    "syscall"
+    "time"
+
+    gh "gh-actions-workflow-runs-sorter/gh"
+    sorter "gh-actions-workflow-runs-sorter/sorter"
+    tracing "gh-actions-workflow-runs-sorter/tracing"
+
+    "github.com/google/go-github/v47/github"
+    log "github.com/sirupsen/logrus"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
+)
+
+// session is what every command runs with - the github client, the optional cache and the span of the command.
+type session struct {
+    ctx      context.Context
+    client   *github.Client
+    api      gh.WorkflowRunsAPI
+    cache    *gh.RunCache
+    rootSpan trace.Span
+    recorder *gh.Recorder
+    clock    sorter.Clock
+
+    // workflows resolves --workflow-file once per invocation.
+    workflows *gh.WorkflowResolver
+
+    // coordinator shares results between the processes of the run attempt - nil without --coordinate.
+    coordinator *gh.Coordinator
+
+    shutdownTracing func(context.Context) error
+    stopSignals     context.CancelFunc
+    opts            *options
+}
+
+// startSession creates the github client - recording or replaying its API calls when asked to - sets up tracing,
+// continuing the caller's trace when TRACEPARENT is set, and opens the optional on-disk cache. Failures to set up
+// tracing or the cache are logged and otherwise ignored; a cassette that cannot be replayed is an error, and so is
+// a missing token when requireToken is set, unless --anonymous was passed, API calls are replayed or go to a
+// --server.
+func startSession(spanName string, opts *options, requireToken bool) (*session, error) {
+
+    if requireToken && opts.token == "" && !opts.anonymous && opts.replay == "" && opts.server == "" {
+        return nil, withExitCode(exitConfigError, fmt.Errorf("No Github token found - set GH_TOKEN (e.g. 'GH_TOKEN: ${{ github.token }}' in the step's env), pass --token-file or --token-stdin, or log in with 'gh auth login' (tokens it keeps in the system keyring are not read - use GH_TOKEN=$(gh auth token)); pass --anonymous to call the API without a token at 60 requests an hour"))
+    }
+
+    var transport http.RoundTripper
+    var recorder *gh.Recorder
+    var clock sorter.Clock
+
+    if opts.record != "" && opts.replay != "" {
+        return nil, withExitCode(exitConfigError, fmt.Errorf("--record and --replay cannot be combined"))
+    }
+
+    // replayed calls never reach the network:
+    if opts.replay == "" {
+
+        network, err := gh.NewTransport(gh.TransportOptions{
+            CACert:          opts.caCert,
+            ClientCert:      opts.clientCert,
+            ClientKey:       opts.clientKey,
+            ConnectTimeout:  time.Duration(opts.connectTimeout)*time.Second,
+            ResponseTimeout: time.Duration(opts.responseTimeout)*time.Second,
+            DebugTLS:        opts.tlsDebug,
+        })
+
+        if err != nil {
+            return nil, withExitCode(exitConfigError, err)
+        }
+
+        transport = network
+    }
+
+    switch {
+
+    case opts.record != "":
+        recorder = gh.NewRecorder(transport)
+        transport = recorder
+
+    case opts.replay != "":
+        cassette, err := gh.LoadCassette(opts.replay)
+
+        if err != nil {
+            return nil, withExitCode(exitConfigError, err)
+        }
+
+        replayer := gh.NewReplayer(cassette)
+        transport, clock = replayer, replayer
+
+        log.WithFields(log.Fields{
+            "cassette":     opts.replay,
+            "recordedAt":   cassette.RecordedAt,
+            "interactions": len(cassette.Interactions),
+        }).Info("Replaying Github API interactions from cassette ...")
+    }
+
+    apiURL, token := opts.apiURL, opts.token
+
+    // the server calls Github with its own token - the runner's token is not sent to it, the server's secret is:
+    if opts.server != "" {
+        apiURL, token = opts.server, opts.serverSecret
+    }
+
+    ctx, client, err := gh.CreateClient(apiURL, token, transport)
+
+    if err != nil {
+        return nil, withExitCode(exitConfigError, err)
+    }
+
+    var coordinator *gh.Coordinator
+
+    if opts.coordinate {
+
+        var coordinationErr error
+
+        coordinator, coordinationErr = gh.NewCoordinator(coordinationDir(opts), opts.coordinationKey)
+
+        if coordinationErr != nil {
+            return nil, withExitCode(exitConfigError, coordinationErr)
+        }
+    }
+
+    // with --exit-codes SIGINT/SIGTERM cancel the command so it can end with the 'cancelled' exit code:
+    stopSignals := context.CancelFunc(func() {})
+
+    if opts.exitCodes {
+        ctx, stopSignals = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+    }
+
+    shutdownTracing, tracingErr := tracing.Setup(ctx, opts.traceExporter, opts.traceEndpoint, opts.traceFile)
+
+    if tracingErr != nil {
+        log.WithFields(log.Fields{
+            "traceExporter": opts.traceExporter,
+        }).Warn(fmt.Sprintf("continuing without tracing: %s", tracingErr.Error()))
+    }
+
+    ctx, rootSpan := tracing.Start(tracing.ContextFromEnv(ctx), spanName,
+        attribute.String("github.repo", fmt.Sprintf("%s/%s", opts.owner, opts.repo)),
+        attribute.String("github.workflow", opts.workflowFile),
+        attribute.String("github.branch", opts.branch),
+        attribute.Int("github.run_number", opts.runNumber),
+    )
+
+    // decisions simulated from a snapshot never call the API:
+    var api gh.WorkflowRunsAPI = client.Actions
+
+    if opts.snapshot != nil {
+        api = opts.snapshot
+    }
+
+    var cache *gh.RunCache
+
+    if opts.cacheDir != "" && opts.snapshot == nil {
+
+        var cacheErr error
+
+        cache, cacheErr = gh.NewRunCache(opts.cacheDir, time.Duration(opts.cacheRunsTTL)*time.Second, time.Duration(opts.cacheStatusTTL)*time.Second, time.Duration(opts.cacheCompletedTTL)*time.Second)
+
+        if cacheErr != nil {
+            log.WithFields(log.Fields{
+                "cacheDir": opts.cacheDir,
+            }).Warn(fmt.Sprintf("continuing without cache: %s", cacheErr.Error()))
+        }
+    }
+
+    return &session{
+        ctx:             ctx,
+        client:          client,
+        api:             api,
+        cache:           cache,
+        rootSpan:        rootSpan,
+        recorder:        recorder,
+        clock:           clock,
+        workflows:       gh.NewWorkflowResolver(client.Actions),
+        coordinator:     coordinator,
+        shutdownTracing: shutdownTracing,
+        stopSignals:     stopSignals,
+        opts:            opts,
+    }, nil
+}
+
+// coordinationDir is where processes of a run attempt coordinate - in --cache-dir, which may be shared between
+// hosts, or else in the runner's temporary directory, which processes on one host share.
+func coordinationDir(opts *options) string {
+
+    switch {
+
+    case opts.cacheDir != "":
+        return filepath.Join(opts.cacheDir, "coordination")
+
+    case os.Getenv("RUNNER_TEMP") != "":
+        return filepath.Join(os.Getenv("RUNNER_TEMP"), "gh-actions-workflow-runs-sorter")
+
+    default:
+        return filepath.Join(os.TempDir(), "gh-actions-workflow-runs-sorter")
+    }
+}
+
+// coordinate fills result with the result another process of the run attempt stored under name - or calls
+// compute and stores its result for them. shared reports whether result came from another process.
+func (s *session) coordinate(ctx context.Context, name string, result interface{}, compute func() error) (shared bool, err error) {
+
+    if s.coordinator == nil {
+        return false, compute()
+    }
+
+    return s.coordinator.Do(ctx, name, result, compute)
+}
+
+// close ends the command's span, flushes traces, exports metrics and writes the recorded cassette.
+func (s *session) close() {
+
+    s.stopSignals()
+    s.rootSpan.End()
+    s.shutdownTracing(context.Background())
+
+    exportMetrics(s.opts.metricsTextfile, s.opts.metricsPushURL, s.opts.metricsPushJob, map[string]string{
+        "repo":     fmt.Sprintf("%s/%s", s.opts.owner, s.opts.repo),
+        "workflow": s.opts.workflowFile,
+        "branch":   s.opts.branch,
+    })
+
+    if s.recorder != nil {
+
+        if err := s.recorder.Save(s.opts.record); err != nil {
+            log.WithFields(log.Fields{
+                "cassette": s.opts.record,
+            }).Error(err.Error())
+        } else {
+            log.WithFields(log.Fields{
+                "cassette":     s.opts.record,
+                "interactions": len(s.recorder.Cassette().Interactions),
+            }).Info("Recorded Github API interactions to cassette ...")
+        }
+    }
+}
+
+// now is the time decisions are made at - the time of the cassette when replaying and the time a snapshot was
+// captured when simulating.
+func (s *session) now() time.Time {
+
+    if s.opts.snapshot != nil {
+        return s.opts.snapshot.CapturedAt
+    }
+
+    if s.clock != nil {
+        return s.clock.Now()
+    }
+
+    return time.Now()
+}
+
+// workflow resolves --workflow-file - an ID, file name, path or display name - to the identifier runs are listed
+// by. The workflow of a snapshot is used as it is.
+func (s *session) workflow() (string, error) {
+
+    if s.opts.workflowFile == "" || s.opts.snapshot != nil {
+        return s.opts.workflowFile, nil
+    }
+
+    workflow, err := s.workflows.Resolve(s.ctx, s.opts.owner, s.opts.repo, s.opts.workflowFile)
+
+    switch {
+
+    case errors.Is(err, gh.ErrWorkflowNotFound) || errors.Is(err, gh.ErrWorkflowAmbiguous):
+        return "", withExitCode(exitConfigError, err)
+
+    case err != nil:
+        return "", withExitCode(exitAPIError, err)
+    }
+
+    return workflow, nil
+}
+
+// gate returns the ordering gate configured by the command's flags - onEvent may be nil.
+func (s *session) gate(onEvent func(sorter.Event)) (*sorter.Gate, error) {
+
+    workflow, err := s.workflow()
+
+    if err != nil {
+        return nil, err
+    }
+
+    events := []string{}
+
+    for _, event := range strings.Split(s.opts.events, ",") {
+
+        if strings.TrimSpace(event) != "" {
+            events = append(events, strings.TrimSpace(event))
+        }
+    }
+
+    var cache sorter.Cache
+
+    // a nil *gh.RunCache is not a nil sorter.Cache:
+    if s.cache != nil {
+        cache = s.cache
+    }
+
+    gate, err := sorter.New(sorter.Options{
+        Client:             s.client,
+        API:                s.api,
+        Owner:              s.opts.owner,
+        Repo:               s.opts.repo,
+        Workflow:           workflow,
+        Branch:             s.opts.branch,
+        RunNumber:          s.opts.runNumber,
+        RunsToReturn:       s.opts.workflowRunsToReturn,
+        Events:             events,
+        WaitBetweenChecks:  time.Duration(s.opts.waitBetweenChecks)*time.Second,
+        WaitBeforeComplete: time.Duration(s.opts.waitBeforeComplete*float64(time.Second)),
+        CompletionPolicy:   s.opts.completionPolicy,
+        RerunGracePeriod:   time.Duration(s.opts.rerunGracePeriod)*time.Second,
+        StopOnAPIError:     s.opts.exitCodes,
+        Cache:              cache,
+        OnEvent:            logAPIEvents(onEvent),
+        Clock:              s.clock,
+    })
+
+    if err != nil {
+        return nil, withExitCode(exitConfigError, err)
+    }
+
+    return gate, nil
+}
+
+// logAPIEvents logs the EventAPI events of a Gate as its API calls would have been logged without one, and passes
+// the other events on to onEvent.
+func logAPIEvents(onEvent func(sorter.Event)) func(sorter.Event) {
+
+    return func(event sorter.Event) {
+
+        if event.Type != sorter.EventAPI {
+
+            if onEvent != nil {
+                onEvent(event)
+            }
+
+            return
+        }
+
+        level, err := log.ParseLevel(event.Level)
+
+        if err != nil {
+            level = log.InfoLevel
+        }
+
+        log.WithFields(log.Fields(event.Fields)).Log(level, event.Message)
+    }
+}
@@ -0,0 +1,40 @@
+package sorter
+
+import (
+    "context"
+    "time"
+)
+
+// Clock is the time source of a Gate - ghfake.Clock advances instantly for deterministic tests.
+type Clock interface {
+    Now() time.Time
+
+    // Sleep waits for d unless ctx is done first, returning ctx's error then.
+    Sleep(ctx context.Context, d time.Duration) error
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+
+    return time.Now()
+}
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) error {
+
+    if d <= 0 {
+        return ctx.Err()
+    }
+
+    timer := time.NewTimer(d)
+    defer timer.Stop()
+
+    select {
+
+    case <-ctx.Done():
+        return ctx.Err()
+
+    case <-timer.C:
+        return nil
+    }
+}
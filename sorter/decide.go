@@ -0,0 +1,148 @@
+package sorter
+
+import (
+    "context"
+    "strings"
+    "time"
+
+    gh "gh-actions-workflow-runs-sorter/gh"
+    util "gh-actions-workflow-runs-sorter/util"
+
+    "github.com/google/go-github/v47/github"
+)
+
+// Decision is whether this run should execute and which run, if any, it must wait for.
+type Decision struct {
+    Outcome     string
+    PastRunID   int64
+    Predecessor *github.WorkflowRun
+    Superseder  *github.WorkflowRun
+
+    // Runs are the runs considered (newest first) - after filtering by event.
+    Runs  []*github.WorkflowRun
+    Trace Trace
+}
+
+// Execute reports whether this run should execute.
+func (d Decision) Execute() bool {
+
+    return d.Outcome != Skip
+}
+
+// WaitForPastRun reports whether this run must wait for the run with PastRunID before completing.
+func (d Decision) WaitForPastRun() bool {
+
+    return d.Outcome == Wait
+}
+
+// Decide returns the decision for Options.RunNumber given the most recent runs of the workflow - the decision
+// returned with ErrNoRuns is to skip.
+func (g *Gate) Decide(ctx context.Context) (Decision, error) {
+
+    all, err := g.allRuns(ctx, g.opts.RunsToReturn)
+
+    if err != nil {
+        return Decision{Outcome: Skip}, err
+    }
+
+    events := strings.Join(g.opts.Events, ",")
+    runs := util.FilterRunsByEvent(all, events)
+    trace := util.ExplainExecuteEvents(all, events, g.opts.RunNumber)
+
+    decision := Decision{
+        Outcome: outcome(trace),
+        Runs:    runs,
+        Trace:   traceOf(trace),
+    }
+
+    for i, run := range trace.Runs {
+
+        switch run.Role {
+
+        case util.RolePredecessor:
+            decision.Predecessor = all[i]
+            decision.PastRunID = run.ID
+
+        case util.RoleSuperseder:
+            decision.Superseder = all[i]
+        }
+    }
+
+    if len(runs) < 1 {
+        return decision, ErrNoRuns
+    }
+
+    return decision, nil
+}
+
+// Queue returns the current ordering queue of the workflow.
+func (g *Gate) Queue(ctx context.Context, now time.Time) (Queue, error) {
+
+    runs, err := g.runs(ctx, g.opts.RunsToReturn)
+
+    if err != nil {
+        return Queue{}, err
+    }
+
+    return queueOf(util.BuildQueue(runs, now)), nil
+}
+
+// Runs returns up to n of the most recent runs of the workflow, filtered by event.
+func (g *Gate) Runs(ctx context.Context, n int) ([]*github.WorkflowRun, error) {
+
+    return g.runs(ctx, n)
+}
+
+func (g *Gate) runs(ctx context.Context, n int) ([]*github.WorkflowRun, error) {
+
+    runs, err := g.allRuns(ctx, n)
+
+    if err != nil {
+        return nil, err
+    }
+
+    return util.FilterRunsByEvent(runs, strings.Join(g.opts.Events, ",")), nil
+}
+
+// allRuns returns up to n of the most recent runs of the workflow, triggered by any event.
+func (g *Gate) allRuns(ctx context.Context, n int) ([]*github.WorkflowRun, error) {
+
+    var runs []*github.WorkflowRun
+    var err error
+
+    if logged := gh.WithLogger(ctx, g.logger); g.opts.Cache != nil {
+        runs, err = g.opts.Cache.WorkflowRuns(logged, g.opts.API, g.opts.Owner, g.opts.Repo, g.opts.Workflow, g.opts.Branch, n)
+    } else {
+        runs, err = gh.ReturnWorkflowRuns(g.opts.Branch, logged, g.opts.API, g.opts.Owner, g.opts.Repo, g.opts.Workflow, n)
+    }
+
+    if err != nil {
+        return nil, apiError(ctx, err)
+    }
+
+    return runs, nil
+}
+
+// apiError wraps a failed call - unless it failed because ctx is done, which is reported as is.
+func apiError(ctx context.Context, err error) error {
+
+    if ctx.Err() != nil {
+        return ctx.Err()
+    }
+
+    return &APIError{Err: err}
+}
+
+func outcome(trace util.ExecuteTrace) string {
+
+    switch {
+
+    case !trace.ShouldRunExecute:
+        return Skip
+
+    case trace.ShouldWaitForPastRun:
+        return Wait
+    }
+
+    return Proceed
+}
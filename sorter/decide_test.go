@@ -0,0 +1,133 @@
+package sorter
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "reflect"
+    "testing"
+
+    gh "gh-actions-workflow-runs-sorter/gh"
+
+    log "github.com/sirupsen/logrus"
+)
+
+const testRuns = `{"total_count":4,"workflow_runs":[
+    {"id": 5555555555, "run_number": 32, "event": "push", "status": "in_progress"},
+    {"id": 4444444444, "run_number": 31, "event": "push", "status": "in_progress"},
+    {"id": 3333333333, "run_number": 30, "event": "workflow_dispatch", "status": "queued"},
+    {"id": 2222222222, "run_number": 29, "event": "push", "status": "completed", "conclusion": "success"}
+]}`
+
+func TestDecide(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    tests := []struct {
+        name          string
+        httpstatus    int
+        runs          string
+        runNumber     int
+        events        []string
+        wantOutcome   string
+        wantPastRunID int64
+        wantErr       error
+    }{
+        {
+            name:          "should wait for the previous run that has not completed",
+            httpstatus:    200,
+            runs:          testRuns,
+            runNumber:     31,
+            wantOutcome:   Wait,
+            wantPastRunID: 3333333333,
+        },
+        {
+            name:          "should proceed once runs of other events are filtered out",
+            httpstatus:    200,
+            runs:          testRuns,
+            runNumber:     31,
+            events:        []string{"push"},
+            wantOutcome:   Proceed,
+            wantPastRunID: 2222222222,
+        },
+        {
+            name:          "should skip when a newer run has completed",
+            httpstatus:    200,
+            runs:          testRuns,
+            runNumber:     28,
+            wantOutcome:   Skip,
+        },
+        {
+            name:          "should fail when no runs were returned",
+            httpstatus:    200,
+            runs:          `{"total_count":0,"workflow_runs":[]}`,
+            runNumber:     31,
+            wantOutcome:   Skip,
+            wantErr:       ErrNoRuns,
+        },
+        {
+            name:          "should fail with an API error",
+            httpstatus:    404,
+            runs:          `{}`,
+            runNumber:     31,
+            wantOutcome:   Skip,
+            wantErr:       &APIError{Err: fmt.Errorf("Workflow not found")},
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            client, mux, _, teardown := gh.Setup()
+            defer teardown()
+
+            mux.HandleFunc("/repos/testowner/testrepo/actions/workflows/release.yml/runs", func(w http.ResponseWriter, r *http.Request) {
+                w.WriteHeader(tt.httpstatus)
+                fmt.Fprint(w, tt.runs)
+            })
+
+            gate, err := New(Options{Client: client, Owner: "testowner", Repo: "testrepo", Workflow: "release.yml", Branch: "main", RunNumber: tt.runNumber, Events: tt.events})
+
+            if err != nil {
+                t.Fatalf("New() failed - %s", err.Error())
+            }
+
+            decision, gotErr := gate.Decide(context.Background())
+
+            if tt.wantErr == ErrNoRuns && !errors.Is(gotErr, ErrNoRuns) || tt.wantErr != ErrNoRuns && !reflect.DeepEqual(gotErr, tt.wantErr) {
+                t.Errorf("Decide() failed - error expects %v but received %v", tt.wantErr, gotErr)
+            }
+
+            if decision.Outcome != tt.wantOutcome {
+                t.Errorf("Decide() failed - outcome expects %s but received %s", tt.wantOutcome, decision.Outcome)
+            }
+
+            if decision.PastRunID != tt.wantPastRunID {
+                t.Errorf("Decide() failed - past run id expects %d but received %d", tt.wantPastRunID, decision.PastRunID)
+            }
+        })
+    }
+}
+
+func TestNew(t *testing.T){
+
+    client, _, _, teardown := gh.Setup()
+    defer teardown()
+
+    gate, err := New(Options{Client: client, Owner: "testowner", Repo: "testrepo"})
+
+    if err != nil {
+        t.Fatalf("New() failed - %s", err.Error())
+    }
+
+    if got := gate.Options(); got.RunsToReturn != DefaultRunsToReturn || got.WaitBetweenChecks != DefaultWaitBetweenChecks || got.CompletionPolicy != CompletionAlways {
+        t.Errorf("New() failed - defaults were not filled in: %+v", got)
+    }
+
+    if _, err := New(Options{Owner: "testowner", CompletionPolicy: "never"}); err == nil {
+        t.Errorf("New() failed - expects an error for missing client, repo and an invalid completion policy")
+    }
+}
@@ -0,0 +1,64 @@
+package sorter
+
+import (
+    "time"
+
+    "github.com/google/go-github/v47/github"
+)
+
+// EventType names a step of WaitForTurn.
+type EventType string
+
+// steps of WaitForTurn, in the order they happen:
+const (
+    // EventChecked - the previous run was checked; Run is set.
+    EventChecked EventType = "checked"
+
+    // EventCheckFailed - checking the previous run failed and is retried; Err is set.
+    EventCheckFailed EventType = "check_failed"
+
+    // EventWaiting - the previous run has not completed; Wait is how long until the next check.
+    EventWaiting EventType = "waiting"
+
+    // EventAttemptChanged - the previous run was re-run; Run is the new attempt and Message says which it replaced.
+    EventAttemptChanged EventType = "attempt_changed"
+
+    // EventRerunGraceWaiting - the previous run did not succeed; Wait is how long until it is checked for a re-run
+    // again within Options.RerunGracePeriod.
+    EventRerunGraceWaiting EventType = "rerun_grace_waiting"
+
+    // EventPredecessorCompleted - the previous run has completed; Run is set.
+    EventPredecessorCompleted EventType = "predecessor_completed"
+
+    // EventPostCompletionSkipped - the completion policy skips the post-completion wait.
+    EventPostCompletionSkipped EventType = "post_completion_skipped"
+
+    // EventPostCompletionWaiting - Wait is how long is left to wait after the previous run completed.
+    EventPostCompletionWaiting EventType = "post_completion_waiting"
+
+    // EventReleased - this run's turn has come.
+    EventReleased EventType = "released"
+)
+
+// EventAPI - what a Github API call of any Gate method has to report, e.g. that it was made, failed or served from
+// the cache. Level is how important it is, as a logrus level name, and Fields describe the call.
+const EventAPI EventType = "api"
+
+// phases of waiting:
+const (
+    PhasePredecessor    = "predecessor"
+    PhasePostCompletion = "post_completion"
+)
+
+// Event reports progress of WaitForTurn, and the API calls of a Gate, through Options.OnEvent.
+type Event struct {
+    Type          EventType
+    Phase         string
+    PreviousRunID int64
+    Run           *github.WorkflowRun
+    Wait          time.Duration
+    Err           error
+    Message       string
+    Level         string
+    Fields        map[string]interface{}
+}
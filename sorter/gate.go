@@ -0,0 +1,228 @@
+// Package sorter decides whether a Github Actions workflow run should execute in run_number order and waits
+// for its turn - it is the library behind the gh-actions-workflow-runs-sorter cli.
+//
+//  gate, err := sorter.New(sorter.Options{
+//      Client:    client,
+//      Owner:     "octo-org",
+//      Repo:      "octo-repo",
+//      Workflow:  "release.yml",
+//      Branch:    "main",
+//      RunNumber: runNumber,
+//  })
+//
+//  decision, err := gate.Decide(ctx)
+//
+//  if decision.WaitForPastRun() {
+//      completion, err := gate.WaitForTurn(ctx, decision.PastRunID)
+//  }
+package sorter
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "strings"
+    "time"
+
+    config "gh-actions-workflow-runs-sorter/config"
+    gh "gh-actions-workflow-runs-sorter/gh"
+    util "gh-actions-workflow-runs-sorter/util"
+
+    "github.com/google/go-github/v47/github"
+    log "github.com/sirupsen/logrus"
+)
+
+// outcomes of a decision:
+const (
+    Proceed = util.DecisionProceed
+    Wait    = util.DecisionWait
+    Skip    = util.DecisionSkip
+)
+
+// completion policies - when to wait post-completion of the previous run:
+const (
+    CompletionAlways  = config.CompletionAlways
+    CompletionSuccess = config.CompletionSuccess
+    CompletionFail    = config.CompletionFail
+)
+
+// defaults for options left unset:
+const (
+    DefaultRunsToReturn      = 20
+    DefaultWaitBetweenChecks = 10 * time.Second
+)
+
+// ErrNoRuns is returned by Decide when Github Actions API returned no runs to order against.
+var ErrNoRuns = errors.New("No previous runs were returned from Github Actions API")
+
+// ErrPredecessorFailed is returned by WaitForTurn when the previous run did not succeed under CompletionFail.
+var ErrPredecessorFailed = errors.New("completion policy 'fail' fails this run")
+
+// APIError is a failed Github API call.
+type APIError struct {
+    Err error
+}
+
+func (e *APIError) Error() string {
+
+    return e.Err.Error()
+}
+
+func (e *APIError) Unwrap() error {
+
+    return e.Err
+}
+
+// Options configure a Gate. Client (or API), Owner, Repo and Workflow are required; RunNumber is required by Decide.
+type Options struct {
+    Client *github.Client
+
+    // API is used instead of Client.Actions when set - e.g. a ghfake.API in tests.
+    API gh.WorkflowRunsAPI
+
+    Owner     string
+    Repo      string
+    Workflow  string
+    Branch    string
+    RunNumber int
+
+    // RunsToReturn is the number of recent runs visited per decision - DefaultRunsToReturn when 0.
+    RunsToReturn int
+
+    // Events limits ordering to runs triggered by these events - all runs when empty.
+    Events []string
+
+    // WaitBetweenChecks is how long to wait between checks on the previous run - DefaultWaitBetweenChecks when 0.
+    WaitBetweenChecks time.Duration
+
+    // WaitBeforeComplete is how long to wait after the previous run completed.
+    WaitBeforeComplete time.Duration
+
+    // CompletionPolicy is one of the completion policies - CompletionAlways when empty.
+    CompletionPolicy string
+
+    // RerunGracePeriod is how long after the previous run completed without success WaitForTurn keeps checking
+    // it for a re-run - and waits for the re-run when there is one. Not at all when 0.
+    RerunGracePeriod time.Duration
+
+    // StopOnAPIError ends WaitForTurn on a failed check instead of retrying it.
+    StopOnAPIError bool
+
+    // Cache optionally shares API responses with other processes.
+    Cache Cache
+
+    // OnEvent is called with every step of WaitForTurn, and with what the Gate has to report about its API calls.
+    OnEvent func(Event)
+
+    // Clock is the time source WaitForTurn reads and sleeps on - the system clock when nil.
+    Clock Clock
+}
+
+// Cache shares API responses with other processes - a *gh.RunCache is one.
+type Cache interface {
+    WorkflowRuns(ctx context.Context, api gh.WorkflowRunsAPI, owner string, repo string, workflow string, branch string, n int) ([]*github.WorkflowRun, error)
+    WorkflowRun(ctx context.Context, api gh.WorkflowRunsAPI, owner string, repo string, runID int64) (*github.WorkflowRun, error)
+}
+
+// Gate orders runs of one workflow on one branch.
+type Gate struct {
+    opts Options
+
+    // logger turns what API calls log into EventAPI events - nothing is written:
+    logger *log.Logger
+}
+
+// New validates opts and fills in defaults.
+func New(opts Options) (*Gate, error) {
+
+    problems := []string{}
+
+    if opts.Client == nil && opts.API == nil {
+        problems = append(problems, "a github client is required")
+    }
+
+    if opts.Owner == "" || opts.Repo == "" {
+        problems = append(problems, "owner and repo are required")
+    }
+
+    if opts.RunsToReturn < 0 || opts.RunsToReturn > 100 {
+        problems = append(problems, "runs to return must be between 1 and 100")
+    }
+
+    if opts.WaitBetweenChecks < 0 || opts.WaitBeforeComplete < 0 || opts.RerunGracePeriod < 0 {
+        problems = append(problems, "waits must not be negative")
+    }
+
+    if opts.CompletionPolicy != "" && !config.ValidCompletionPolicy(opts.CompletionPolicy) {
+        problems = append(problems, fmt.Sprintf("completion policy must be '%s', '%s' or '%s'", CompletionAlways, CompletionSuccess, CompletionFail))
+    }
+
+    if len(problems) > 0 {
+        return nil, fmt.Errorf("Invalid options: %s", strings.Join(problems, "; "))
+    }
+
+    if opts.API == nil {
+        opts.API = opts.Client.Actions
+    }
+
+    if opts.RunsToReturn == 0 {
+        opts.RunsToReturn = DefaultRunsToReturn
+    }
+
+    if opts.WaitBetweenChecks == 0 {
+        opts.WaitBetweenChecks = DefaultWaitBetweenChecks
+    }
+
+    if opts.CompletionPolicy == "" {
+        opts.CompletionPolicy = CompletionAlways
+    }
+
+    if opts.Clock == nil {
+        opts.Clock = systemClock{}
+    }
+
+    gate := &Gate{opts: opts, logger: log.New()}
+
+    gate.logger.SetOutput(io.Discard)
+    gate.logger.SetLevel(log.TraceLevel)
+    gate.logger.AddHook(eventHook{gate: gate})
+
+    return gate, nil
+}
+
+// Options returns the options of g with defaults filled in.
+func (g *Gate) Options() Options {
+
+    return g.opts
+}
+
+func (g *Gate) emit(event Event) {
+
+    if g.opts.OnEvent != nil {
+        g.opts.OnEvent(event)
+    }
+}
+
+// eventHook emits what is logged to a Gate's logger as EventAPI events.
+type eventHook struct {
+    gate *Gate
+}
+
+func (h eventHook) Levels() []log.Level {
+
+    return log.AllLevels
+}
+
+func (h eventHook) Fire(entry *log.Entry) error {
+
+    fields := map[string]interface{}{}
+
+    for name, value := range entry.Data {
+        fields[name] = value
+    }
+
+    h.gate.emit(Event{Type: EventAPI, Level: entry.Level.String(), Message: entry.Message, Fields: fields})
+
+    return nil
+}
@@ -0,0 +1,99 @@
+package sorter
+
+import (
+    "time"
+
+    util "gh-actions-workflow-runs-sorter/util"
+)
+
+// Trace is every run considered in a decision along with the rule that decided.
+type Trace struct {
+    RunNumber            int
+    ShouldExecute        bool
+    ShouldWaitForPastRun bool
+    PastRunID            int64
+    Rule                 string
+    Runs                 []RunTrace
+}
+
+// RunTrace is how a single run was treated in a decision - Role is one of the util.Role* constants.
+type RunTrace struct {
+    RunNumber  int
+    ID         int64
+    Status     string
+    Conclusion string
+    HeadSHA    string
+    Branch     string
+    HTMLURL    string
+    Role       string
+}
+
+// Queue is the current ordering queue of a workflow - pending runs in run_number order with the latest
+// completed run as their baseline.
+type Queue struct {
+    Baseline *RunTrace
+    Entries  []QueueEntry
+}
+
+// QueueEntry is a queued or in_progress run along with the decision it would get right now.
+type QueueEntry struct {
+    RunNumber    int
+    ID           int64
+    Status       string
+    HeadSHA      string
+    Decision     string
+    WaitsOn      *RunTrace
+    WaitingSince time.Time
+    Waiting      time.Duration
+    Trace        Trace
+}
+
+func traceOf(trace util.ExecuteTrace) Trace {
+
+    converted := Trace{
+        RunNumber:            trace.RunNumber,
+        ShouldExecute:        trace.ShouldRunExecute,
+        ShouldWaitForPastRun: trace.ShouldWaitForPastRun,
+        PastRunID:            trace.PastRunId,
+        Rule:                 trace.Rule,
+    }
+
+    for _, run := range trace.Runs {
+        converted.Runs = append(converted.Runs, RunTrace(run))
+    }
+
+    return converted
+}
+
+func queueOf(queue util.Queue) Queue {
+
+    converted := Queue{Baseline: runTraceOf(queue.Baseline)}
+
+    for _, entry := range queue.Entries {
+
+        converted.Entries = append(converted.Entries, QueueEntry{
+            RunNumber:    entry.RunNumber,
+            ID:           entry.ID,
+            Status:       entry.Status,
+            HeadSHA:      entry.HeadSHA,
+            Decision:     entry.Decision,
+            WaitsOn:      runTraceOf(entry.WaitsOn),
+            WaitingSince: entry.WaitingSince,
+            Waiting:      entry.Waiting,
+            Trace:        traceOf(entry.Trace),
+        })
+    }
+
+    return converted
+}
+
+func runTraceOf(run *util.RunTrace) *RunTrace {
+
+    if run == nil {
+        return nil
+    }
+
+    converted := RunTrace(*run)
+
+    return &converted
+}
@@ -0,0 +1,307 @@
+package sorter
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    gh "gh-actions-workflow-runs-sorter/gh"
+    tracing "gh-actions-workflow-runs-sorter/tracing"
+
+    "github.com/google/go-github/v47/github"
+)
+
+// Completion is how WaitForTurn ended.
+type Completion struct {
+    Predecessor *github.WorkflowRun
+
+    // WaitBeforeComplete is the post-completion wait that applied - 0 when the completion policy skipped it.
+    WaitBeforeComplete time.Duration
+
+    PredecessorWaited    time.Duration
+    PostCompletionWaited time.Duration
+    ReleasedAt           time.Time
+    Rule                 string
+}
+
+// Check returns the current state of the run with previousRunID.
+func (g *Gate) Check(ctx context.Context, previousRunID int64) (*github.WorkflowRun, error) {
+
+    ctx = gh.WithLogger(ctx, g.logger)
+
+    if g.opts.Cache == nil {
+        return g.check(ctx, previousRunID, true)
+    }
+
+    run, err := g.opts.Cache.WorkflowRun(ctx, g.opts.API, g.opts.Owner, g.opts.Repo, previousRunID)
+
+    if err != nil {
+        return nil, apiError(ctx, err)
+    }
+
+    return run, nil
+}
+
+// WaitForTurn waits until the run with previousRunID has completed and Options.WaitBeforeComplete has passed since.
+// A run re-run while it is waited on - or within Options.RerunGracePeriod of completing without success - is
+// waited on again, attempt by attempt.
+// It returns ctx's error when ctx is done first, ErrPredecessorFailed when the previous run did not succeed under
+// CompletionFail and an *APIError on a failed check with Options.StopOnAPIError.
+func (g *Gate) WaitForTurn(ctx context.Context, previousRunID int64) (Completion, error) {
+
+    completion := Completion{WaitBeforeComplete: g.opts.WaitBeforeComplete}
+
+    // each wait phase gets a span - the post-completion phase starts once the previous run has completed:
+    _, waitSpan := tracing.Start(ctx, "wait "+PhasePredecessor)
+    defer waitSpan.End()
+
+    var run *github.WorkflowRun
+    var last *github.WorkflowRun
+
+    fresh := false
+
+    // check the previous run until it has completed - and was not re-run within RerunGracePeriod when it did not
+    // succeed:
+    for run == nil {
+
+        completed, err := g.waitForCompletion(ctx, previousRunID, fresh, &last, &completion)
+
+        if err != nil {
+            return completion, err
+        }
+
+        rerun, err := g.watchForRerun(ctx, previousRunID, completed, &last, &completion)
+
+        if err != nil {
+            return completion, err
+        }
+
+        if !rerun {
+            run = completed
+        }
+
+        // the cache would serve the attempt that completed - the re-run is checked with the API:
+        fresh = true
+    }
+
+    waitSpan.End()
+
+    completion.Predecessor = run
+
+    g.emit(Event{Type: EventPredecessorCompleted, Phase: PhasePredecessor, PreviousRunID: previousRunID, Run: run})
+
+    // the 'fail' completion policy fails this run when the previous run did not succeed:
+    if g.opts.CompletionPolicy == CompletionFail && run.GetConclusion() != "success" {
+        return completion, fmt.Errorf("previous run %d concluded '%s' - %w", previousRunID, run.GetConclusion(), ErrPredecessorFailed)
+    }
+
+    _, postCompletionSpan := tracing.Start(ctx, "wait "+PhasePostCompletion)
+    defer postCompletionSpan.End()
+
+    // the 'success' completion policy only waits post-completion of a successful run:
+    if g.opts.CompletionPolicy == CompletionSuccess && run.GetConclusion() != "success" {
+
+        completion.WaitBeforeComplete = 0
+
+        g.emit(Event{Type: EventPostCompletionSkipped, Phase: PhasePostCompletion, PreviousRunID: previousRunID, Run: run,
+            Message: fmt.Sprintf("previous run concluded '%s' - completion policy '%s' skips the post-completion wait", run.GetConclusion(), g.opts.CompletionPolicy)})
+    }
+
+    completedAt := run.GetUpdatedAt().Time
+
+    // a completion time ahead of the clock (skew) never makes the wait longer than WaitBeforeComplete:
+    if now := g.opts.Clock.Now(); completedAt.After(now) {
+        completedAt = now
+    }
+
+    // wait until WaitBeforeComplete has passed since the previous run completed:
+    for {
+
+        now := g.opts.Clock.Now()
+        remaining := completion.WaitBeforeComplete - now.Sub(completedAt)
+
+        if remaining <= 0 {
+            completion.ReleasedAt = now
+            completion.Rule = CompleteRule(run.GetStatus(), completedAt, completion.WaitBeforeComplete, now)
+
+            break
+        }
+
+        g.emit(Event{Type: EventPostCompletionWaiting, Phase: PhasePostCompletion, PreviousRunID: previousRunID, Run: run, Wait: remaining})
+
+        if err := g.sleep(ctx, remaining, &completion.PostCompletionWaited); err != nil {
+            return completion, err
+        }
+    }
+
+    g.emit(Event{Type: EventReleased, PreviousRunID: previousRunID, Run: run, Message: completion.Rule})
+
+    return completion, nil
+}
+
+// waitForCompletion checks the previous run until it has completed - with the API rather than the cache when
+// fresh is set, or once the cache reported the run completed without success.
+func (g *Gate) waitForCompletion(ctx context.Context, previousRunID int64, fresh bool, last **github.WorkflowRun, completion *Completion) (*github.WorkflowRun, error) {
+
+    for {
+
+        checked, err := g.check(ctx, previousRunID, fresh)
+
+        if err != nil && (ctx.Err() != nil || g.opts.StopOnAPIError) {
+            return nil, err
+        }
+
+        if err != nil {
+            g.emit(Event{Type: EventCheckFailed, Phase: PhasePredecessor, PreviousRunID: previousRunID, Err: err, Message: err.Error()})
+        } else {
+            g.emit(Event{Type: EventChecked, Phase: PhasePredecessor, PreviousRunID: previousRunID, Run: checked})
+            g.trackAttempt(previousRunID, last, checked)
+        }
+
+        if checked.GetStatus() == "completed" {
+
+            // the cache may hold a run that did not succeed from before it was re-run - the API has the last word,
+            // and is asked from then on:
+            if !fresh && g.opts.Cache != nil && checked.GetConclusion() != "success" {
+                fresh = true
+                continue
+            }
+
+            return checked, nil
+        }
+
+        g.emit(Event{Type: EventWaiting, Phase: PhasePredecessor, PreviousRunID: previousRunID, Run: checked, Wait: g.opts.WaitBetweenChecks})
+
+        if err := g.sleep(ctx, g.opts.WaitBetweenChecks, &completion.PredecessorWaited); err != nil {
+            return nil, err
+        }
+    }
+}
+
+// sleep waits for d and adds the time actually waited to waited - a sleep cut short when ctx is done counts as
+// far as it went.
+func (g *Gate) sleep(ctx context.Context, d time.Duration, waited *time.Duration) error {
+
+    start := g.opts.Clock.Now()
+    err := g.opts.Clock.Sleep(ctx, d)
+
+    *waited += g.opts.Clock.Now().Sub(start)
+
+    return err
+}
+
+// watchForRerun keeps checking a previous run that completed without success until RerunGracePeriod has passed
+// since it completed - it reports whether the run was re-run in the meantime.
+func (g *Gate) watchForRerun(ctx context.Context, previousRunID int64, run *github.WorkflowRun, last **github.WorkflowRun, completion *Completion) (bool, error) {
+
+    if g.opts.RerunGracePeriod == 0 || run.GetConclusion() == "success" {
+        return false, nil
+    }
+
+    completedAt := run.GetUpdatedAt().Time
+
+    // a completion time ahead of the clock (skew) never makes the grace period longer:
+    if now := g.opts.Clock.Now(); completedAt.After(now) {
+        completedAt = now
+    }
+
+    for {
+
+        remaining := g.opts.RerunGracePeriod - g.opts.Clock.Now().Sub(completedAt)
+
+        if remaining <= 0 {
+            return false, nil
+        }
+
+        wait := g.opts.WaitBetweenChecks
+
+        if remaining < wait {
+            wait = remaining
+        }
+
+        g.emit(Event{Type: EventRerunGraceWaiting, Phase: PhasePredecessor, PreviousRunID: previousRunID, Run: run, Wait: wait,
+            Message: fmt.Sprintf("previous run concluded '%s' - checking it for a re-run for another %.0f seconds", run.GetConclusion(), remaining.Seconds())})
+
+        if err := g.sleep(ctx, wait, &completion.PredecessorWaited); err != nil {
+            return false, err
+        }
+
+        checked, err := g.check(ctx, previousRunID, true)
+
+        if err != nil && (ctx.Err() != nil || g.opts.StopOnAPIError) {
+            return false, err
+        }
+
+        if err != nil {
+            g.emit(Event{Type: EventCheckFailed, Phase: PhasePredecessor, PreviousRunID: previousRunID, Err: err, Message: err.Error()})
+            continue
+        }
+
+        g.emit(Event{Type: EventChecked, Phase: PhasePredecessor, PreviousRunID: previousRunID, Run: checked})
+
+        if g.trackAttempt(previousRunID, last, checked) {
+            return true, nil
+        }
+    }
+}
+
+// trackAttempt reports whether run is a re-run of the run last checked - a new attempt, or the same attempt no
+// longer completed - and emits EventAttemptChanged when it is.
+func (g *Gate) trackAttempt(previousRunID int64, last **github.WorkflowRun, run *github.WorkflowRun) bool {
+
+    previous := *last
+    *last = run
+
+    if previous == nil {
+        return false
+    }
+
+    switch {
+
+    case run.GetRunAttempt() != previous.GetRunAttempt():
+        g.emit(Event{Type: EventAttemptChanged, Phase: PhasePredecessor, PreviousRunID: previousRunID, Run: run,
+            Message: fmt.Sprintf("previous run was re-run - attempt %d ('%s') replaces attempt %d ('%s')", run.GetRunAttempt(), run.GetStatus(), previous.GetRunAttempt(), previous.GetConclusion())})
+
+    case previous.GetStatus() == "completed" && run.GetStatus() != "completed":
+        g.emit(Event{Type: EventAttemptChanged, Phase: PhasePredecessor, PreviousRunID: previousRunID, Run: run,
+            Message: fmt.Sprintf("previous run was re-run - attempt %d is '%s' again", run.GetRunAttempt(), run.GetStatus())})
+
+    default:
+        return false
+    }
+
+    return true
+}
+
+// check returns the state of the previous run - from the API, past the cache, when fresh is set.
+func (g *Gate) check(ctx context.Context, previousRunID int64, fresh bool) (*github.WorkflowRun, error) {
+
+    if !fresh {
+        return g.Check(ctx, previousRunID)
+    }
+
+    run, err := gh.ReturnWorkflowRun(gh.WithLogger(ctx, g.logger), g.opts.API, g.opts.Owner, g.opts.Repo, int(previousRunID))
+
+    if err != nil {
+        return nil, apiError(ctx, err)
+    }
+
+    return run, nil
+}
+
+// CompleteRule is the rule WaitForTurn applies given the status of the previous run and when it completed.
+func CompleteRule(status string, completedAt time.Time, waitBeforeComplete time.Duration, now time.Time) string {
+
+    elapsed := now.Sub(completedAt)
+
+    switch {
+
+    case status != "completed":
+        return fmt.Sprintf("previous run is '%s' - wait for it to complete before completing this run", status)
+
+    case elapsed < waitBeforeComplete:
+        return fmt.Sprintf("previous run completed %.0f seconds ago - wait another %.0f seconds post-completion before completing this run", elapsed.Seconds(), (waitBeforeComplete - elapsed).Seconds())
+    }
+
+    return fmt.Sprintf("previous run completed %.0f seconds ago (at least %.0f seconds) - complete this run", elapsed.Seconds(), waitBeforeComplete.Seconds())
+}
@@ -0,0 +1,451 @@
+package sorter
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "reflect"
+    "sync"
+    "testing"
+    "time"
+
+    gh "gh-actions-workflow-runs-sorter/gh"
+    ghfake "gh-actions-workflow-runs-sorter/ghfake"
+
+    log "github.com/sirupsen/logrus"
+)
+
+func TestWaitForTurn(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    tests := []struct {
+        name             string
+        policy           string
+        conclusion       string
+        pendingChecks    int
+        cancel           bool
+        wantEvents       []EventType
+        wantWaitBefore   time.Duration
+        wantErr          error
+    }{
+        {
+            name:           "should release once the previous run completed",
+            policy:         CompletionAlways,
+            conclusion:     "success",
+            pendingChecks:  2,
+            wantEvents:     []EventType{EventChecked, EventWaiting, EventChecked, EventWaiting, EventChecked, EventPredecessorCompleted, EventReleased},
+            wantWaitBefore: 0,
+        },
+        {
+            name:           "should skip the post-completion wait after a failed run with the success policy",
+            policy:         CompletionSuccess,
+            conclusion:     "failure",
+            wantEvents:     []EventType{EventChecked, EventPredecessorCompleted, EventPostCompletionSkipped, EventReleased},
+            wantWaitBefore: 0,
+        },
+        {
+            name:           "should fail after a failed run with the fail policy",
+            policy:         CompletionFail,
+            conclusion:     "failure",
+            wantEvents:     []EventType{EventChecked, EventPredecessorCompleted},
+            wantErr:        ErrPredecessorFailed,
+        },
+        {
+            name:           "should stop waiting when cancelled",
+            policy:         CompletionAlways,
+            pendingChecks:  1000,
+            cancel:         true,
+            wantEvents:     []EventType{EventChecked, EventWaiting},
+            wantErr:        context.Canceled,
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            client, mux, _, teardown := gh.Setup()
+            defer teardown()
+
+            checks := 0
+
+            mux.HandleFunc("/repos/testowner/testrepo/actions/runs/3333333333", func(w http.ResponseWriter, r *http.Request) {
+
+                checks++
+
+                if checks <= tt.pendingChecks {
+                    fmt.Fprint(w, `{"id": 3333333333, "run_number": 30, "status": "in_progress"}`)
+                    return
+                }
+
+                fmt.Fprintf(w, `{"id": 3333333333, "run_number": 30, "status": "completed", "conclusion": "%s", "updated_at": "2022-12-12T23:47:06Z"}`, tt.conclusion)
+            })
+
+            ctx, cancel := context.WithCancel(context.Background())
+            defer cancel()
+
+            var mu sync.Mutex
+            gotEvents := []EventType{}
+            gotAPIEvents := []Event{}
+
+            gate, err := New(Options{
+                Client:             client,
+                Owner:              "testowner",
+                Repo:               "testrepo",
+                WaitBetweenChecks:  time.Millisecond,
+                WaitBeforeComplete: time.Minute,
+                CompletionPolicy:   tt.policy,
+                OnEvent: func(event Event) {
+                    mu.Lock()
+                    defer mu.Unlock()
+
+                    // what the API calls report is checked below:
+                    if event.Type == EventAPI {
+                        gotAPIEvents = append(gotAPIEvents, event)
+                        return
+                    }
+
+                    gotEvents = append(gotEvents, event.Type)
+
+                    if tt.cancel && event.Type == EventWaiting {
+                        cancel()
+                    }
+                },
+            })
+
+            if err != nil {
+                t.Fatalf("New() failed - %s", err.Error())
+            }
+
+            completion, gotErr := gate.WaitForTurn(ctx, 3333333333)
+
+            if !errors.Is(gotErr, tt.wantErr) {
+                t.Errorf("WaitForTurn() failed - error expects %v but received %v", tt.wantErr, gotErr)
+            }
+
+            if !reflect.DeepEqual(gotEvents, tt.wantEvents) {
+                t.Errorf("WaitForTurn() failed - events expect %v but received %v", tt.wantEvents, gotEvents)
+            }
+
+            if len(gotAPIEvents) == 0 || gotAPIEvents[0].Level == "" || gotAPIEvents[0].Fields["workflowRunId"] == nil {
+                t.Errorf("WaitForTurn() failed - expects its API calls reported as events but received %+v", gotAPIEvents)
+            }
+
+            if gotErr == nil && (completion.PredecessorWaited < time.Duration(tt.pendingChecks)*time.Millisecond || completion.WaitBeforeComplete != tt.wantWaitBefore && tt.policy == CompletionSuccess) {
+                t.Errorf("WaitForTurn() failed - unexpected completion %+v", completion)
+            }
+        })
+    }
+}
+
+func TestWaitForTurnSchedule(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    start := time.Date(2022, time.December, 13, 0, 0, 0, 0, time.UTC)
+
+    tests := []struct {
+        name               string
+        pendingPolls       int
+        completedAt        time.Time
+        waitBeforeComplete time.Duration
+        wantSleeps         []time.Duration
+        wantPredecessor    time.Duration
+        wantPostCompletion time.Duration
+        wantReleasedAt     time.Time
+    }{
+        {
+            name:               "should sleep between 3 polls and then for the 45s of bake time that remain",
+            pendingPolls:       3,
+            completedAt:        start.Add(15*time.Second),
+            waitBeforeComplete: time.Minute,
+            wantSleeps:         []time.Duration{10*time.Second, 10*time.Second, 10*time.Second, 45*time.Second},
+            wantPredecessor:    30*time.Second,
+            wantPostCompletion: 45*time.Second,
+            wantReleasedAt:     start.Add(75*time.Second),
+        },
+        {
+            name:               "should not sleep when the bake time has passed - negative remaining time",
+            pendingPolls:       0,
+            completedAt:        start.Add(-5*time.Minute),
+            waitBeforeComplete: time.Minute,
+            wantSleeps:         []time.Duration{},
+            wantReleasedAt:     start,
+        },
+        {
+            name:               "should not sleep when the bake time has passed exactly",
+            pendingPolls:       1,
+            completedAt:        start.Add(-50*time.Second),
+            waitBeforeComplete: time.Minute,
+            wantSleeps:         []time.Duration{10*time.Second},
+            wantPredecessor:    10*time.Second,
+            wantReleasedAt:     start.Add(10*time.Second),
+        },
+        {
+            name:               "should not sleep longer than the bake time when the completion time is ahead of the clock",
+            pendingPolls:       0,
+            completedAt:        start.Add(time.Minute),
+            waitBeforeComplete: 30*time.Second,
+            wantSleeps:         []time.Duration{30*time.Second},
+            wantPostCompletion: 30*time.Second,
+            wantReleasedAt:     start.Add(30*time.Second),
+        },
+        {
+            name:               "should not sleep without a bake time",
+            pendingPolls:       2,
+            completedAt:        start,
+            waitBeforeComplete: 0,
+            wantSleeps:         []time.Duration{10*time.Second, 10*time.Second},
+            wantPredecessor:    20*time.Second,
+            wantReleasedAt:     start.Add(20*time.Second),
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            clock := ghfake.NewClock(start)
+
+            api := ghfake.New()
+            api.Now = clock.Now
+
+            api.AddRun(ghfake.Run{ID: 3333333333, RunNumber: 30, Workflow: "release.yml", Branch: "main", Status: "in_progress"})
+            api.Script(3333333333, ghfake.Change{AfterPolls: tt.pendingPolls, Status: "completed", Conclusion: "success", UpdatedAt: tt.completedAt})
+
+            gate, err := New(Options{
+                API:                api,
+                Owner:              "testowner",
+                Repo:               "testrepo",
+                WaitBetweenChecks:  10*time.Second,
+                WaitBeforeComplete: tt.waitBeforeComplete,
+                Clock:              clock,
+            })
+
+            if err != nil {
+                t.Fatalf("New() failed - %s", err.Error())
+            }
+
+            completion, err := gate.WaitForTurn(context.Background(), 3333333333)
+
+            if err != nil {
+                t.Fatalf("WaitForTurn() failed - %s", err.Error())
+            }
+
+            if gotSleeps := clock.Sleeps(); !reflect.DeepEqual(gotSleeps, tt.wantSleeps) {
+                t.Errorf("WaitForTurn() failed - sleeps expect %v but received %v", tt.wantSleeps, gotSleeps)
+            }
+
+            if completion.PredecessorWaited != tt.wantPredecessor || completion.PostCompletionWaited != tt.wantPostCompletion {
+                t.Errorf("WaitForTurn() failed - waits expect %s/%s but received %s/%s", tt.wantPredecessor, tt.wantPostCompletion, completion.PredecessorWaited, completion.PostCompletionWaited)
+            }
+
+            if !completion.ReleasedAt.Equal(tt.wantReleasedAt) {
+                t.Errorf("WaitForTurn() failed - released at expects %s but received %s", tt.wantReleasedAt, completion.ReleasedAt)
+            }
+
+            if api.Polls(3333333333) != tt.pendingPolls+1 {
+                t.Errorf("WaitForTurn() failed - polls expect %d but received %d", tt.pendingPolls+1, api.Polls(3333333333))
+            }
+        })
+    }
+}
+
+func TestWaitForTurnRerun(t *testing.T){
+
+    log.SetOutput(ioutil.Discard)
+
+    start := time.Date(2022, time.December, 13, 0, 0, 0, 0, time.UTC)
+
+    tests := []struct {
+        name              string
+        policy            string
+        conclusion        string
+        gracePeriod       time.Duration
+        cached            bool
+        changes           []ghfake.Change
+        wantSleeps        []time.Duration
+        wantAttempt       int
+        wantConclusion    string
+        wantAttemptEvents int
+        wantReleasedAt    time.Time
+    }{
+        {
+            name:        "should wait for a re-run started within the grace period - and not fail on the attempt it replaced",
+            policy:      CompletionFail,
+            conclusion:  "failure",
+            gracePeriod: time.Minute,
+            changes: []ghfake.Change{
+                {At: start.Add(25*time.Second), Status: "in_progress", RunAttempt: 2},
+                {At: start.Add(50*time.Second), Status: "completed", Conclusion: "success"},
+            },
+            wantSleeps:        []time.Duration{10*time.Second, 10*time.Second, 10*time.Second, 10*time.Second, 10*time.Second},
+            wantAttempt:       2,
+            wantConclusion:    "success",
+            wantAttemptEvents: 1,
+            wantReleasedAt:    start.Add(50*time.Second),
+        },
+        {
+            name:           "should release once the grace period passed without a re-run",
+            conclusion:     "failure",
+            gracePeriod:    25*time.Second,
+            changes:        []ghfake.Change{{At: start.Add(time.Minute), Status: "in_progress", RunAttempt: 2}},
+            wantSleeps:     []time.Duration{10*time.Second, 10*time.Second, 5*time.Second},
+            wantAttempt:    1,
+            wantConclusion: "failure",
+            wantReleasedAt: start.Add(25*time.Second),
+        },
+        {
+            name:           "should not watch a successful run for a re-run",
+            conclusion:     "success",
+            gracePeriod:    time.Minute,
+            wantSleeps:     []time.Duration{},
+            wantAttempt:    1,
+            wantConclusion: "success",
+            wantReleasedAt: start,
+        },
+        {
+            name:        "should check a failed run served by the cache with the API - and wait for its re-run",
+            conclusion:  "failure",
+            cached:      true,
+            changes: []ghfake.Change{
+                {AfterPolls: 1, Status: "in_progress", RunAttempt: 2},
+                {At: start.Add(30*time.Second), Status: "completed", Conclusion: "success"},
+            },
+            wantSleeps:        []time.Duration{10*time.Second, 10*time.Second, 10*time.Second},
+            wantAttempt:       2,
+            wantConclusion:    "success",
+            wantAttemptEvents: 1,
+            wantReleasedAt:    start.Add(30*time.Second),
+        },
+        {
+            name:           "should not watch for a re-run without a grace period",
+            conclusion:     "failure",
+            changes:        []ghfake.Change{{At: start.Add(time.Second), Status: "in_progress", RunAttempt: 2}},
+            wantSleeps:     []time.Duration{},
+            wantAttempt:    1,
+            wantConclusion: "failure",
+            wantReleasedAt: start,
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            clock := ghfake.NewClock(start)
+
+            api := ghfake.New()
+            api.Now = clock.Now
+
+            api.AddRun(ghfake.Run{ID: 3333333333, RunNumber: 30, RunAttempt: 1, Workflow: "release.yml", Branch: "main", Status: "completed", Conclusion: tt.conclusion, UpdatedAt: start})
+            api.Script(3333333333, tt.changes...)
+
+            var cache Cache
+
+            if tt.cached {
+
+                runCache, err := gh.NewRunCache(t.TempDir(), time.Minute, time.Hour, time.Hour)
+
+                if err != nil {
+                    t.Fatalf("NewRunCache() failed - %s", err.Error())
+                }
+
+                cache = runCache
+            }
+
+            attemptEvents := 0
+
+            gate, err := New(Options{
+                API:               api,
+                Owner:             "testowner",
+                Repo:              "testrepo",
+                WaitBetweenChecks: 10*time.Second,
+                CompletionPolicy:  tt.policy,
+                RerunGracePeriod:  tt.gracePeriod,
+                Cache:             cache,
+                Clock:             clock,
+                OnEvent: func(event Event) {
+                    if event.Type == EventAttemptChanged {
+                        attemptEvents++
+                    }
+                },
+            })
+
+            if err != nil {
+                t.Fatalf("New() failed - %s", err.Error())
+            }
+
+            // another process cached the attempt that failed before it was re-run:
+            if tt.cached {
+                gate.Check(context.Background(), 3333333333)
+            }
+
+            completion, err := gate.WaitForTurn(context.Background(), 3333333333)
+
+            if err != nil {
+                t.Fatalf("WaitForTurn() failed - %s", err.Error())
+            }
+
+            if gotSleeps := clock.Sleeps(); !reflect.DeepEqual(gotSleeps, tt.wantSleeps) {
+                t.Errorf("WaitForTurn() failed - sleeps expect %v but received %v", tt.wantSleeps, gotSleeps)
+            }
+
+            if completion.Predecessor.GetRunAttempt() != tt.wantAttempt || completion.Predecessor.GetConclusion() != tt.wantConclusion {
+                t.Errorf("WaitForTurn() failed - expects attempt %d concluding '%s' but received attempt %d concluding '%s'", tt.wantAttempt, tt.wantConclusion, completion.Predecessor.GetRunAttempt(), completion.Predecessor.GetConclusion())
+            }
+
+            if attemptEvents != tt.wantAttemptEvents {
+                t.Errorf("WaitForTurn() failed - expects %d attempt changes but received %d", tt.wantAttemptEvents, attemptEvents)
+            }
+
+            if !completion.ReleasedAt.Equal(tt.wantReleasedAt) {
+                t.Errorf("WaitForTurn() failed - released at expects %s but received %s", tt.wantReleasedAt, completion.ReleasedAt)
+            }
+        })
+    }
+}
+
+func TestCompleteRule(t *testing.T){
+
+    completedAt := time.Date(2022, time.December, 12, 23, 47, 6, 0, time.UTC)
+
+    tests := []struct {
+        name   string
+        status string
+        now    time.Time
+        want   string
+    }{
+        {
+            name:   "should wait for a run that has not completed",
+            status: "in_progress",
+            now:    completedAt,
+            want:   "previous run is 'in_progress' - wait for it to complete before completing this run",
+        },
+        {
+            name:   "should wait post-completion",
+            status: "completed",
+            now:    completedAt.Add(20*time.Second),
+            want:   "previous run completed 20 seconds ago - wait another 40 seconds post-completion before completing this run",
+        },
+        {
+            name:   "should complete",
+            status: "completed",
+            now:    completedAt.Add(90*time.Second),
+            want:   "previous run completed 90 seconds ago (at least 60 seconds) - complete this run",
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            if got := CompleteRule(tt.status, completedAt, time.Minute, tt.now); got != tt.want {
+                t.Errorf("CompleteRule() failed - expects %q but received %q", tt.want, got)
+            }
+        })
+    }
+}
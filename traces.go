@@ -0,0 +1,58 @@
+package main
+
+import (
+    sorter "gh-actions-workflow-runs-sorter/sorter"
+    util "gh-actions-workflow-runs-sorter/util"
+)
+
+// executeTrace is trace as the util writers and step summaries take it.
+func executeTrace(trace sorter.Trace) util.ExecuteTrace {
+
+    converted := util.ExecuteTrace{
+        RunNumber:            trace.RunNumber,
+        Rule:                 trace.Rule,
+        ShouldRunExecute:     trace.ShouldExecute,
+        ShouldWaitForPastRun: trace.ShouldWaitForPastRun,
+        PastRunId:            trace.PastRunID,
+    }
+
+    for _, run := range trace.Runs {
+        converted.Runs = append(converted.Runs, util.RunTrace(run))
+    }
+
+    return converted
+}
+
+// utilQueue is queue as util.WriteQueue takes it.
+func utilQueue(queue sorter.Queue) util.Queue {
+
+    converted := util.Queue{Baseline: utilRunTrace(queue.Baseline)}
+
+    for _, entry := range queue.Entries {
+
+        converted.Entries = append(converted.Entries, util.QueueEntry{
+            RunNumber:    entry.RunNumber,
+            ID:           entry.ID,
+            Status:       entry.Status,
+            HeadSHA:      entry.HeadSHA,
+            Decision:     entry.Decision,
+            WaitsOn:      utilRunTrace(entry.WaitsOn),
+            WaitingSince: entry.WaitingSince,
+            Waiting:      entry.Waiting,
+            Trace:        executeTrace(entry.Trace),
+        })
+    }
+
+    return converted
+}
+
+func utilRunTrace(run *sorter.RunTrace) *util.RunTrace {
+
+    if run == nil {
+        return nil
+    }
+
+    converted := util.RunTrace(*run)
+
+    return &converted
+}
@@ -0,0 +1,133 @@
+package tracing
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "os"
+    "time"
+
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+    "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+    "go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+    "go.opentelemetry.io/otel/propagation"
+    "go.opentelemetry.io/otel/sdk/resource"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+    "go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "gh-actions-workflow-runs-sorter"
+
+// exporters supported by Setup:
+const (
+    ExporterNone     = "none"
+    ExporterOTLPGRPC = "otlp-grpc"
+    ExporterOTLPHTTP = "otlp-http"
+    ExporterStdout   = "stdout"
+    ExporterFile     = "file"
+)
+
+// Setup installs a global tracer provider exporting to exporter. OTLP exporters read the standard
+// OTEL_EXPORTER_OTLP_* environment variables; endpoint overrides the collector address when set.
+// The stdout exporter writes to stderr - stdout carries the exported variables of 'shouldExecute' mode.
+// The returned shutdown flushes pending spans and must be called before exiting.
+func Setup(ctx context.Context, exporter string, endpoint string, file string) (func(context.Context) error, error) {
+
+    noop := func(context.Context) error { return nil }
+
+    var spanExporter sdktrace.SpanExporter
+    var err error
+
+    closer := io.Closer(nil)
+
+    switch exporter {
+
+    case "", ExporterNone:
+        return noop, nil
+
+    case ExporterOTLPGRPC:
+        opts := []otlptracegrpc.Option{}
+
+        if endpoint != "" {
+            opts = append(opts, otlptracegrpc.WithEndpoint(endpoint))
+        }
+
+        spanExporter, err = otlptracegrpc.New(ctx, opts...)
+
+    case ExporterOTLPHTTP:
+        opts := []otlptracehttp.Option{}
+
+        if endpoint != "" {
+            opts = append(opts, otlptracehttp.WithEndpoint(endpoint))
+        }
+
+        spanExporter, err = otlptracehttp.New(ctx, opts...)
+
+    case ExporterStdout:
+        spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr), stdouttrace.WithPrettyPrint())
+
+    case ExporterFile:
+        if file == "" {
+            return noop, fmt.Errorf("A trace file is required for the '%s' exporter", ExporterFile)
+        }
+
+        f, openErr := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+
+        if openErr != nil {
+            return noop, openErr
+        }
+
+        closer = f
+        spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+
+    default:
+        return noop, fmt.Errorf("trace exporter passed is %s - allowed values are %s, %s, %s, %s or %s", exporter, ExporterNone, ExporterOTLPGRPC, ExporterOTLPHTTP, ExporterStdout, ExporterFile)
+    }
+
+    if err != nil {
+        return noop, err
+    }
+
+    provider := sdktrace.NewTracerProvider(
+        sdktrace.WithBatcher(spanExporter),
+        sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(tracerName))),
+    )
+
+    otel.SetTracerProvider(provider)
+    otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+    return func(ctx context.Context) error {
+
+        ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+        defer cancel()
+
+        err := provider.Shutdown(ctx)
+
+        if closer != nil {
+            closer.Close()
+        }
+
+        return err
+    }, nil
+}
+
+// ContextFromEnv continues the trace described by the TRACEPARENT/TRACESTATE environment variables when present,
+// e.g. when the calling workflow step is itself traced.
+func ContextFromEnv(ctx context.Context) context.Context {
+
+    carrier := propagation.MapCarrier{
+        "traceparent": os.Getenv("TRACEPARENT"),
+        "tracestate":  os.Getenv("TRACESTATE"),
+    }
+
+    return propagation.TraceContext{}.Extract(ctx, carrier)
+}
+
+// Start starts a span with this tool's tracer - a no-op span when tracing was not set up.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+
+    return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
@@ -0,0 +1,52 @@
+package tracing
+
+import (
+    "context"
+    "testing"
+
+    "go.opentelemetry.io/otel/trace"
+)
+
+func TestContextFromEnv(t *testing.T){
+
+    tests := []struct {
+        name        string
+        traceparent string
+        wantTraceID string
+    }{
+        {
+            name:        "continues the trace from TRACEPARENT",
+            traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+            wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+        },
+        {
+            name:        "starts a new trace without TRACEPARENT",
+            traceparent: "",
+            wantTraceID: "00000000000000000000000000000000",
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            t.Setenv("TRACEPARENT", tt.traceparent)
+
+            gotTraceID := trace.SpanContextFromContext(ContextFromEnv(context.Background())).TraceID().String()
+
+            if gotTraceID != tt.wantTraceID {
+                t.Errorf("ContextFromEnv() failed - trace id expects '%s' but received '%s'", tt.wantTraceID, gotTraceID)
+            }
+
+        })
+    }
+
+}
+
+func TestSetupRejectsUnknownExporter(t *testing.T){
+
+    if _, err := Setup(context.Background(), "zipkin", "", ""); err == nil {
+        t.Errorf("Setup() expects an error for an unknown exporter")
+    }
+
+}
@@ -0,0 +1,150 @@
+package util
+
+import (
+    "math"
+    "sort"
+    "time"
+
+    "github.com/google/go-github/v47/github"
+)
+
+// DurationStats summarizes how long recent runs (or jobs) took.
+type DurationStats struct {
+    Samples int
+    P50     time.Duration
+    P90     time.Duration
+}
+
+// Estimate is when a predecessor is expected to finish and, after post-completion wait, when this run is released.
+type Estimate struct {
+    PredecessorDoneP50 time.Time
+    PredecessorDoneP90 time.Time
+    ReleaseP50         time.Time
+    ReleaseP90         time.Time
+}
+
+// RunDurationStats computes p50/p90 durations from completed runs - cancelled and skipped runs are left out
+// as they would skew the estimate towards zero.
+func RunDurationStats(runs []*github.WorkflowRun) DurationStats {
+
+    durations := []time.Duration{}
+
+    for _, run := range runs {
+
+        if run.GetStatus() != "completed" || run.GetConclusion() == "cancelled" || run.GetConclusion() == "skipped" {
+            continue
+        }
+
+        started := RunStartedAt(run)
+
+        if started.IsZero() || run.UpdatedAt == nil || run.UpdatedAt.Time.Before(started) {
+            continue
+        }
+
+        durations = append(durations, run.UpdatedAt.Time.Sub(started))
+    }
+
+    return durationStats(durations)
+}
+
+// JobDurationStats computes p50/p90 durations of the completed jobs named jobName.
+func JobDurationStats(jobs []*github.WorkflowJob, jobName string) DurationStats {
+
+    durations := []time.Duration{}
+
+    for _, job := range jobs {
+
+        if job.GetName() != jobName || job.GetStatus() != "completed" || job.GetConclusion() == "cancelled" || job.GetConclusion() == "skipped" {
+            continue
+        }
+
+        if job.StartedAt == nil || job.CompletedAt == nil || job.CompletedAt.Time.Before(job.StartedAt.Time) {
+            continue
+        }
+
+        durations = append(durations, job.CompletedAt.Time.Sub(job.StartedAt.Time))
+    }
+
+    return durationStats(durations)
+}
+
+// RunStartedAt returns when run started - falling back to its creation time for runs without run_started_at.
+func RunStartedAt(run *github.WorkflowRun) time.Time {
+
+    if run.RunStartedAt != nil && !run.RunStartedAt.Time.IsZero() {
+        return run.RunStartedAt.Time
+    }
+
+    if run.CreatedAt != nil {
+        return run.CreatedAt.Time
+    }
+
+    return time.Time{}
+}
+
+// EstimateRelease estimates when something that started at started will be done based on stats,
+// and when this run is released waitBeforeComplete later. Estimates already in the past are moved to now -
+// a predecessor running longer than its p90 is still expected to finish "any moment".
+func EstimateRelease(started time.Time, stats DurationStats, waitBeforeComplete time.Duration, now time.Time) Estimate {
+
+    estimate := Estimate{
+        PredecessorDoneP50: latest(started.Add(stats.P50), now),
+        PredecessorDoneP90: latest(started.Add(stats.P90), now),
+    }
+
+    estimate.ReleaseP50 = latest(estimate.PredecessorDoneP50.Add(waitBeforeComplete), now)
+    estimate.ReleaseP90 = latest(estimate.PredecessorDoneP90.Add(waitBeforeComplete), now)
+
+    return estimate
+}
+
+// EstimateReleaseAfterCompletion is the estimate once the predecessor has completed at completedAt.
+func EstimateReleaseAfterCompletion(completedAt time.Time, waitBeforeComplete time.Duration, now time.Time) Estimate {
+
+    release := latest(completedAt.Add(waitBeforeComplete), now)
+
+    return Estimate{
+        PredecessorDoneP50: completedAt,
+        PredecessorDoneP90: completedAt,
+        ReleaseP50:         release,
+        ReleaseP90:         release,
+    }
+}
+
+func durationStats(durations []time.Duration) DurationStats {
+
+    if len(durations) == 0 {
+        return DurationStats{}
+    }
+
+    sort.Slice(durations, func(i, j int) bool {
+        return durations[i] < durations[j]
+    })
+
+    return DurationStats{
+        Samples: len(durations),
+        P50:     percentile(durations, 0.5),
+        P90:     percentile(durations, 0.9),
+    }
+}
+
+// percentile uses the nearest-rank method on sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+
+    rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+
+    if rank < 0 {
+        rank = 0
+    }
+
+    return sorted[rank]
+}
+
+func latest(a time.Time, b time.Time) time.Time {
+
+    if a.After(b) {
+        return a
+    }
+
+    return b
+}
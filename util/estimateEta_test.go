@@ -0,0 +1,90 @@
+package util
+
+import (
+    "testing"
+    "time"
+
+    "github.com/google/go-github/v47/github"
+)
+
+func TestRunDurationStats(t *testing.T){
+
+    start := time.Date(2022, time.December, 12, 20, 0, 0, 0, time.UTC)
+
+    runs := []*github.WorkflowRun{}
+
+    // ten completed runs taking 1..10 minutes:
+    for i := 1; i <= 10; i++ {
+        runs = append(runs, &github.WorkflowRun{
+            Status:       github.String("completed"),
+            Conclusion:   github.String("success"),
+            RunStartedAt: &github.Timestamp{Time: start},
+            UpdatedAt:    &github.Timestamp{Time: start.Add(time.Duration(i)*time.Minute)},
+        })
+    }
+
+    // runs that are left out of the statistics:
+    runs = append(runs,
+        &github.WorkflowRun{Status: github.String("completed"), Conclusion: github.String("cancelled"), RunStartedAt: &github.Timestamp{Time: start}, UpdatedAt: &github.Timestamp{Time: start.Add(time.Second)}},
+        &github.WorkflowRun{Status: github.String("in_progress"), RunStartedAt: &github.Timestamp{Time: start}, UpdatedAt: &github.Timestamp{Time: start.Add(time.Hour)}},
+    )
+
+    gotStats := RunDurationStats(runs)
+
+    if gotStats.Samples != 10 {
+        t.Errorf("RunDurationStats() failed - samples expects 10 but received %d", gotStats.Samples)
+    }
+
+    if gotStats.P50 != 5*time.Minute {
+        t.Errorf("RunDurationStats() failed - p50 expects %v but received %v", 5*time.Minute, gotStats.P50)
+    }
+
+    if gotStats.P90 != 9*time.Minute {
+        t.Errorf("RunDurationStats() failed - p90 expects %v but received %v", 9*time.Minute, gotStats.P90)
+    }
+
+}
+
+func TestEstimateRelease(t *testing.T){
+
+    now := time.Date(2022, time.December, 12, 20, 0, 0, 0, time.UTC)
+    stats := DurationStats{Samples: 10, P50: 5*time.Minute, P90: 9*time.Minute}
+
+    tests := []struct {
+        name           string
+        started        time.Time
+        wantReleaseP50 time.Time
+        wantReleaseP90 time.Time
+    }{
+        {
+            name:           "predecessor started 2 minutes ago",
+            started:        now.Add(-2*time.Minute),
+            wantReleaseP50: now.Add(4*time.Minute),
+            wantReleaseP90: now.Add(8*time.Minute),
+        },
+        {
+            name:           "predecessor running longer than p50",
+            started:        now.Add(-7*time.Minute),
+            wantReleaseP50: now.Add(time.Minute),
+            wantReleaseP90: now.Add(3*time.Minute),
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            gotEstimate := EstimateRelease(tt.started, stats, time.Minute, now)
+
+            if !gotEstimate.ReleaseP50.Equal(tt.wantReleaseP50) {
+                t.Errorf("EstimateRelease() failed - p50 release expects %v but received %v", tt.wantReleaseP50, gotEstimate.ReleaseP50)
+            }
+
+            if !gotEstimate.ReleaseP90.Equal(tt.wantReleaseP90) {
+                t.Errorf("EstimateRelease() failed - p90 release expects %v but received %v", tt.wantReleaseP90, gotEstimate.ReleaseP90)
+            }
+
+        })
+    }
+
+}
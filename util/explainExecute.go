@@ -0,0 +1,157 @@
+package util
+
+import (
+    "fmt"
+    "io"
+    "text/tabwriter"
+
+    "github.com/google/go-github/v47/github"
+)
+
+// roles a run can play in a 'shouldExecute' decision:
+const (
+    RoleCurrent     = "current"
+    RolePending     = "newer, pending"
+    RoleFiltered    = "filtered"
+    RoleSuperseder  = "superseder"
+    RolePredecessor = "predecessor"
+    RoleNotExamined = "not examined"
+)
+
+// RunTrace records how a single run was treated while deciding whether to execute.
+type RunTrace struct {
+    RunNumber  int
+    ID         int64
+    Status     string
+    Conclusion string
+    HeadSHA    string
+    Branch     string
+    HTMLURL    string
+    Role       string
+}
+
+// ExecuteTrace is the full decision trace behind ShouldExecute - every run considered and the rule that decided.
+type ExecuteTrace struct {
+    RunNumber            int
+    Runs                 []RunTrace
+    Rule                 string
+    ShouldRunExecute     bool
+    ShouldWaitForPastRun bool
+    PastRunId            int64
+}
+
+// ExplainExecute walks runs (newest first, as returned by Github Actions API) the same way ShouldExecute does
+// and records the role of each run along with the rule that produced the final decision.
+func ExplainExecute(runs []*github.WorkflowRun, runNumber int) ExecuteTrace {
+
+    return ExplainExecuteEvents(runs, "", runNumber)
+}
+
+// ExplainExecuteEvents is ExplainExecute for the runs triggered by one of events (a comma separated list) - runs
+// triggered by other events are listed as filtered and do not affect the decision.
+func ExplainExecuteEvents(runs []*github.WorkflowRun, events string, runNumber int) ExecuteTrace {
+
+    allowed := eventSet(events)
+    considered := len(runs)
+
+    if allowed != nil {
+        considered = len(FilterRunsByEvent(runs, events))
+    }
+
+    trace := ExecuteTrace{
+        RunNumber: runNumber,
+        Rule:                      fmt.Sprintf("no newer completed run and no previous run were found among the %d runs returned - do not execute", considered),
+    }
+
+    decided := false
+
+    for _, run := range runs {
+
+        runTrace := RunTrace{
+            RunNumber:  run.GetRunNumber(),
+            ID:         run.GetID(),
+            Status:     run.GetStatus(),
+            Conclusion: run.GetConclusion(),
+            HeadSHA:    run.GetHeadSHA(),
+            Branch:     run.GetHeadBranch(),
+            HTMLURL:    run.GetHTMLURL(),
+        }
+
+        switch {
+
+        // triggered by an event that is not ordered:
+        case allowed != nil && !allowed[run.GetEvent()]:
+            runTrace.Role = RoleFiltered
+
+        // the decision was already made by a run visited earlier:
+        case decided:
+            runTrace.Role = RoleNotExamined
+
+        case runTrace.RunNumber == runNumber:
+            runTrace.Role = RoleCurrent
+
+        // latest completed/successful run has a higher run_number:
+        case runTrace.RunNumber > runNumber && runTrace.Status == "completed":
+            runTrace.Role = RoleSuperseder
+            trace.Rule = fmt.Sprintf("newer run #%d (id %d) has already completed - this run lost its order and should not execute", runTrace.RunNumber, runTrace.ID)
+            decided = true
+
+        // found the first previous run with a complete status:
+        case runTrace.RunNumber < runNumber && runTrace.Status == "completed":
+            runTrace.Role = RolePredecessor
+            trace.ShouldRunExecute = true
+            trace.PastRunId = runTrace.ID
+            trace.Rule = fmt.Sprintf("previous run #%d (id %d) has completed and no newer run has - execute without waiting", runTrace.RunNumber, runTrace.ID)
+            decided = true
+
+        case runTrace.RunNumber < runNumber:
+            runTrace.Role = RolePredecessor
+            trace.ShouldRunExecute = true
+            trace.ShouldWaitForPastRun = true
+            trace.PastRunId = runTrace.ID
+            trace.Rule = fmt.Sprintf("previous run #%d (id %d) is '%s' and no newer run has completed - execute after waiting for it", runTrace.RunNumber, runTrace.ID, runTrace.Status)
+            decided = true
+
+        // newer runs that have not completed do not affect the decision:
+        default:
+            runTrace.Role = RolePending
+        }
+
+        trace.Runs = append(trace.Runs, runTrace)
+    }
+
+    return trace
+}
+
+// WriteExecuteTrace prints trace as a table followed by the rule that produced the decision.
+func WriteExecuteTrace(w io.Writer, trace ExecuteTrace) {
+
+    tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+    fmt.Fprintln(tw, "NUMBER\tID\tSTATUS\tCONCLUSION\tSHA\tBRANCH\tROLE")
+
+    for _, run := range trace.Runs {
+
+        sha := run.HeadSHA
+
+        if len(sha) > 7 {
+            sha = sha[:7]
+        }
+
+        fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\t%s\t%s\n", run.RunNumber, run.ID, orDash(run.Status), orDash(run.Conclusion), orDash(sha), orDash(run.Branch), run.Role)
+    }
+
+    tw.Flush()
+
+    fmt.Fprintf(w, "\ndecision for run #%d: SHOULD_RUN_EXECUTE=%t SHOULD_WAIT_FOR_PAST_RUN=%t PAST_RUN_ID=%d\n", trace.RunNumber, trace.ShouldRunExecute, trace.ShouldWaitForPastRun, trace.PastRunId)
+    fmt.Fprintf(w, "rule: %s\n", trace.Rule)
+}
+
+func orDash(value string) string {
+
+    if value == "" {
+        return "-"
+    }
+
+    return value
+}
@@ -0,0 +1,99 @@
+package util
+
+import (
+    "bytes"
+    "reflect"
+    "strings"
+    "testing"
+
+    "github.com/google/go-github/v47/github"
+)
+
+func TestExplainExecute(t *testing.T){
+
+    tests := []struct {
+        name      string
+        runs      []*github.WorkflowRun
+        events    string
+        runNumber int
+        wantRoles []string
+        wantRule  string
+    }{
+        {
+            name: "superseded by a newer completed run",
+            runs: []*github.WorkflowRun{
+                {ID: github.Int64(3333333333), RunNumber: github.Int(30), Status: github.String("completed"), Conclusion: github.String("success")},
+                {ID: github.Int64(2222222222), RunNumber: github.Int(29), Status: github.String("completed"), Conclusion: github.String("success")},
+            },
+            runNumber: 20,
+            wantRoles: []string{RoleSuperseder, RoleNotExamined},
+            wantRule:  "newer run #30 (id 3333333333) has already completed",
+        },
+        {
+            name: "newer in_progress run pending and previous run waited on",
+            runs: []*github.WorkflowRun{
+                {ID: github.Int64(5555555555), RunNumber: github.Int(32), Status: github.String("in_progress")},
+                {ID: github.Int64(4444444444), RunNumber: github.Int(31), Status: github.String("in_progress")},
+                {ID: github.Int64(3333333333), RunNumber: github.Int(30), Status: github.String("queued")},
+                {ID: github.Int64(2222222222), RunNumber: github.Int(29), Status: github.String("completed"), Conclusion: github.String("success")},
+            },
+            runNumber: 31,
+            wantRoles: []string{RolePending, RoleCurrent, RolePredecessor, RoleNotExamined},
+            wantRule:  "previous run #30 (id 3333333333) is 'queued'",
+        },
+        {
+            name: "runs of other events filtered and previous run of the same event waited on",
+            runs: []*github.WorkflowRun{
+                {ID: github.Int64(5555555555), RunNumber: github.Int(32), Status: github.String("completed"), Conclusion: github.String("success"), Event: github.String("push")},
+                {ID: github.Int64(4444444444), RunNumber: github.Int(31), Status: github.String("in_progress"), Event: github.String("schedule")},
+                {ID: github.Int64(3333333333), RunNumber: github.Int(30), Status: github.String("completed"), Conclusion: github.String("success"), Event: github.String("push")},
+                {ID: github.Int64(2222222222), RunNumber: github.Int(29), Status: github.String("queued"), Event: github.String("schedule")},
+            },
+            events:    "schedule, workflow_dispatch",
+            runNumber: 31,
+            wantRoles: []string{RoleFiltered, RoleCurrent, RoleFiltered, RolePredecessor},
+            wantRule:  "previous run #29 (id 2222222222) is 'queued'",
+        },
+        {
+            name: "no previous run found",
+            runs: []*github.WorkflowRun{
+                {ID: github.Int64(1111111111), RunNumber: github.Int(1), Status: github.String("in_progress")},
+            },
+            runNumber: 1,
+            wantRoles: []string{RoleCurrent},
+            wantRule:  "no newer completed run and no previous run were found",
+        },
+    }
+
+    for _, tt := range tests {
+
+        t.Run(tt.name, func(t *testing.T) {
+
+            gotTrace := ExplainExecuteEvents(tt.runs, tt.events, tt.runNumber)
+
+            gotRoles := []string{}
+
+            for _, run := range gotTrace.Runs {
+                gotRoles = append(gotRoles, run.Role)
+            }
+
+            if !reflect.DeepEqual(gotRoles, tt.wantRoles){
+                t.Errorf("ExplainExecute() failed - roles expects %v but received %v", tt.wantRoles, gotRoles)
+            }
+
+            if !strings.HasPrefix(gotTrace.Rule, tt.wantRule){
+                t.Errorf("ExplainExecute() failed - rule expects '%s...' but received '%s'", tt.wantRule, gotTrace.Rule)
+            }
+
+            var out bytes.Buffer
+
+            WriteExecuteTrace(&out, gotTrace)
+
+            if !strings.Contains(out.String(), "rule: "+gotTrace.Rule){
+                t.Errorf("WriteExecuteTrace() failed - output does not state the rule:\n%s", out.String())
+            }
+
+        })
+    }
+
+}
@@ -0,0 +1,44 @@
+package util
+
+import (
+    "strings"
+
+    "github.com/google/go-github/v47/github"
+)
+
+// FilterRunsByEvent keeps the runs triggered by one of events (a comma separated list) - all runs when events is empty.
+func FilterRunsByEvent(runs []*github.WorkflowRun, events string) []*github.WorkflowRun {
+
+    allowed := eventSet(events)
+
+    if allowed == nil {
+        return runs
+    }
+
+    filtered := []*github.WorkflowRun{}
+
+    for _, run := range runs {
+
+        if allowed[run.GetEvent()] {
+            filtered = append(filtered, run)
+        }
+    }
+
+    return filtered
+}
+
+// eventSet parses events (a comma separated list) - nil when events is empty, which allows every event.
+func eventSet(events string) map[string]bool {
+
+    if strings.TrimSpace(events) == "" {
+        return nil
+    }
+
+    allowed := map[string]bool{}
+
+    for _, event := range strings.Split(events, ",") {
+        allowed[strings.TrimSpace(event)] = true
+    }
+
+    return allowed
+}
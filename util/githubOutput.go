@@ -0,0 +1,29 @@
+package util
+
+import (
+    "fmt"
+    "os"
+)
+
+// WriteGithubOutput appends name=value to the file at $GITHUB_OUTPUT so later steps can read it as a step output.
+// Outside of Github Actions ($GITHUB_OUTPUT not set) this is a no-op.
+func WriteGithubOutput(name string, value string) error {
+
+    path := os.Getenv("GITHUB_OUTPUT")
+
+    if path == "" {
+        return nil
+    }
+
+    f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+
+    if err != nil {
+        return err
+    }
+
+    defer f.Close()
+
+    _, err = fmt.Fprintf(f, "%s=%s\n", name, value)
+
+    return err
+}
@@ -0,0 +1,151 @@
+package util
+
+import (
+    "fmt"
+    "io"
+    "sort"
+    "text/tabwriter"
+    "time"
+
+    "github.com/google/go-github/v47/github"
+)
+
+// decisions a pending run would get from 'shouldExecute' mode:
+const (
+    DecisionProceed = "proceed"
+    DecisionWait    = "wait"
+    DecisionSkip    = "skip"
+)
+
+// QueueEntry is a queued or in_progress run along with the decision it would get right now.
+type QueueEntry struct {
+    RunNumber    int
+    ID           int64
+    Status       string
+    HeadSHA      string
+    Decision     string
+    WaitsOn      *RunTrace
+    WaitingSince time.Time
+    Waiting      time.Duration
+    Trace        ExecuteTrace
+}
+
+// Queue is the current ordering queue of a workflow - pending runs in run_number order
+// with the latest completed run as their baseline.
+type Queue struct {
+    Baseline *RunTrace
+    Entries  []QueueEntry
+}
+
+// BuildQueue computes the ordering queue from runs (as returned by Github Actions API) using the same
+// decision logic as ShouldExecute for every run that has not completed yet.
+func BuildQueue(runs []*github.WorkflowRun, now time.Time) Queue {
+
+    queue := Queue{}
+
+    for _, run := range runs {
+
+        if run.GetStatus() != "completed" {
+            continue
+        }
+
+        if queue.Baseline == nil || run.GetRunNumber() > queue.Baseline.RunNumber {
+            queue.Baseline = &RunTrace{
+                RunNumber:  run.GetRunNumber(),
+                ID:         run.GetID(),
+                Status:     run.GetStatus(),
+                Conclusion: run.GetConclusion(),
+                HeadSHA:    run.GetHeadSHA(),
+                Branch:     run.GetHeadBranch(),
+            }
+        }
+    }
+
+    for _, run := range runs {
+
+        if run.GetStatus() == "completed" {
+            continue
+        }
+
+        entry := QueueEntry{
+            RunNumber: run.GetRunNumber(),
+            ID:        run.GetID(),
+            Status:    run.GetStatus(),
+            HeadSHA:   run.GetHeadSHA(),
+            Trace:     ExplainExecute(runs, run.GetRunNumber()),
+        }
+
+        switch {
+
+        case !entry.Trace.ShouldRunExecute:
+            entry.Decision = DecisionSkip
+
+        case entry.Trace.ShouldWaitForPastRun:
+            entry.Decision = DecisionWait
+
+        default:
+            entry.Decision = DecisionProceed
+        }
+
+        if entry.Decision == DecisionWait {
+
+            for i := range entry.Trace.Runs {
+
+                if entry.Trace.Runs[i].Role == RolePredecessor {
+                    entry.WaitsOn = &entry.Trace.Runs[i]
+                }
+            }
+        }
+
+        if run.CreatedAt != nil {
+            entry.WaitingSince = run.CreatedAt.Time
+            entry.Waiting = now.Sub(run.CreatedAt.Time).Truncate(time.Second)
+        }
+
+        queue.Entries = append(queue.Entries, entry)
+    }
+
+    sort.Slice(queue.Entries, func(i, j int) bool {
+        return queue.Entries[i].RunNumber < queue.Entries[j].RunNumber
+    })
+
+    return queue
+}
+
+// WriteQueue prints the baseline followed by a table of pending runs in order.
+func WriteQueue(w io.Writer, queue Queue) {
+
+    if queue.Baseline != nil {
+        fmt.Fprintf(w, "baseline: run #%d (id %d) completed with conclusion '%s'\n\n", queue.Baseline.RunNumber, queue.Baseline.ID, orDash(queue.Baseline.Conclusion))
+    } else {
+        fmt.Fprintf(w, "baseline: no completed run found\n\n")
+    }
+
+    if len(queue.Entries) == 0 {
+        fmt.Fprintln(w, "no queued or in_progress runs")
+        return
+    }
+
+    tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+    fmt.Fprintln(tw, "NUMBER\tID\tSTATUS\tSHA\tDECISION\tWAITS ON\tWAITING")
+
+    for _, entry := range queue.Entries {
+
+        sha := entry.HeadSHA
+
+        if len(sha) > 7 {
+            sha = sha[:7]
+        }
+
+        waitsOn := "-"
+
+        if entry.WaitsOn != nil {
+            waitsOn = fmt.Sprintf("#%d (%s)", entry.WaitsOn.RunNumber, entry.WaitsOn.Status)
+        }
+
+        fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\t%s\t%s\n", entry.RunNumber, entry.ID, entry.Status, orDash(sha), entry.Decision, waitsOn, entry.Waiting)
+    }
+
+    tw.Flush()
+}
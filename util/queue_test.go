@@ -0,0 +1,59 @@
+package util
+
+import (
+    "reflect"
+    "testing"
+    "time"
+
+    "github.com/google/go-github/v47/github"
+)
+
+func TestBuildQueue(t *testing.T){
+
+    now := time.Date(2022, time.December, 13, 0, 0, 0, 0, time.UTC)
+
+    runs := []*github.WorkflowRun{
+        {ID: github.Int64(5555555555), RunNumber: github.Int(32), Status: github.String("queued"), CreatedAt: &github.Timestamp{Time: now.Add(-1*time.Minute)}},
+        {ID: github.Int64(4444444444), RunNumber: github.Int(31), Status: github.String("in_progress"), CreatedAt: &github.Timestamp{Time: now.Add(-5*time.Minute)}},
+        {ID: github.Int64(3333333333), RunNumber: github.Int(30), Status: github.String("in_progress"), CreatedAt: &github.Timestamp{Time: now.Add(-9*time.Minute)}},
+        {ID: github.Int64(2222222222), RunNumber: github.Int(29), Status: github.String("completed"), Conclusion: github.String("success")},
+        {ID: github.Int64(1111111111), RunNumber: github.Int(28), Status: github.String("completed"), Conclusion: github.String("failure")},
+    }
+
+    gotQueue := BuildQueue(runs, now)
+
+    if gotQueue.Baseline == nil || gotQueue.Baseline.RunNumber != 29 {
+        t.Fatalf("BuildQueue() failed - baseline expects run #29 but received %v", gotQueue.Baseline)
+    }
+
+    gotNumbers, gotDecisions, gotWaitsOn := []int{}, []string{}, []int{}
+
+    for _, entry := range gotQueue.Entries {
+
+        gotNumbers = append(gotNumbers, entry.RunNumber)
+        gotDecisions = append(gotDecisions, entry.Decision)
+
+        if entry.WaitsOn != nil {
+            gotWaitsOn = append(gotWaitsOn, entry.WaitsOn.RunNumber)
+        } else {
+            gotWaitsOn = append(gotWaitsOn, 0)
+        }
+    }
+
+    if !reflect.DeepEqual(gotNumbers, []int{30, 31, 32}) {
+        t.Errorf("BuildQueue() failed - order expects [30 31 32] but received %v", gotNumbers)
+    }
+
+    if !reflect.DeepEqual(gotDecisions, []string{DecisionProceed, DecisionWait, DecisionWait}) {
+        t.Errorf("BuildQueue() failed - decisions expects [proceed wait wait] but received %v", gotDecisions)
+    }
+
+    if !reflect.DeepEqual(gotWaitsOn, []int{0, 30, 31}) {
+        t.Errorf("BuildQueue() failed - waits on expects [0 30 31] but received %v", gotWaitsOn)
+    }
+
+    if gotQueue.Entries[0].Waiting != 9*time.Minute {
+        t.Errorf("BuildQueue() failed - waiting expects %v but received %v", 9*time.Minute, gotQueue.Entries[0].Waiting)
+    }
+
+}
@@ -30,47 +30,23 @@ func ShouldExecute(runs []*github.WorkflowRun, runNumber int, workflowRunsToRetu
 
     // }
 
-    // set the execution run requirement to false by default
-    shouldRunExecute := "false"
-    shouldWaitForPastRun := "false"
-    pastRunId := int64(0)
-	
-    // loop through each run from this workflow:
-    for _, run := range runs {
-
-        // latest completed/successful run has a higher run_number:
-        if (*run.RunNumber > runNumber) && (*run.Status == "completed") {
-
-            log.WithFields(log.Fields{
-                "runNumber": runNumber,
-            }).Warn(fmt.Sprintf("There's no need to re-run this workflow run; latest 'future' workflow run has completed with id %d\n", *run.RunNumber))
+    // walk the runs and record the decision (defaults to not executing):
+    trace := ExplainExecute(runs, runNumber)
 
-            // do not update the shouldRunExecute, shouldWaitForPastRun or pastRunId
-            // break loop - the rest of the logic is not required
-            break
+    shouldRunExecute := strconv.FormatBool(trace.ShouldRunExecute)
+    shouldWaitForPastRun := strconv.FormatBool(trace.ShouldWaitForPastRun)
+    pastRunIdStr := strconv.FormatInt(trace.PastRunId, 10)
 
-        // found the first previous run with a complete status:
-        } else if (*run.RunNumber < runNumber) && (*run.Status == "completed") {
+    for _, run := range trace.Runs {
 
-            // do not update shouldWaitForPastRun - fine as false
-            // update shouldRunExecute and pastRunId
-            shouldRunExecute = "true"
-            pastRunId = *run.ID
-            break
+        if run.Role == RoleSuperseder {
 
-        } else if (*run.RunNumber < runNumber) && (*run.Status != "completed") {
-
-            // update shouldRunExecute, shouldWaitForPastRun and pastRunId
-            shouldRunExecute = "true"
-            shouldWaitForPastRun = "true"
-            pastRunId = *run.ID
-            break
+            log.WithFields(log.Fields{
+                "runNumber": runNumber,
+            }).Warn(fmt.Sprintf("There's no need to re-run this workflow run; latest 'future' workflow run has completed with id %d\n", run.RunNumber))
         }
     }
 
-    // convert to string from pastRunId
-    pastRunIdStr := strconv.Itoa(int(pastRunId))
-
     // -- Post-Logic check --
     // Check for the size of the list of runs - expected to be minimum of 20
     // Q: when can it not be 20?
@@ -0,0 +1,141 @@
+package util
+
+import (
+    "fmt"
+    "io"
+    "os"
+    "strings"
+    "time"
+)
+
+// PhaseWait is the time spent in one wait phase.
+type PhaseWait struct {
+    Phase  string
+    Waited time.Duration
+}
+
+// StepSummary is what gets reported on the run's summary page after a decision.
+type StepSummary struct {
+    Mode        string
+    RunNumber   int
+    Decision    string
+    Rule        string
+    Predecessor *RunTrace
+    Superseder  *RunTrace
+    Waits       []PhaseWait
+    Trace       *ExecuteTrace
+}
+
+// NewExecuteStepSummary builds the summary of a 'shouldExecute' decision from its trace.
+func NewExecuteStepSummary(trace ExecuteTrace, decision string) StepSummary {
+
+    summary := StepSummary{
+        Mode:      "shouldExecute",
+        RunNumber: trace.RunNumber,
+        Decision:  decision,
+        Rule:      trace.Rule,
+        Trace:     &trace,
+    }
+
+    for i := range trace.Runs {
+
+        switch trace.Runs[i].Role {
+
+        case RolePredecessor:
+            summary.Predecessor = &trace.Runs[i]
+
+        case RoleSuperseder:
+            summary.Superseder = &trace.Runs[i]
+        }
+    }
+
+    return summary
+}
+
+// RenderStepSummary writes summary as Markdown.
+func RenderStepSummary(w io.Writer, summary StepSummary) {
+
+    fmt.Fprintf(w, "### Workflow runs sorter: run #%d - `%s`\n\n", summary.RunNumber, summary.Decision)
+    fmt.Fprintf(w, "**Mode:** `%s`  \n", summary.Mode)
+    fmt.Fprintf(w, "**Reason:** %s\n\n", summary.Rule)
+
+    if summary.Predecessor != nil {
+        fmt.Fprintf(w, "- Predecessor: %s\n", runLink(*summary.Predecessor))
+    }
+
+    if summary.Superseder != nil {
+        fmt.Fprintf(w, "- Superseded by: %s\n", runLink(*summary.Superseder))
+    }
+
+    for _, wait := range summary.Waits {
+        fmt.Fprintf(w, "- Waited `%s` in phase `%s`\n", wait.Waited.Round(time.Second), wait.Phase)
+    }
+
+    if summary.Trace != nil && len(summary.Trace.Runs) > 0 {
+
+        fmt.Fprintf(w, "\n| run | status | conclusion | sha | branch | role |\n")
+        fmt.Fprintf(w, "| --- | --- | --- | --- | --- | --- |\n")
+
+        for _, run := range summary.Trace.Runs {
+
+            sha := run.HeadSHA
+
+            if len(sha) > 7 {
+                sha = sha[:7]
+            }
+
+            fmt.Fprintf(w, "| %s | %s | %s | %s | %s | %s |\n", runLink(run), orDash(run.Status), orDash(run.Conclusion), orDash(sha), orDash(run.Branch), run.Role)
+        }
+    }
+
+    fmt.Fprintln(w)
+}
+
+// WriteStepSummary appends summary to the file at $GITHUB_STEP_SUMMARY - a no-op outside of Github Actions.
+func WriteStepSummary(summary StepSummary) error {
+
+    path := os.Getenv("GITHUB_STEP_SUMMARY")
+
+    if path == "" {
+        return nil
+    }
+
+    f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+
+    if err != nil {
+        return err
+    }
+
+    defer f.Close()
+
+    RenderStepSummary(f, summary)
+
+    return nil
+}
+
+// RunURL is the link to a workflow run on the Github server this job runs against ($GITHUB_SERVER_URL).
+func RunURL(owner string, repo string, runId int64) string {
+
+    server := strings.TrimSuffix(os.Getenv("GITHUB_SERVER_URL"), "/")
+
+    if server == "" {
+        server = "https://github.com"
+    }
+
+    return fmt.Sprintf("%s/%s/%s/actions/runs/%d", server, owner, repo, runId)
+}
+
+func runLink(run RunTrace) string {
+
+    label := fmt.Sprintf("#%d", run.RunNumber)
+
+    if run.RunNumber == 0 {
+        label = fmt.Sprintf("run %d", run.ID)
+    }
+
+    if run.HTMLURL == "" {
+        return label
+    }
+
+    return fmt.Sprintf("[%s](%s)", label, run.HTMLURL)
+}
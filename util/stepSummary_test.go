@@ -0,0 +1,41 @@
+package util
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/google/go-github/v47/github"
+)
+
+func TestRenderStepSummary(t *testing.T){
+
+    runs := []*github.WorkflowRun{
+        {ID: github.Int64(4444444444), RunNumber: github.Int(31), Status: github.String("in_progress"), HTMLURL: github.String("https://github.com/testowner/testrepo/actions/runs/4444444444")},
+        {ID: github.Int64(3333333333), RunNumber: github.Int(30), Status: github.String("in_progress"), HTMLURL: github.String("https://github.com/testowner/testrepo/actions/runs/3333333333")},
+    }
+
+    summary := NewExecuteStepSummary(ExplainExecute(runs, 31), DecisionWait)
+    summary.Waits = []PhaseWait{{Phase: "predecessor", Waited: 95*time.Second}}
+
+    var out bytes.Buffer
+
+    RenderStepSummary(&out, summary)
+
+    wantLines := []string{
+        "### Workflow runs sorter: run #31 - `wait`",
+        "**Reason:** previous run #30 (id 3333333333) is 'in_progress'",
+        "- Predecessor: [#30](https://github.com/testowner/testrepo/actions/runs/3333333333)",
+        "- Waited `1m35s` in phase `predecessor`",
+        "| [#31](https://github.com/testowner/testrepo/actions/runs/4444444444) | in_progress | - | - | - | current |",
+    }
+
+    for _, line := range wantLines {
+
+        if !strings.Contains(out.String(), line) {
+            t.Errorf("RenderStepSummary() failed - expects '%s' in:\n%s", line, out.String())
+        }
+    }
+
+}